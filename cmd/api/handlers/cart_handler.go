@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type CartHandler struct {
+	cartService service.CartService
+}
+
+func NewCartHandler(cartService service.CartService) *CartHandler {
+	return &CartHandler{cartService}
+}
+
+// cartItemRequest is a scan at the till. Weighed products take a measure
+// in the unit they are sold in instead of a quantity.
+type cartItemRequest struct {
+	CodeValue string         `json:"code_value" binding:"required"`
+	Quantity  int            `json:"quantity"`
+	Measure   domain.Measure `json:"measure" swaggertype:"number"`
+}
+
+// cartErrorStatus maps a cart service error to an HTTP status, using
+// fallback for anything else.
+func cartErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, repository.ErrCartNotFound),
+		errors.Is(err, repository.ErrSaleNotFound),
+		errors.Is(err, service.ErrUnknownCode):
+		return 404
+	case errors.Is(err, service.ErrCartClosed),
+		errors.Is(err, service.ErrNotForSale),
+		errors.Is(err, service.ErrInsufficientStock):
+		return 409
+	}
+	return fallback
+}
+
+// CreateCart godoc
+// @Summary      Open a cart
+// @Tags         pos
+// @Produce      json
+// @Param        token header string true "token"
+// @Success      201 {object}  web.response
+// @Router       /carts [post]
+func (h *CartHandler) CreateCart(ctx *gin.Context) {
+	cart, err := h.cartService.CreateCart()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 201, cart)
+}
+
+func (h *CartHandler) GetCartByID(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	cart, err := h.cartService.GetCartByID(id)
+	if err != nil {
+		web.Failure(ctx, cartErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, cart)
+}
+
+// AddItem godoc
+// @Summary      Scan a product
+// @Description  Add the product with the scanned code_value, barcode, PLU or price/weight-embedded scale label to the cart; quantity defaults to 1, weighed products take a measure such as 0.355 (kg) instead
+// @Tags         pos
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "cart id"
+// @Param        item body cartItemRequest true "scanned code and quantity"
+// @Success      200 {object}  web.response
+// @Failure      404 {object}  web.errorResponse
+// @Failure      409 {object}  web.errorResponse
+// @Router       /carts/{id}/items [post]
+func (h *CartHandler) AddItem(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var item cartItemRequest
+	if err := ctx.ShouldBindJSON(&item); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	cart, err := h.cartService.AddItem(id, item.CodeValue, item.Quantity, item.Measure)
+	if err != nil {
+		web.Failure(ctx, cartErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 200, cart)
+}
+
+// RemoveItem godoc
+// @Summary      Remove a product from a cart
+// @Description  Remove units of a product, or the whole line when quantity is omitted
+// @Tags         pos
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "cart id"
+// @Param        code_value path string true "code value, barcode, PLU or scale label of the product"
+// @Param        quantity query int false "units to remove"
+// @Success      200 {object}  web.response
+// @Failure      404 {object}  web.errorResponse
+// @Failure      409 {object}  web.errorResponse
+// @Router       /carts/{id}/items/{code_value} [delete]
+func (h *CartHandler) RemoveItem(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	quantity := 0
+	if raw, ok := ctx.GetQuery("quantity"); ok {
+		converted, errConverted := strconv.Atoi(raw)
+		if errConverted != nil {
+			web.Failure(ctx, 400, errConverted)
+			return
+		}
+		quantity = converted
+	}
+	cart, err := h.cartService.RemoveItem(id, ctx.Param("code_value"), quantity)
+	if err != nil {
+		web.Failure(ctx, cartErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 200, cart)
+}
+
+// Quote godoc
+// @Summary      Price a cart
+// @Description  Price the cart with today's markdowns and promotions, explaining which promotion applied to each line, without selling it
+// @Tags         pos
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "cart id"
+// @Success      200 {object}  web.response
+// @Failure      404 {object}  web.errorResponse
+// @Failure      409 {object}  web.errorResponse
+// @Router       /carts/{id}/quote [get]
+func (h *CartHandler) Quote(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	sale, err := h.cartService.Quote(id)
+	if err != nil {
+		web.Failure(ctx, cartErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 200, sale)
+}
+
+// Checkout godoc
+// @Summary      Check out a cart
+// @Description  Sell every product in the cart and return the receipt. Nothing is sold when a product is unpublished or short of stock
+// @Tags         pos
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "cart id"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Failure      409 {object}  web.errorResponse
+// @Router       /carts/{id}/checkout [post]
+func (h *CartHandler) Checkout(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	sale, err := h.cartService.Checkout(id)
+	if err != nil {
+		web.Failure(ctx, cartErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 201, sale)
+}
+
+func (h *CartHandler) GetAllSales(ctx *gin.Context) {
+	sales, err := h.cartService.GetAllSales()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, sales)
+}
+
+// GetSaleByID godoc
+// @Summary      Get a receipt
+// @Tags         pos
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "sale id"
+// @Success      200 {object}  web.response
+// @Failure      404 {object}  web.errorResponse
+// @Router       /sales/{id} [get]
+func (h *CartHandler) GetSaleByID(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	sale, err := h.cartService.GetSaleByID(id)
+	if err != nil {
+		web.Failure(ctx, cartErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, sale)
+}
@@ -0,0 +1,254 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Carts_Checkout(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D","quantity":2}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"M4637"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"M4637"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"T65812"}`, http.StatusConflict},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"NOPE"}`, http.StatusNotFound},
+		{http.MethodDelete, "/carts/1/items/M4637?quantity=1", ``, http.StatusOK},
+		{http.MethodPost, "/carts/1/checkout", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/checkout", ``, http.StatusConflict},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"M4637"}`, http.StatusConflict},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/2/checkout", ``, http.StatusBadRequest},
+		{http.MethodPost, "/carts/2/items", `{"code_value":"M4637"}`, http.StatusOK},
+		{http.MethodPost, "/carts/2/items", `{"code_value":"S82254D","quantity":100000}`, http.StatusOK},
+		{http.MethodPost, "/carts/2/checkout", ``, http.StatusConflict},
+	})
+
+	var sale domain.Sale
+	rr := serveData(r, http.MethodGet, "/sales/1", "", &sale)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, sale.Lines, 2)
+	assert.Equal(t, p[0].Price.Mul(2).Add(p[1].Price), sale.Total)
+	assert.Equal(t, p[0].Quantity-2, after[0].Quantity)
+	assert.Equal(t, p[1].Quantity-1, after[1].Quantity)
+}
+
+func Test_Carts_CheckoutFailure(t *testing.T) {
+	r := createServer(t, "my-secret-value", "sales.json")
+	p := fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D","quantity":2}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/checkout", ``, http.StatusBadRequest},
+	})
+
+	var cart domain.Cart
+	serveData(r, http.MethodGet, "/carts/1", "", &cart)
+	var movements []domain.StockMovement
+	serveData(r, http.MethodGet, "/products/1/movements", "", &movements)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, domain.CartOpen, cart.Status)
+	assert.Zero(t, cart.SaleID)
+	assert.Equal(t, p[0].Quantity, after[0].Quantity)
+	if assert.Len(t, movements, 3) {
+		assert.Equal(t, domain.MovementSale, movements[1].Type)
+		assert.Equal(t, service.ReasonReversal, movements[2].Reason)
+		assert.Equal(t, 2, movements[2].Quantity)
+		assert.Equal(t, p[0].Quantity, movements[2].Balance)
+	}
+}
+
+func Test_Taxes_Breakdown(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPatch, "/products/2", `{"tax_class":"reduced"}`, http.StatusOK},
+		{http.MethodPatch, "/products/1", `{"tax_class":"luxury"}`, http.StatusBadRequest},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S60152S"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"M4637"}`, http.StatusOK},
+	})
+
+	var product domain.PricedProduct
+	serveData(r, http.MethodGet, "/products/2", "", &product)
+	var sale domain.Sale
+	rr := serveData(r, http.MethodPost, "/carts/1/checkout", "", &sale)
+
+	assert.Equal(t, 10.5, product.TaxRate)
+	assert.Equal(t, domain.Cents(31927), product.NetPrice)
+	assert.Equal(t, domain.Cents(3352), product.TaxAmount)
+	assert.Equal(t, p[1].Price, product.GrossPrice)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.True(t, sale.PricesIncludeTax)
+	assert.Equal(t, p[0].Price.Add(p[4].Price).Add(p[1].Price), sale.Total)
+	assert.Equal(t, domain.Cents(19153), sale.Tax)
+	if assert.Len(t, sale.Taxes, 2) {
+		assert.Equal(t, domain.TaxBreakdown{Class: domain.TaxStandard, Rate: 21, Net: domain.Cents(75243), Tax: domain.Cents(15801), Gross: domain.Cents(91044)}, sale.Taxes[0])
+		assert.Equal(t, domain.TaxBreakdown{Class: domain.TaxReduced, Rate: 10.5, Net: domain.Cents(31927), Tax: domain.Cents(3352), Gross: domain.Cents(35279)}, sale.Taxes[1])
+	}
+	if assert.Len(t, sale.Lines, 3) {
+		assert.Equal(t, domain.Cents(1240), sale.Lines[0].Tax)
+		assert.Equal(t, domain.Cents(14561), sale.Lines[1].Tax)
+		assert.Equal(t, domain.TaxReduced, sale.Lines[2].TaxClass)
+		assert.Equal(t, domain.Cents(3352), sale.Lines[2].Tax)
+	}
+}
+
+func Test_WeighedItems_PLU(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/products", `{"name":"Bananas","measure":5,"code_value":"BAN-KG","is_published":true,"expiration":"2099-12-31","price":2.4,"unit":"lb","plu":"4011"}`, http.StatusBadRequest},
+		{http.MethodPost, "/products", `{"name":"Bananas","measure":5,"code_value":"BAN-KG","is_published":true,"expiration":"2099-12-31","price":2.4,"unit":"KG","plu":"4011"}`, http.StatusCreated},
+		{http.MethodPatch, "/products/2", `{"plu":"04011"}`, http.StatusBadRequest},
+		{http.MethodPatch, "/products/2", `{"plu":"40"}`, http.StatusBadRequest},
+		{http.MethodPatch, "/products/501", `{"unit":"each"}`, http.StatusBadRequest},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"4011"}`, http.StatusBadRequest},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"4011","quantity":2}`, http.StatusBadRequest},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"4011","measure":0.3555}`, http.StatusBadRequest},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D","measure":0.5}`, http.StatusBadRequest},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"2504011012506"}`, http.StatusBadRequest},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"2504011012505","measure":1}`, http.StatusBadRequest},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"04011","measure":0.355}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"2504011012505"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"2004011006004"}`, http.StatusOK},
+	})
+
+	var cart domain.Cart
+	serveData(r, http.MethodGet, "/carts/1", "", &cart)
+	var sale domain.Sale
+	rr := serveData(r, http.MethodPost, "/carts/1/checkout", "", &sale)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	if assert.Len(t, cart.Lines, 3) {
+		measures := []string{"0.355", "1.25", "2.5"}
+		quantities := []int{355, 1250, 2500}
+		for i, line := range cart.Lines {
+			assert.Equal(t, "BAN-KG", line.CodeValue)
+			assert.Equal(t, domain.UnitKilogram, line.Unit)
+			assert.Equal(t, quantities[i], line.Quantity)
+			if assert.NotNil(t, line.Measure) {
+				assert.Equal(t, measures[i], line.Measure.String())
+			}
+		}
+		if assert.NotNil(t, cart.Lines[2].LabelPrice) {
+			assert.Equal(t, domain.Cents(600), *cart.Lines[2].LabelPrice)
+		}
+	}
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	if assert.Len(t, sale.Lines, 3) {
+		assert.Equal(t, domain.Cents(85), sale.Lines[0].Total)
+		assert.Equal(t, domain.Cents(300), sale.Lines[1].Total)
+		assert.Equal(t, domain.Cents(600), sale.Lines[2].Total)
+		assert.Equal(t, domain.Cents(240), sale.Lines[0].UnitPrice)
+	}
+	assert.Equal(t, domain.Cents(985), sale.Total)
+	if assert.Len(t, after, 501) {
+		assert.Equal(t, domain.UnitKilogram, after[500].Unit)
+		assert.Equal(t, "4011", after[500].PLU)
+		assert.Equal(t, 895, after[500].Quantity)
+	}
+}
+
+func Test_WeighedItems_Grams(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/products", `{"name":"Saffron","measure":1000,"code_value":"SAF-G","is_published":true,"expiration":"2099-12-31","price":0.02,"unit":"g","plu":"4200"}`, http.StatusCreated},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"2504200002508"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"2504200001006"}`, http.StatusOK},
+		{http.MethodDelete, "/carts/1/items/2504200001006", ``, http.StatusOK},
+	})
+
+	var cart domain.Cart
+	serveData(r, http.MethodGet, "/carts/1", "", &cart)
+	var sale domain.Sale
+	rr := serveData(r, http.MethodPost, "/carts/1/checkout", "", &sale)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	if assert.Len(t, cart.Lines, 1) {
+		assert.Equal(t, domain.UnitGram, cart.Lines[0].Unit)
+		assert.Equal(t, 250, cart.Lines[0].Quantity)
+		if assert.NotNil(t, cart.Lines[0].Measure) {
+			assert.Equal(t, "250", cart.Lines[0].Measure.String())
+		}
+	}
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, domain.Cents(500), sale.Total)
+	if assert.Len(t, after, 501) {
+		assert.Equal(t, 750, after[500].Quantity)
+	}
+}
+
+func Test_Carts_RemoveScanned(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPatch, "/products/1", `{"barcodes":[{"gtin":"036000291452"},{"gtin":"10036000291459","packaging":"case","units":12}]}`, http.StatusOK},
+		{http.MethodPost, "/products", `{"name":"Bananas","measure":5,"code_value":"BAN-KG","is_published":true,"expiration":"2099-12-31","price":2.4,"unit":"KG","plu":"4011"}`, http.StatusCreated},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"10036000291459"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"4011","measure":0.355}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"2504011012505"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"2004011006004"}`, http.StatusOK},
+		{http.MethodDelete, "/carts/1/items/036000291452?quantity=2", ``, http.StatusOK},
+		{http.MethodDelete, "/carts/1/items/0036000291452?quantity=1", ``, http.StatusOK},
+		{http.MethodDelete, "/carts/1/items/2004011006004", ``, http.StatusOK},
+		{http.MethodDelete, "/carts/1/items/2004011006004", ``, http.StatusNotFound},
+		{http.MethodDelete, "/carts/1/items/4011", ``, http.StatusOK},
+		{http.MethodDelete, "/carts/1/items/96385074", ``, http.StatusNotFound},
+	})
+
+	var cart domain.Cart
+	serveData(r, http.MethodGet, "/carts/1", "", &cart)
+
+	if assert.Len(t, cart.Lines, 2) {
+		assert.Equal(t, 1, cart.Lines[0].ProductID)
+		assert.Equal(t, 9, cart.Lines[0].Quantity)
+		assert.Equal(t, "BAN-KG", cart.Lines[1].CodeValue)
+		assert.Equal(t, 1250, cart.Lines[1].Quantity)
+	}
+}
+
+func Test_Carts_RemoveDeleted(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"M4637","quantity":2}`, http.StatusOK},
+		{http.MethodDelete, "/products/2", ``, http.StatusNoContent},
+		{http.MethodGet, "/carts/1/quote", ``, http.StatusBadRequest},
+		{http.MethodDelete, "/carts/1/items/M4637?quantity=1", ``, http.StatusOK},
+		{http.MethodDelete, "/carts/1/items/M4637", ``, http.StatusOK},
+		{http.MethodDelete, "/carts/1/items/M4637", ``, http.StatusNotFound},
+		{http.MethodGet, "/carts/1/quote", ``, http.StatusOK},
+	})
+
+	var cart domain.Cart
+	serveData(r, http.MethodGet, "/carts/1", "", &cart)
+
+	if assert.Len(t, cart.Lines, 1) {
+		assert.Equal(t, "S82254D", cart.Lines[0].CodeValue)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	categoryService service.CategoryService
+}
+
+func NewCategoryHandler(categoryService service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{categoryService}
+}
+
+func (h *CategoryHandler) GetAllCategories(ctx *gin.Context) {
+	categories, err := h.categoryService.GetAllCategories()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, categories)
+}
+
+// GetCategoryTree godoc
+// @Summary      Category tree
+// @Description  List top level categories with their subcategories nested under children
+// @Tags         categories
+// @Produce      json
+// @Param        token header string true "token"
+// @Success      200 {object}  web.response
+// @Router       /categories/tree [get]
+func (h *CategoryHandler) GetCategoryTree(ctx *gin.Context) {
+	tree, err := h.categoryService.GetCategoryTree()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, tree)
+}
+
+// GetCategorySummary godoc
+// @Summary      Inventory per category
+// @Description  Product count, total quantity and inventory value of each category, subcategories included
+// @Tags         categories
+// @Produce      json
+// @Param        token header string true "token"
+// @Success      200 {object}  web.response
+// @Router       /categories/summary [get]
+func (h *CategoryHandler) GetCategorySummary(ctx *gin.Context) {
+	summary, err := h.categoryService.GetCategorySummary()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, summary)
+}
+
+func (h *CategoryHandler) GetCategoryByID(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	category, err := h.categoryService.GetCategoryByID(id)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, category)
+}
+
+// CreateCategory godoc
+// @Summary      Create a category
+// @Description  Create a category, optionally below parent_id. The slug is derived from the name when omitted
+// @Tags         categories
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        category body domain.Category true "category"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /categories [post]
+func (h *CategoryHandler) CreateCategory(ctx *gin.Context) {
+	var category domain.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	created, err := h.categoryService.CreateCategory(&category)
+	if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 201, created)
+}
+
+func (h *CategoryHandler) UpdateCategory(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var category domain.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	err := h.categoryService.UpdateCategory(id, &category)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 200, category)
+}
+
+// DeleteCategory godoc
+// @Summary      Delete a category
+// @Description  Categories that still have subcategories or products cannot be deleted
+// @Tags         categories
+// @Param        token header string true "token"
+// @Success      204
+// @Failure      404 {object}  web.errorResponse
+// @Failure      409 {object}  web.errorResponse
+// @Router       /categories/{id} [delete]
+func (h *CategoryHandler) DeleteCategory(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	err := h.categoryService.DeleteCategory(id)
+	if errors.Is(err, service.ErrCategoryInUse) {
+		web.Failure(ctx, 409, err)
+		return
+	} else if errors.Is(err, repository.ErrCategoryNotFound) {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 204, nil)
+}
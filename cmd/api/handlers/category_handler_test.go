@@ -0,0 +1,85 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Categories_Hierarchy(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	bodies := []string{
+		`{"name":"Food"}`,
+		`{"name":"Dairy & Eggs","parent_id":1}`,
+		`{"name":"Cheese","parent_id":2}`,
+	}
+	for _, body := range bodies {
+		req, rr := createRequestTest(http.MethodPost, "/categories", body, "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, body)
+	}
+	for path, body := range map[string]string{"/products/1": `{"category_ids":[3]}`, "/products/2": `{"category_ids":[1]}`} {
+		req, rr := createRequestTest(http.MethodPatch, path, body, "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+	}
+	req, rr := createRequestTest(http.MethodPatch, "/products/3", `{"category_ids":[9]}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	counts := map[string]int{}
+	for _, category := range []string{"food", "dairy-eggs", "3"} {
+		req, rr := createRequestTest(http.MethodGet, "/products?category="+category, "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		listed := map[string][]domain.Product{}
+		_ = json.Unmarshal(rr.Body.Bytes(), &listed)
+		counts[category] = len(listed["data"])
+	}
+	req, rr = createRequestTest(http.MethodGet, "/products?category=toys", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var summary []service.CategorySummary
+	rr = serveData(r, http.MethodGet, "/categories/summary", "", &summary)
+
+	req, rr = createRequestTest(http.MethodPut, "/categories/1", `{"name":"Food","parent_id":3}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	req, rr = createRequestTest(http.MethodDelete, "/categories/2", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	var tree []service.CategoryNode
+	rr = serveData(r, http.MethodGet, "/categories/tree", "", &tree)
+
+	assert.Equal(t, map[string]int{"food": 2, "dairy-eggs": 1, "3": 1}, counts)
+	assert.Len(t, summary, 3)
+	assert.Equal(t, 2, summary[0].ProductCount)
+	assert.Equal(t, p[0].Quantity+p[1].Quantity, summary[0].TotalQuantity)
+	assert.Len(t, tree, 1)
+	assert.Equal(t, "cheese", tree[0].Children[0].Children[0].Slug)
+}
+
+func Test_Categories_WeighedValue(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/categories", `{"name":"Fruit"}`, http.StatusCreated},
+		{http.MethodPost, "/products", `{"name":"Bananas","measure":5,"code_value":"BAN-KG","is_published":true,"expiration":"2099-12-31","price":2.4,"unit":"kg","category_ids":[1]}`, http.StatusCreated},
+	})
+
+	var summary []service.CategorySummary
+	serveData(r, http.MethodGet, "/categories/summary", "", &summary)
+
+	if assert.Len(t, summary, 1) {
+		assert.Equal(t, 5000, summary[0].TotalQuantity)
+		assert.Equal(t, domain.Cents(1200), summary[0].InventoryValue)
+	}
+}
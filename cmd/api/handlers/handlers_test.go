@@ -0,0 +1,161 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/cmd/api/handlers"
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type response struct {
+	Data interface{} `json:"data"`
+}
+
+// createServer serves the API over copies of products_copy.json and
+// pricing_rules_copy.json, keeping every other collection in a temporary
+// directory. The collections named in unwritable read as empty but cannot be
+// saved, to exercise writes that fail.
+func createServer(t *testing.T, token string, unwritable ...string) *gin.Engine {
+
+	if token != "" {
+		err := os.Setenv("TOKEN", token)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	dir := t.TempDir()
+	path := func(name string) string {
+		if name == "pricing_rules.json" {
+			return fixture(t, "pricing_rules_copy.json")
+		}
+		for _, missing := range unwritable {
+			if name == missing {
+				// The file is saved through a temporary file in its
+				// directory, which does not exist.
+				return filepath.Join(dir, "missing", name)
+			}
+		}
+		return filepath.Join(dir, name)
+	}
+	services, err := handlers.NewServices(store.NewStore(fixture(t, "products_copy.json")), path, service.DefaultTaxPolicy(), path("exchange_rates.json"))
+	if err != nil {
+		panic(err)
+	}
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.Default()
+	handlers.RegisterRoutes(r, services)
+	return r
+}
+
+// step is one request of a scenario and the status it should get.
+type step struct {
+	method string
+	path   string
+	body   string
+	status int
+}
+
+// runSteps serves steps in order and checks the status of each.
+func runSteps(t *testing.T, r *gin.Engine, steps []step) {
+	t.Helper()
+	for _, step := range steps {
+		req, rr := createRequestTest(step.method, step.path, step.body, "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, step.status, rr.Code, step.method+" "+step.path+" "+step.body)
+	}
+}
+
+// serveData serves a request and decodes the data of the response into out.
+func serveData(r *gin.Engine, method string, path string, body string, out interface{}) *httptest.ResponseRecorder {
+	req, rr := createRequestTest(method, path, body, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	_ = json.Unmarshal(rr.Body.Bytes(), &struct {
+		Data interface{} `json:"data"`
+	}{out})
+	return rr
+}
+
+// fixtures holds the copies of the checked-in fixtures made for each test.
+var fixtures sync.Map
+
+// fixture returns the path of the test's own copy of the checked-in fixture
+// name, made in a temporary directory the first time it is asked for, so
+// tests never write to the tracked file.
+func fixture(t *testing.T, name string) string {
+	key := t.Name() + "/" + name
+	if path, ok := fixtures.Load(key); ok {
+		return path.(string)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		panic(err)
+	}
+	fixtures.Store(key, path)
+	t.Cleanup(func() { fixtures.Delete(key) })
+	return path
+}
+
+// fixtureProducts returns the products the test starts with.
+func fixtureProducts(t *testing.T) []domain.Product {
+	p, err := loadProducts(fixture(t, "products_copy.json"))
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func createRequestTest(method string, url string, body string, token string) (*http.Request, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, url, bytes.NewBuffer([]byte(body)))
+	req.Header.Add("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Add("TOKEN", token)
+	}
+	return req, httptest.NewRecorder()
+}
+
+func loadProducts(path string) ([]domain.Product, error) {
+	var products []domain.Product
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(file), &products)
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func writeProducts(path string, list []domain.Product) error {
+	bytes, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path, bytes, 0644)
+	if err != nil {
+		return err
+	}
+	// Drop the ID sequence so it is rebuilt from the restored products.
+	err = os.Remove(path + ".seq")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type LotHandler struct {
+	stockService service.StockService
+}
+
+func NewLotHandler(stockService service.StockService) *LotHandler {
+	return &LotHandler{stockService}
+}
+
+// GetLots godoc
+// @Summary      List the lots of a product
+// @Description  List a product's lots, first to expire first
+// @Tags         lots
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "product id"
+// @Success      200 {object}  web.response
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/{id}/lots [get]
+func (h *LotHandler) GetLots(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	lots, err := h.stockService.GetLots(id)
+	if err != nil && err.Error() == "Product not found" {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, lots)
+}
+
+// CreateLot godoc
+// @Summary      Receive a lot
+// @Description  Add a lot to a product's stock through a receipt movement. Existing stock without lots becomes an OPENING lot
+// @Tags         lots
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "product id"
+// @Param        lot body domain.Lot true "lot_number, quantity and expiration"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/{id}/lots [post]
+func (h *LotHandler) CreateLot(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var lot domain.Lot
+	if err := ctx.ShouldBindJSON(&lot); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	if lot.LotNumber == "" {
+		web.Failure(ctx, 400, errors.New("Lot number is required"))
+		return
+	}
+	movement, err := h.stockService.RecordMovement(id, &domain.StockMovement{
+		Type:       domain.MovementReceipt,
+		Quantity:   lot.Quantity,
+		Measure:    lot.Measure,
+		LotNumber:  lot.LotNumber,
+		Expiration: &lot.Expiration,
+	})
+	if err != nil && err.Error() == "Product not found" {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	lots, err := h.stockService.GetLots(id)
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	for _, stored := range lots {
+		if stored.ID == movement.Allocations[0].LotID {
+			web.Success(ctx, 201, stored)
+			return
+		}
+	}
+	web.Failure(ctx, 500, repository.ErrLotNotFound)
+}
+
+func (h *LotHandler) UpdateLot(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	lotID, errConverted := strconv.Atoi(ctx.Param("lot_id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var lot domain.Lot
+	if err := ctx.ShouldBindJSON(&lot); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	err := h.stockService.UpdateLot(id, lotID, &lot)
+	if errors.Is(err, repository.ErrLotNotFound) || (err != nil && err.Error() == "Product not found") {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 200, lot)
+}
+
+// RecallLots godoc
+// @Summary      Recall a lot
+// @Description  Find the lots with a lot number, their remaining stock and every movement that touched them
+// @Tags         lots
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        lot_number query string true "lot number"
+// @Param        product_id query int false "only this product"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /lots/recall [get]
+func (h *LotHandler) RecallLots(ctx *gin.Context) {
+	productID := 0
+	if raw, ok := ctx.GetQuery("product_id"); ok {
+		converted, errConverted := strconv.Atoi(raw)
+		if errConverted != nil {
+			web.Failure(ctx, 400, errConverted)
+			return
+		}
+		productID = converted
+	}
+	recalls, err := h.stockService.RecallLots(ctx.Query("lot_number"), productID)
+	if errors.Is(err, service.ErrInvalidQuery) {
+		web.Failure(ctx, 400, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, recalls)
+}
@@ -0,0 +1,117 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Lots_FEFO(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	for _, body := range []string{
+		`{"lot_number":"A","quantity":5,"expiration":"2031-01-01"}`,
+		`{"lot_number":"B","quantity":5,"expiration":"2030-06-01"}`,
+	} {
+		req, rr := createRequestTest(http.MethodPost, "/products/10/lots", body, "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, body)
+	}
+	req, rr := createRequestTest(http.MethodPost, "/products/10/lots", `{"lot_number":"C","quantity":5}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	sale := fmt.Sprintf(`{"type":"sale","quantity":%d}`, p[9].Quantity+3)
+	var movement domain.StockMovement
+	rr = serveData(r, http.MethodPost, "/products/10/movements", sale, &movement)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var product domain.Product
+	rr = serveData(r, http.MethodGet, "/products/10", "", &product)
+
+	req, rr = createRequestTest(http.MethodPatch, "/products/10", `{"expiration":"2040-01-01"}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var recalls []service.LotRecall
+	rr = serveData(r, http.MethodGet, "/lots/recall?lot_number=B", "", &recalls)
+
+	req, rr = createRequestTest(http.MethodPut, "/products/10/lots/3", `{"lot_number":"B","expiration":"2032-01-01"}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var lots []domain.Lot
+	rr = serveData(r, http.MethodGet, "/products/10/lots", "", &lots)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, []domain.LotAllocation{
+		{LotID: 1, LotNumber: service.OpeningLotNumber, Quantity: -p[9].Quantity},
+		{LotID: 3, LotNumber: "B", Quantity: -3},
+	}, movement.Allocations)
+	assert.Equal(t, 7, product.Quantity)
+	assert.Equal(t, "2030-06-01", product.Expiration.String())
+	assert.Len(t, recalls, 1)
+	assert.Equal(t, 5, recalls[0].Received)
+	assert.Equal(t, 3, recalls[0].Sold)
+	assert.Equal(t, 2, recalls[0].Quantity)
+	assert.Len(t, recalls[0].Movements, 2)
+	assert.Equal(t, []string{"OPENING", "A", "B"}, []string{lots[0].LotNumber, lots[1].LotNumber, lots[2].LotNumber})
+	assert.Equal(t, "2031-01-01", after[9].Expiration.String())
+}
+
+func Test_Lots_LedgerFailure(t *testing.T) {
+	r := createServer(t, "my-secret-value", "stock_movements.json")
+	p := fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/products/10/lots", `{"lot_number":"A","quantity":5,"expiration":"2031-01-01"}`, http.StatusBadRequest},
+	})
+
+	var lots []domain.Lot
+	serveData(r, http.MethodGet, "/products/10/lots", "", &lots)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Empty(t, lots)
+	assert.Equal(t, p[9].Quantity, after[9].Quantity)
+}
+
+func Test_Lots_ReconcileDrift(t *testing.T) {
+	fixtureProducts(t)
+	dir := t.TempDir()
+	productRepo, err := repository.NewProductRepository(store.NewStore(fixture(t, "products_copy.json")))
+	if err != nil {
+		panic(err)
+	}
+	lotRepo := repository.NewLotRepository(store.NewCollection[domain.Lot](filepath.Join(dir, "lots.json")))
+	movementRepo := repository.NewStockMovementRepository(store.NewCollection[domain.StockMovement](filepath.Join(dir, "stock_movements.json")))
+	stockService := service.NewStockService(productRepo, movementRepo, lotRepo)
+
+	expiration, _ := domain.ParseDate("2031-01-01")
+	_, err = stockService.RecordMovement(10, &domain.StockMovement{Type: domain.MovementReceipt, Quantity: 5, LotNumber: "A", Expiration: &expiration})
+	assert.NoError(t, err)
+	first, err := stockService.Reconcile()
+	assert.NoError(t, err)
+
+	lots, _ := lotRepo.GetLotsByProduct(10)
+	lots[0].Quantity -= 2
+	assert.NoError(t, lotRepo.SaveLots(&lots[0]))
+	product, _ := productRepo.GetProductByID(10)
+	second, err := stockService.Reconcile()
+	assert.NoError(t, err)
+
+	for _, correction := range first {
+		assert.Nil(t, correction.LotQuantity)
+	}
+	if assert.Len(t, second, 1) && assert.NotNil(t, second[0].LotQuantity) {
+		assert.Equal(t, 10, second[0].ProductID)
+		assert.Equal(t, product.Quantity, second[0].After)
+		assert.Equal(t, product.Quantity-2, *second[0].LotQuantity)
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type PriceHistoryHandler struct {
+	priceHistoryService service.PriceHistoryService
+}
+
+func NewPriceHistoryHandler(priceHistoryService service.PriceHistoryService) *PriceHistoryHandler {
+	return &PriceHistoryHandler{priceHistoryService}
+}
+
+// changeNote reads who makes a change from the user header and why from the
+// reason query parameter.
+func changeNote(ctx *gin.Context) service.ChangeNote {
+	by := strings.TrimSpace(ctx.GetHeader("user"))
+	if by == "" {
+		by = "anonymous"
+	}
+	return service.ChangeNote{By: by, Reason: strings.TrimSpace(ctx.Query("reason"))}
+}
+
+// parseMoment reads an RFC 3339 time, or a date meaning the end of that day
+// in local time.
+func parseMoment(value string) (time.Time, error) {
+	if moment, err := time.Parse(time.RFC3339, value); err == nil {
+		return moment, nil
+	}
+	date, err := domain.ParseDate(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("as_of must be an RFC 3339 time or a date such as 2024-06-01")
+	}
+	day := date.Time()
+	return time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 999999999, time.Local), nil
+}
+
+func priceHistoryErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, repository.ErrPriceChangeNotFound), err.Error() == "Product not found":
+		return 404
+	case errors.Is(err, service.ErrPriceChangeStatus):
+		return 409
+	}
+	return fallback
+}
+
+// GetPriceHistory godoc
+// @Summary      Price history of a product
+// @Description  List the applied, scheduled and cancelled price changes of a product in order of effect, and the price in effect at as_of
+// @Tags         pricing
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "product id"
+// @Param        as_of query string false "RFC 3339 time, or a date meaning the end of that day"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/{id}/prices [get]
+func (h *PriceHistoryHandler) GetPriceHistory(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var asOf *time.Time
+	if raw, ok := ctx.GetQuery("as_of"); ok {
+		moment, err := parseMoment(raw)
+		if err != nil {
+			web.Failure(ctx, 400, err)
+			return
+		}
+		asOf = &moment
+	}
+	history, err := h.priceHistoryService.GetPriceHistory(id, asOf)
+	if err != nil {
+		web.Failure(ctx, priceHistoryErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, history)
+}
+
+type priceChangeRequest struct {
+	NewPrice    domain.Money `json:"new_price" swaggertype:"number"`
+	EffectiveAt time.Time    `json:"effective_at"`
+	Reason      string       `json:"reason"`
+}
+
+// SchedulePriceChange godoc
+// @Summary      Change a price
+// @Description  Change the price of a product at effective_at, or right away when it is empty or past. The user header says who makes the change.
+// @Tags         pricing
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        user header string false "who makes the change"
+// @Param        id path int true "product id"
+// @Param        change body priceChangeRequest true "new price, when and why"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/{id}/prices [post]
+func (h *PriceHistoryHandler) SchedulePriceChange(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var request priceChangeRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	change := domain.PriceChange{
+		NewPrice:    request.NewPrice,
+		EffectiveAt: request.EffectiveAt,
+		ChangedBy:   changeNote(ctx).By,
+		Reason:      strings.TrimSpace(request.Reason),
+	}
+	created, err := h.priceHistoryService.SchedulePriceChange(id, &change)
+	if err != nil {
+		web.Failure(ctx, priceHistoryErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 201, created)
+}
+
+func (h *PriceHistoryHandler) CancelPriceChange(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	changeID, errConverted := strconv.Atoi(ctx.Param("change_id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	if err := h.priceHistoryService.CancelPriceChange(id, changeID); err != nil {
+		web.Failure(ctx, priceHistoryErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 204, nil)
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PriceHistory_Timeline(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	updated := p[0]
+	updated.Price = domain.Cents(7500)
+	body, _ := json.Marshal(updated)
+	req, rr := createRequestTest(http.MethodPut, "/products/1?reason=Cost+increase", string(body), "my-secret-value")
+	req.Header.Add("user", "jane")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	soon := time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano)
+	later := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	runSteps(t, r, []step{
+		{http.MethodPatch, "/products/1", `{"price":76.5}`, http.StatusOK},
+		{http.MethodPatch, "/products/1", `{"name":"Same price"}`, http.StatusOK},
+		{http.MethodPost, "/products/1/prices", `{"new_price":80,"effective_at":"` + later + `","reason":"Summer"}`, http.StatusCreated},
+		{http.MethodPost, "/products/1/prices", `{"new_price":90,"effective_at":"` + soon + `"}`, http.StatusCreated},
+		{http.MethodPost, "/products/1/prices", `{"new_price":0}`, http.StatusBadRequest},
+		{http.MethodPost, "/products/100000/prices", `{"new_price":10}`, http.StatusNotFound},
+		{http.MethodGet, "/products/1/prices?as_of=yesterday", ``, http.StatusBadRequest},
+	})
+
+	history := func(query string) service.PriceHistory {
+		req, rr := createRequestTest(http.MethodGet, "/products/1/prices"+query, "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, query)
+		actual := map[string]service.PriceHistory{}
+		_ = json.Unmarshal(rr.Body.Bytes(), &actual)
+		return actual["data"]
+	}
+
+	before := history("?as_of=2020-01-01")
+	assert.Equal(t, domain.Cents(7650), before.CurrentPrice)
+	assert.Equal(t, p[0].Price, *before.PriceAsOf)
+	if assert.Len(t, before.Changes, 4) {
+		first := before.Changes[0]
+		assert.Equal(t, p[0].Price, first.OldPrice)
+		assert.Equal(t, domain.Cents(7500), first.NewPrice)
+		assert.Equal(t, "jane", first.ChangedBy)
+		assert.Equal(t, "Cost increase", first.Reason)
+		assert.Equal(t, domain.PriceChangeApplied, first.Status)
+		assert.Equal(t, "anonymous", before.Changes[1].ChangedBy)
+		assert.Equal(t, []int{4, 3}, []int{before.Changes[2].ID, before.Changes[3].ID})
+		assert.Equal(t, domain.PriceChangeScheduled, before.Changes[3].Status)
+	}
+	assert.Equal(t, domain.Cents(8000), *history("?as_of=" + url.QueryEscape(time.Now().Add(2*time.Hour).Format(time.RFC3339))).PriceAsOf)
+
+	for _, step := range []struct {
+		path   string
+		status int
+	}{
+		{"/products/1/prices/3", http.StatusNoContent},
+		{"/products/1/prices/3", http.StatusConflict},
+		{"/products/2/prices/4", http.StatusNotFound},
+	} {
+		req, rr := createRequestTest(http.MethodDelete, step.path, "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, step.status, rr.Code, step.path)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	after := history("?as_of=" + url.QueryEscape(time.Now().Add(2*time.Hour).Format(time.RFC3339)))
+
+	assert.Equal(t, domain.Cents(9000), after.CurrentPrice)
+	assert.Equal(t, domain.Cents(9000), *after.PriceAsOf)
+	if assert.Len(t, after.Changes, 4) {
+		assert.Equal(t, domain.PriceChangeApplied, after.Changes[2].Status)
+		assert.Equal(t, domain.Cents(7650), after.Changes[2].OldPrice)
+		assert.Equal(t, domain.PriceChangeCancelled, after.Changes[3].Status)
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type PriceListHandler struct {
+	currencyService service.CurrencyService
+}
+
+func NewPriceListHandler(currencyService service.CurrencyService) *PriceListHandler {
+	return &PriceListHandler{currencyService}
+}
+
+func (h *PriceListHandler) GetExchangeRates(ctx *gin.Context) {
+	rates, err := h.currencyService.GetAllRates()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, rates)
+}
+
+type exchangeRateRequest struct {
+	Rate float64 `json:"rate" binding:"required"`
+}
+
+// SetExchangeRate godoc
+// @Summary      Set an exchange rate
+// @Description  Set how many units of the currency one unit of the default currency buys
+// @Tags         pricing
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        currency path string true "three letter currency code"
+// @Param        rate body exchangeRateRequest true "rate"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /exchange-rates/{currency} [put]
+func (h *PriceListHandler) SetExchangeRate(ctx *gin.Context) {
+	var request exchangeRateRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	rate := domain.ExchangeRate{Currency: domain.Currency(ctx.Param("currency")), Rate: request.Rate}
+	if err := h.currencyService.SetRate(&rate); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 200, rate)
+}
+
+func (h *PriceListHandler) DeleteExchangeRate(ctx *gin.Context) {
+	err := h.currencyService.DeleteRate(ctx.Param("currency"))
+	if errors.Is(err, repository.ErrExchangeRateNotFound) {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 204, nil)
+}
+
+func (h *PriceListHandler) GetAllPriceLists(ctx *gin.Context) {
+	priceLists, err := h.currencyService.GetAllPriceLists()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, priceLists)
+}
+
+func (h *PriceListHandler) GetPriceListByID(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	priceList, err := h.currencyService.GetPriceListByID(id)
+	if err != nil {
+		web.Failure(ctx, 404, err)
+		return
+	}
+	web.Success(ctx, 200, priceList)
+}
+
+// CreatePriceList godoc
+// @Summary      Create a price list
+// @Description  Create fixed prices for products sold in a currency, optionally only in one store
+// @Tags         pricing
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        priceList body domain.PriceList true "price list"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /price-lists [post]
+func (h *PriceListHandler) CreatePriceList(ctx *gin.Context) {
+	var priceList domain.PriceList
+	if err := ctx.ShouldBindJSON(&priceList); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	created, err := h.currencyService.CreatePriceList(&priceList)
+	if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 201, created)
+}
+
+func (h *PriceListHandler) UpdatePriceList(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var priceList domain.PriceList
+	if err := ctx.ShouldBindJSON(&priceList); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	err := h.currencyService.UpdatePriceList(id, &priceList)
+	if errors.Is(err, repository.ErrPriceListNotFound) {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 200, priceList)
+}
+
+func (h *PriceListHandler) DeletePriceList(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	if err := h.currencyService.DeletePriceList(id); err != nil {
+		web.Failure(ctx, 404, err)
+		return
+	}
+	web.Success(ctx, 204, nil)
+}
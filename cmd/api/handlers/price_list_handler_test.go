@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PriceLists_Currency(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+
+	runSteps(t, r, []step{
+		{http.MethodGet, "/products/1?currency=EUR", ``, http.StatusBadRequest},
+		{http.MethodGet, "/products/1?currency=EURO", ``, http.StatusBadRequest},
+		{http.MethodPut, "/exchange-rates/USD", `{"rate":2}`, http.StatusBadRequest},
+		{http.MethodPut, "/exchange-rates/eur", `{"rate":-1}`, http.StatusBadRequest},
+		{http.MethodPut, "/exchange-rates/eur", `{"rate":0.92}`, http.StatusOK},
+		{http.MethodPost, "/price-lists", `{"name":"Euro","currency":"eur","prices":[{"product_id":2,"price":320}]}`, http.StatusCreated},
+		{http.MethodPost, "/price-lists", `{"name":"Euro again","currency":"EUR","prices":[]}`, http.StatusBadRequest},
+		{http.MethodPost, "/price-lists", `{"name":"Madrid","currency":"EUR","store":"madrid","prices":[{"product_id":2,"price":310},{"product_id":2,"price":300}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/price-lists", `{"name":"Madrid","currency":"EUR","store":"madrid","prices":[{"product_id":100000,"price":310}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/price-lists", `{"name":"Madrid","currency":"EUR","store":"madrid","prices":[{"product_id":2,"price":310}]}`, http.StatusCreated},
+		{http.MethodPost, "/price-lists", `{"name":"Pounds","currency":"GBP","prices":[{"product_id":2,"price":280}]}`, http.StatusCreated},
+		{http.MethodGet, "/products/1?currency=GBP", ``, http.StatusBadRequest},
+		{http.MethodDelete, "/exchange-rates/JPY", ``, http.StatusNotFound},
+	})
+
+	get := func(path string) domain.PricedProduct {
+		req, rr := createRequestTest(http.MethodGet, path, "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+		product := map[string]domain.PricedProduct{}
+		_ = json.Unmarshal(rr.Body.Bytes(), &product)
+		return product["data"]
+	}
+
+	converted := get("/products/1?currency=eur")
+	assert.Equal(t, domain.Currency("EUR"), converted.Currency)
+	assert.Equal(t, domain.Cents(6571), converted.Price)
+	assert.Equal(t, 0.92, converted.ExchangeRate)
+	assert.Equal(t, converted.Price, converted.GrossPrice)
+
+	listed := get("/products/2?currency=EUR")
+	assert.Equal(t, 1, listed.PriceListID)
+	assert.Equal(t, domain.Cents(32000), listed.Price)
+	assert.Equal(t, domain.Cents(26446), listed.NetPrice)
+	assert.Equal(t, domain.Cents(5554), listed.TaxAmount)
+	assert.Equal(t, domain.Cents(31000), get("/products/2?currency=EUR&store=madrid").Price)
+	assert.Equal(t, domain.Cents(32000), get("/products/2?currency=EUR&store=lisbon").Price)
+	assert.Equal(t, domain.Cents(28000), get("/products/2?currency=GBP").Price)
+	assert.Equal(t, domain.Currency("USD"), get("/products/2").Currency)
+
+	req, rr := createRequestTest(http.MethodGet, "/products?currency=EUR&fields=id,price,currency,price_list_id&limit=2", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `{"currency":"EUR","id":1,"price":65.71,"price_list_id":null}`)
+	assert.Contains(t, rr.Body.String(), `{"currency":"EUR","id":2,"price":320,"price_list_id":1}`)
+}
+
+func Test_PriceLists_MarkedDownInCurrency(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	edited := make([]domain.Product, len(p))
+	copy(edited, p)
+	edited[0].Expiration = domain.Today()
+	edited[1].Expiration = domain.Today()
+	edited[1].TaxClass = domain.TaxReduced
+	if err := writeProducts(fixture(t, "products_copy.json"), edited); err != nil {
+		panic(err)
+	}
+	runSteps(t, r, []step{
+		{http.MethodPut, "/exchange-rates/EUR", `{"rate":0.92}`, http.StatusOK},
+		{http.MethodPost, "/price-lists", `{"name":"Euro","currency":"EUR","prices":[{"product_id":2,"price":320}]}`, http.StatusCreated},
+	})
+
+	// Markdowns and tax on localized prices must stay in the currency; mixing
+	// it with dollars would panic and answer 500.
+	var converted, listed domain.PricedProduct
+	rr := serveData(r, http.MethodGet, "/products/1?currency=EUR", "", &converted)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = serveData(r, http.MethodGet, "/products/2?currency=EUR", "", &listed)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	for _, path := range []string{"/products?currency=EUR", "/products?currency=EUR&store=madrid&limit=5"} {
+		req, rr := createRequestTest(http.MethodGet, path, "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+	}
+
+	for _, product := range []domain.PricedProduct{converted, listed} {
+		if assert.NotNil(t, product.AppliedRule) {
+			assert.Equal(t, "Last day", product.AppliedRule.Name)
+		}
+		assert.Equal(t, domain.Currency("EUR"), product.Currency)
+		assert.Equal(t, product.EffectivePrice, product.GrossPrice)
+		assert.Equal(t, product.GrossPrice, product.NetPrice.Add(product.TaxAmount))
+	}
+	assert.Equal(t, domain.Cents(3285), converted.EffectivePrice)
+	assert.Equal(t, domain.Cents(16000), listed.EffectivePrice)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type PricingHandler struct {
+	pricingService service.PricingService
+}
+
+func NewPricingHandler(pricingService service.PricingService) *PricingHandler {
+	return &PricingHandler{pricingService}
+}
+
+func (h *PricingHandler) GetAllRules(ctx *gin.Context) {
+	rules, err := h.pricingService.GetAllRules()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, rules)
+}
+
+func (h *PricingHandler) GetRuleByID(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	rule, err := h.pricingService.GetRuleByID(id)
+	if err != nil {
+		web.Failure(ctx, 404, err)
+		return
+	}
+	web.Success(ctx, 200, rule)
+}
+
+// CreateRule godoc
+// @Summary      Create a markdown pricing rule
+// @Description  Discount products expiring within within_days days by discount_percent. A rule sent without active is active
+// @Tags         pricing
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        rule body domain.PricingRule true "rule"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /pricing/rules [post]
+func (h *PricingHandler) CreateRule(ctx *gin.Context) {
+	var rule domain.PricingRule
+	if err := ctx.ShouldBindJSON(&rule); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	created, err := h.pricingService.CreateRule(&rule)
+	if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 201, created)
+}
+
+func (h *PricingHandler) UpdateRule(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var rule domain.PricingRule
+	if err := ctx.ShouldBindJSON(&rule); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	err := h.pricingService.UpdateRule(id, &rule)
+	if errors.Is(err, repository.ErrPricingRuleNotFound) {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 200, rule)
+}
+
+func (h *PricingHandler) DeleteRule(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	if err := h.pricingService.DeleteRule(id); err != nil {
+		web.Failure(ctx, 404, err)
+		return
+	}
+	web.Success(ctx, 204, nil)
+}
+
+// Preview godoc
+// @Summary      Preview markdown pricing
+// @Description  Apply the given rules, or the stored rules when the body is empty, to the catalog without saving anything
+// @Tags         pricing
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        rules body []domain.PricingRule false "rules to try"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /pricing/preview [post]
+func (h *PricingHandler) Preview(ctx *gin.Context) {
+	var rules []domain.PricingRule
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&rules); err != nil {
+			web.Failure(ctx, 400, err)
+			return
+		}
+	}
+	preview, err := h.pricingService.Preview(rules)
+	if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 200, preview)
+}
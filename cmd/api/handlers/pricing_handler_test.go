@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GetProductByID_EffectivePrice(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	edited := make([]domain.Product, len(p))
+	copy(edited, p)
+	edited[0].Expiration = domain.Today()
+	edited[1].Expiration = domain.Today().AddDays(2)
+	if err := writeProducts(fixture(t, "products_copy.json"), edited); err != nil {
+		panic(err)
+	}
+
+	actual := []map[string]domain.PricedProduct{}
+	for _, id := range []string{"1", "2", "3"} {
+		req, rr := createRequestTest(http.MethodGet, "/products/"+id, "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		priced := map[string]domain.PricedProduct{}
+		_ = json.Unmarshal(rr.Body.Bytes(), &priced)
+		actual = append(actual, priced)
+	}
+
+	assert.Equal(t, p[0].Price.Percent(50), actual[0]["data"].EffectivePrice)
+	assert.Equal(t, "Last day", actual[0]["data"].AppliedRule.Name)
+	assert.Equal(t, p[1].Price.Percent(70), actual[1]["data"].EffectivePrice)
+	assert.Equal(t, p[1].Price, actual[1]["data"].Price)
+	assert.Equal(t, p[2].Price, actual[2]["data"].EffectivePrice)
+	assert.Nil(t, actual[2]["data"].AppliedRule)
+}
+
+func Test_FilterAndSearch_EffectivePrice(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	edited := make([]domain.Product, len(p))
+	copy(edited, p)
+	edited[0].Expiration = domain.Today()
+	if err := writeProducts(fixture(t, "products_copy.json"), edited); err != nil {
+		panic(err)
+	}
+
+	type match struct {
+		domain.PricedProduct
+		Score float64 `json:"score"`
+	}
+	find := func(path string) match {
+		var matches []match
+		rr := serveData(r, http.MethodGet, path, "", &matches)
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+		for _, m := range matches {
+			if m.ID == 1 {
+				return m
+			}
+		}
+		t.Errorf("%s: product 1 not found", path)
+		return match{}
+	}
+
+	paths := []string{
+		"/products/filter?where=" + url.QueryEscape(`code_value="`+p[0].CodeValue+`"`),
+		"/products/filter?price=" + p[0].Price.Sub(domain.Cents(1)).String(),
+		"/products/search?q=" + url.QueryEscape(p[0].Name),
+	}
+	for _, path := range paths {
+		m := find(path)
+		assert.Equal(t, p[0].Price, m.Price, path)
+		assert.Equal(t, p[0].Price.Percent(50), m.EffectivePrice, path)
+		if assert.NotNil(t, m.AppliedRule, path) {
+			assert.Equal(t, "Last day", m.AppliedRule.Name, path)
+		}
+		assert.NotZero(t, m.TaxRate, path)
+	}
+	assert.Greater(t, find(paths[2]).Score, 0.0)
+}
+
+func Test_PricingPreview_DryRun(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	rules, err := os.ReadFile(fixture(t, "pricing_rules_copy.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	body := `[{"name":"Everything expired","within_days":100000,"discount_percent":10,"active":true}]`
+	req, rr := createRequestTest(http.MethodPost, "/pricing/preview", body, "my-secret-value")
+	r.ServeHTTP(rr, req)
+
+	actual := map[string]service.PricingPreview{}
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &actual))
+	assert.Equal(t, actual["data"].AffectedProducts, len(actual["data"].Products))
+	assert.Equal(t, actual["data"].ListValue.Sub(actual["data"].EffectiveValue), actual["data"].MarkdownValue)
+
+	after, _ := os.ReadFile(fixture(t, "pricing_rules_copy.json"))
+	assert.Equal(t, rules, after)
+}
+
+func Test_PricingRules_CRUD(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+
+	req, rr := createRequestTest(http.MethodPost, "/pricing/rules", `{"name":"Week","within_days":7,"discount_percent":10,"active":true}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	created := map[string]domain.PricingRule{}
+	_ = json.Unmarshal(rr.Body.Bytes(), &created)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, 3, created["data"].ID)
+
+	req, rr = createRequestTest(http.MethodPost, "/pricing/rules", `{"name":"Free","within_days":1,"discount_percent":150}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	req, rr = createRequestTest(http.MethodDelete, "/pricing/rules/3", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	req, rr = createRequestTest(http.MethodPut, "/pricing/rules/3", `{"name":"Week","within_days":7,"discount_percent":10}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func Test_PricingPreview_WeighedValue(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	body := `[{"name":"Last day","within_days":1,"discount_percent":10,"active":true}]`
+	var before, after service.PricingPreview
+	serveData(r, http.MethodPost, "/pricing/preview", body, &before)
+	runSteps(t, r, []step{
+		{http.MethodPost, "/products", `{"name":"Bananas","measure":5,"code_value":"BAN-KG","is_published":true,"expiration":"2099-12-31","price":2.4,"unit":"kg"}`, http.StatusCreated},
+	})
+	serveData(r, http.MethodPost, "/pricing/preview", body, &after)
+
+	assert.Equal(t, domain.Cents(1200), after.ListValue.Sub(before.ListValue))
+	assert.Equal(t, domain.Cents(1200), after.EffectiveValue.Sub(before.EffectiveValue))
+}
+
+func Test_PricingRules_DefaultActive(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+
+	var created, paused, replaced domain.PricingRule
+	serveData(r, http.MethodPost, "/pricing/rules", `{"name":"Week","within_days":7,"discount_percent":10}`, &created)
+	serveData(r, http.MethodPost, "/pricing/rules", `{"name":"Month","within_days":30,"discount_percent":5,"active":false}`, &paused)
+	serveData(r, http.MethodPut, "/pricing/rules/4", `{"name":"Month","within_days":30,"discount_percent":5}`, &replaced)
+
+	if assert.NotNil(t, created.Active) && assert.NotNil(t, paused.Active) && assert.NotNil(t, replaced.Active) {
+		assert.True(t, *created.Active)
+		assert.False(t, *paused.Active)
+		assert.True(t, *replaced.Active)
+	}
+
+	fixtureProducts(t)
+	runSteps(t, r, []step{
+		{http.MethodPost, "/products", `{"name":"Bananas","measure":5,"code_value":"BAN-KG","is_published":true,"expiration":"2099-12-31","price":2.4,"unit":"kg"}`, http.StatusCreated},
+	})
+	var preview service.PricingPreview
+	serveData(r, http.MethodPost, "/pricing/preview", `[{"name":"Everything","within_days":100000,"discount_percent":10}]`, &preview)
+	assert.NotZero(t, preview.AffectedProducts)
+}
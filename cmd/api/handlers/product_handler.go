@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/NPG27/supermarket_dop/internal/domain"
@@ -10,22 +11,104 @@ import (
 )
 
 type ProductHandler struct {
-	productService service.ProductService
+	productService  service.ProductService
+	pricingService  service.PricingService
+	currencyService service.CurrencyService
 }
 
-func NewProductHandler(productService service.ProductService) *ProductHandler {
-	return &ProductHandler{productService}
+func NewProductHandler(productService service.ProductService, pricingService service.PricingService, currencyService service.CurrencyService) *ProductHandler {
+	return &ProductHandler{productService, pricingService, currencyService}
 }
 
+// localize reprices products in the currency and store of the request, if
+// any were asked for.
+func (h *ProductHandler) localize(ctx *gin.Context, priced []domain.PricedProduct) (int, error) {
+	currency, store := ctx.Query("currency"), ctx.Query("store")
+	if currency == "" && store == "" {
+		return 0, nil
+	}
+	err := h.currencyService.LocalizeProducts(priced, currency, store)
+	if errors.Is(err, service.ErrUnknownCurrency) {
+		return 400, err
+	} else if err != nil {
+		return 500, err
+	}
+	return 0, nil
+}
+
+// GetAllProducts godoc
+// @Summary      List products
+// @Description  List products, optionally sorted, paginated and with a subset of fields
+// @Tags         products
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        category query string false "category ID or slug, includes its subcategories"
+// @Param        sort query string false "comma separated fields, prefix with - for descending (e.g. price,-expiration)"
+// @Param        limit query int false "page size, enables pagination"
+// @Param        offset query int false "number of products to skip, enables pagination"
+// @Param        cursor query string false "opaque cursor taken from a previous page"
+// @Param        fields query string false "comma separated fields to return (e.g. id,name,price,effective_price)"
+// @Param        currency query string false "currency to price in, from its price list or converted at the exchange rate"
+// @Param        store query string false "store whose price list to use"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /products [get]
 func (h *ProductHandler) GetAllProducts(ctx *gin.Context) {
-	products, err := h.productService.GetAllProducts()
+	query, err := productQueryFromRequest(ctx)
 	if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	fields, err := parseFields(ctx.Query("fields"))
+	if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	page, err := h.productService.ListProducts(query)
+	if errors.Is(err, service.ErrInvalidQuery) {
+		web.Failure(ctx, 400, err)
+		return
+	} else if err != nil {
 		web.Failure(ctx, 500, err)
 		return
 	}
-	web.Success(ctx, 200, products)
+	priced, err := h.pricingService.PriceProducts(page.Products)
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	if status, err := h.localize(ctx, priced); err != nil {
+		web.Failure(ctx, status, err)
+		return
+	}
+	data := selectFields(priced, fields)
+	if !query.Paginate {
+		web.Success(ctx, 200, data)
+		return
+	}
+	web.SuccessPage(ctx, 200, data, web.Meta{
+		Total:      page.Total,
+		Count:      len(page.Products),
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}, pageLinks(ctx, query, page))
 }
 
+// GetProductByID godoc
+// @Summary      Get a product
+// @Description  Get a product with its effective price and tax, optionally in another currency or for a store
+// @Tags         products
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "product id"
+// @Param        currency query string false "currency to price in, from its price list or converted at the exchange rate"
+// @Param        store query string false "store whose price list to use"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/{id} [get]
 func (h *ProductHandler) GetProductByID(ctx *gin.Context) {
 	id := ctx.Param("id")
 	idConverted, errConverted := strconv.Atoi(id)
@@ -38,18 +121,182 @@ func (h *ProductHandler) GetProductByID(ctx *gin.Context) {
 		web.Failure(ctx, 404, err)
 		return
 	}
-	web.Success(ctx, 200, product)
+	priced, err := h.pricingService.PriceProduct(product)
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	localized := []domain.PricedProduct{priced}
+	if status, err := h.localize(ctx, localized); err != nil {
+		web.Failure(ctx, status, err)
+		return
+	}
+	web.Success(ctx, 200, localized[0])
+}
+
+// barcodeMatch is a product found by barcode and the barcode that matched.
+type barcodeMatch struct {
+	Product domain.PricedProduct `json:"product"`
+	Barcode domain.Barcode       `json:"barcode"`
 }
 
+// GetProductByBarcode godoc
+// @Summary      Find a product by barcode
+// @Description  Look a product up by any of its barcodes, given as EAN-8, UPC-A, EAN-13 or GTIN-14. The matched barcode tells whether it is a unit or a case.
+// @Tags         products
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        gtin path string true "barcode digits"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/barcode/{gtin} [get]
+func (h *ProductHandler) GetProductByBarcode(ctx *gin.Context) {
+	product, barcode, err := h.productService.GetProductByBarcode(ctx.Param("gtin"))
+	if errors.Is(err, domain.ErrInvalidGTIN) {
+		web.Failure(ctx, 400, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 404, err)
+		return
+	}
+	priced, err := h.pricingService.PriceProduct(product)
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, barcodeMatch{Product: priced, Barcode: barcode})
+}
+
+// GetProductByPriceGreaterThan godoc
+// @Summary      Filter products
+// @Description  Filter products with an expression such as price>=10 AND is_published=true AND name~"milk".
+// @Description  Supports AND, OR, NOT, parentheses and the operators = != > >= < <= ~ (contains).
+// @Description  The legacy price parameter returns products with a greater price.
+// @Description  Products come with their effective price and tax.
+// @Tags         products
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        where query string false "filter expression"
+// @Param        price query number false "minimum price (exclusive)"
+// @Param        currency query string false "currency to price in, from its price list or converted at the exchange rate"
+// @Param        store query string false "store whose price list to use"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /products/filter [get]
 func (h *ProductHandler) GetProductByPriceGreaterThan(ctx *gin.Context) {
-	price := ctx.Query("price")
-	priceConverted, errConverted := strconv.ParseFloat(price, 64)
-	if errConverted != nil {
-		web.Failure(ctx, 400, errConverted)
+	var products []domain.Product
+	if where, ok := ctx.GetQuery("where"); ok {
+		filtered, err := h.productService.FilterProducts(where)
+		if errors.Is(err, service.ErrInvalidQuery) {
+			web.Failure(ctx, 400, err)
+			return
+		} else if err != nil {
+			web.Failure(ctx, 500, err)
+			return
+		}
+		products = filtered
+	} else {
+		price := ctx.Query("price")
+		priceConverted, errConverted := domain.ParseMoney(price)
+		if errConverted != nil {
+			web.Failure(ctx, 400, errConverted)
+			return
+		}
+		products = h.productService.GetProductByPriceGreaterThan(priceConverted)
+	}
+	priced, err := h.pricingService.PriceProducts(products)
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	if status, err := h.localize(ctx, priced); err != nil {
+		web.Failure(ctx, status, err)
+		return
+	}
+	web.Success(ctx, 200, priced)
+}
+
+// searchMatch is a product found by search, priced, and how well it matched.
+type searchMatch struct {
+	domain.PricedProduct
+	Score float64 `json:"score"`
+}
+
+// SearchProducts godoc
+// @Summary      Search products by name
+// @Description  Full-text search over product names with prefix and typo-tolerant matching, best matches first.
+// @Description  Products come with their effective price and tax.
+// @Tags         products
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        q query string true "search terms"
+// @Param        limit query int false "maximum number of results"
+// @Param        currency query string false "currency to price in, from its price list or converted at the exchange rate"
+// @Param        store query string false "store whose price list to use"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /products/search [get]
+func (h *ProductHandler) SearchProducts(ctx *gin.Context) {
+	limit := 0
+	if raw, ok := ctx.GetQuery("limit"); ok {
+		limitConverted, errConverted := strconv.Atoi(raw)
+		if errConverted != nil {
+			web.Failure(ctx, 400, errConverted)
+			return
+		}
+		limit = limitConverted
+	}
+	results, err := h.productService.SearchProducts(ctx.Query("q"), limit)
+	if errors.Is(err, service.ErrInvalidQuery) {
+		web.Failure(ctx, 400, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	products := make([]domain.Product, len(results))
+	for i, result := range results {
+		products[i] = result.Product
+	}
+	priced, err := h.pricingService.PriceProducts(products)
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	if status, err := h.localize(ctx, priced); err != nil {
+		web.Failure(ctx, status, err)
+		return
+	}
+	matches := make([]searchMatch, len(results))
+	for i, result := range results {
+		matches[i] = searchMatch{PricedProduct: priced[i], Score: result.Score}
+	}
+	web.Success(ctx, 200, matches)
+}
+
+// GetExpiringProducts godoc
+// @Summary      List expiring products
+// @Description  List products already expired or expiring within the window, soonest first
+// @Tags         products
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        within query string false "window such as 7d or 2w (default 7d)"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /products/expiring [get]
+func (h *ProductHandler) GetExpiringProducts(ctx *gin.Context) {
+	windowDays, err := service.ParseDays(ctx.DefaultQuery("within", "7d"))
+	if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	alerts, err := h.productService.GetExpiringProducts(windowDays)
+	if err != nil {
+		web.Failure(ctx, 500, err)
 		return
 	}
-	products := h.productService.GetProductByPriceGreaterThan(priceConverted)
-	web.Success(ctx, 200, products)
+	web.Success(ctx, 200, alerts)
 }
 
 // Post godoc
@@ -89,7 +336,7 @@ func (h *ProductHandler) UpdateProduct(ctx *gin.Context) {
 		web.Failure(ctx, 400, err)
 		return
 	}
-	err := h.productService.UpdateProduct(idConverted, &product)
+	err := h.productService.UpdateProduct(idConverted, &product, changeNote(ctx))
 	if err != nil && err.Error() == "Product not found" {
 		web.Failure(ctx, 404, err)
 		return
@@ -111,7 +358,7 @@ func (h *ProductHandler) PatchProduct(ctx *gin.Context) {
 		web.Failure(ctx, 400, err)
 		return
 	}
-	err := h.productService.PatchProduct(idConverted, &product)
+	err := h.productService.PatchProduct(idConverted, &product, changeNote(ctx))
 	if err != nil && err.Error() == "Product not found" {
 		web.Failure(ctx, 404, err)
 		return
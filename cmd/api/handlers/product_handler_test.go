@@ -1,98 +1,28 @@
 package handlers_test
 
 import (
-	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
-	"net/http/httptest"
-	"os"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/NPG27/supermarket_dop/cmd/api/handlers"
 	"github.com/NPG27/supermarket_dop/internal/domain"
-	"github.com/NPG27/supermarket_dop/internal/middleware"
-	"github.com/NPG27/supermarket_dop/internal/repository"
 	"github.com/NPG27/supermarket_dop/internal/service"
-	"github.com/NPG27/supermarket_dop/pkg/store"
-	"github.com/gin-gonic/gin"
+	"github.com/NPG27/supermarket_dop/pkg/web"
 	"github.com/stretchr/testify/assert"
 )
 
-type response struct {
-	Data interface{} `json:"data"`
-}
-
-func createServer(token string) *gin.Engine {
-
-	if token != "" {
-		err := os.Setenv("TOKEN", token)
-		if err != nil {
-			panic(err)
-		}
-	}
-
-	db := store.NewStore("./products_copy.json")
-	repo, _ := repository.NewProductRepository(db)
-	service := service.NewProductService(repo)
-	productHandler := handlers.NewProductHandler(service)
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
-
-	products := r.Group("/products")
-	products.Use(middleware.VerifyToken())
-	{
-		products.GET("", productHandler.GetAllProducts)
-		products.GET("/:id", productHandler.GetProductByID)
-		products.GET("/filter", productHandler.GetProductByPriceGreaterThan)
-		products.POST("", productHandler.CreateProduct)
-		products.PATCH("/:id", productHandler.PatchProduct)
-		products.PUT("/:id", productHandler.UpdateProduct)
-		products.DELETE("/:id", productHandler.DeleteProduct)
-	}
-	return r
-}
-
-func createRequestTest(method string, url string, body string, token string) (*http.Request, *httptest.ResponseRecorder) {
-	req := httptest.NewRequest(method, url, bytes.NewBuffer([]byte(body)))
-	req.Header.Add("Content-Type", "application/json")
-	if token != "" {
-		req.Header.Add("TOKEN", token)
-	}
-	return req, httptest.NewRecorder()
-}
-
-func loadProducts(path string) ([]domain.Product, error) {
-	var products []domain.Product
-	file, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	err = json.Unmarshal([]byte(file), &products)
-	if err != nil {
-		return nil, err
-	}
-	return products, nil
-}
-
-func writeProducts(path string, list []domain.Product) error {
-	bytes, err := json.Marshal(list)
-	if err != nil {
-		return err
-	}
-	err = os.WriteFile(path, bytes, 0644)
-	if err != nil {
-		return err
-	}
-	return err
-}
-
 func Test_GetAllProducts_OK(t *testing.T) {
 	var expected = response{Data: []domain.Product{}}
 
-	r := createServer("my-secret-value")
+	r := createServer(t, "my-secret-value")
 	req, rr := createRequestTest(http.MethodGet, "/products", "", "my-secret-value")
 
-	p, err := loadProducts("./products_copy.json")
+	p, err := loadProducts(fixture(t, "products_copy.json"))
 	if err != nil {
 		panic(err)
 	}
@@ -107,14 +37,71 @@ func Test_GetAllProducts_OK(t *testing.T) {
 	assert.Equal(t, expected.Data, actual["data"])
 }
 
+type pageResponse struct {
+	Data  []map[string]interface{} `json:"data"`
+	Meta  web.Meta                 `json:"meta"`
+	Links web.Links                `json:"links"`
+}
+
+func Test_GetAllProducts_Paginated(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	req, rr := createRequestTest(http.MethodGet, "/products?limit=10&offset=20&sort=-price&fields=id,price", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+
+	var actual pageResponse
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &actual))
+	assert.Equal(t, 500, actual.Meta.Total)
+	assert.Equal(t, 10, actual.Meta.Count)
+	assert.Equal(t, 20, actual.Meta.Offset)
+	assert.Len(t, actual.Data, 10)
+	assert.Len(t, actual.Data[0], 2)
+	for i := 1; i < len(actual.Data); i++ {
+		assert.GreaterOrEqual(t, actual.Data[i-1]["price"], actual.Data[i]["price"])
+	}
+	assert.Contains(t, actual.Links.Next, "offset=30")
+	assert.Contains(t, actual.Links.Prev, "offset=10")
+}
+
+func Test_GetAllProducts_Cursor(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	req, rr := createRequestTest(http.MethodGet, "/products?limit=5&sort=price", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	var first pageResponse
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &first))
+
+	req, rr = createRequestTest(http.MethodGet, "/products?limit=5&sort=price&cursor="+first.Meta.NextCursor, "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	var second pageResponse
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &second))
+	assert.Equal(t, 5, second.Meta.Offset)
+	assert.LessOrEqual(t, first.Data[4]["price"], second.Data[0]["price"])
+
+	req, rr = createRequestTest(http.MethodGet, "/products?limit=5&sort=price&cursor="+second.Meta.PrevCursor, "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	var back pageResponse
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &back))
+	assert.Equal(t, first.Data, back.Data)
+}
+
+func Test_GetAllProducts_BadQuery(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	for _, query := range []string{"sort=color", "fields=id,color", "limit=abc", "limit=1000", "cursor=not-a-cursor"} {
+		req, rr := createRequestTest(http.MethodGet, "/products?"+query, "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
 func Test_GetProductByID_OK(t *testing.T) {
 	var expected = response{Data: domain.Product{}}
 
-	r := createServer("my-secret-value")
+	r := createServer(t, "my-secret-value")
 	req, rr := createRequestTest(http.MethodGet, "/products/1", "", "my-secret-value")
 	r.ServeHTTP(rr, req)
 
-	p, err := loadProducts("./products_copy.json")
+	p, err := loadProducts(fixture(t, "products_copy.json"))
 	if err != nil {
 		panic(err)
 	}
@@ -134,20 +121,17 @@ func Test_CreateProduct_OK(t *testing.T) {
 		Quantity:    20,
 		CodeValue:   "TEST1",
 		IsPublished: false,
-		Expiration:  "15/12/2023",
-		Price:       100.50,
+		Expiration:  domain.NewDate(2023, time.December, 15),
+		Price:       domain.Cents(10050),
 	}}
 
 	product, _ := json.Marshal(expected.Data)
-	r := createServer("my-secret-value")
+	r := createServer(t, "my-secret-value")
 	req, rr := createRequestTest(http.MethodPost, "/products", string(product), "my-secret-value")
 
-	p, _ := loadProducts("./products_copy.json")
-
 	r.ServeHTTP(rr, req)
 	actual := map[string]domain.Product{}
 	_ = json.Unmarshal(rr.Body.Bytes(), &actual)
-	_ = writeProducts("./products_copy.json", p)
 
 	assert.Equal(t, http.StatusCreated, rr.Code)
 	assert.Equal(t, expected.Data, actual["data"])
@@ -155,20 +139,11 @@ func Test_CreateProduct_OK(t *testing.T) {
 
 func Test_Delete_OK(t *testing.T) {
 
-	r := createServer("my-secret-token")
+	r := createServer(t, "my-secret-token")
 	req, rr := createRequestTest(http.MethodDelete, "/products/1", "", "my-secret-token")
 
-	p, err := loadProducts("./products_copy.json")
-	if err != nil {
-		panic(err)
-	}
-
 	r.ServeHTTP(rr, req)
 
-	err = writeProducts("./products_copy.json", p)
-	if err != nil {
-		panic(err)
-	}
 	assert.Equal(t, 204, rr.Code)
 	assert.Nil(t, rr.Body.Bytes())
 }
@@ -177,7 +152,7 @@ func Test_BadRequest(t *testing.T) {
 
 	test := []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
 
-	r := createServer("my-secret-token")
+	r := createServer(t, "my-secret-token")
 	for _, method := range test {
 		req, rr := createRequestTest(method, "/products/not_a_number", "", "my-secret-token")
 		r.ServeHTTP(rr, req)
@@ -189,7 +164,7 @@ func Test_BadRequest(t *testing.T) {
 func Test_BadRequest_GET(t *testing.T) {
 
 	test := http.MethodGet
-	r := createServer("my-secret-token")
+	r := createServer(t, "my-secret-token")
 	req, rr := createRequestTest(test, "/products/not_a_number", "", "my-secret-token")
 	r.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
@@ -200,7 +175,7 @@ func Test_NotFound(t *testing.T) {
 
 	test := []string{http.MethodGet, http.MethodPatch, http.MethodDelete}
 
-	r := createServer("my-secret-token")
+	r := createServer(t, "my-secret-token")
 	for _, method := range test {
 		req, rr := createRequestTest(method, "/products/800", "{}", "my-secret-token")
 		r.ServeHTTP(rr, req)
@@ -212,7 +187,7 @@ func Test_Unauthorized(t *testing.T) {
 
 	test := []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
 
-	r := createServer("my-secret-token")
+	r := createServer(t, "my-secret-token")
 	for _, method := range test {
 		req, rr := createRequestTest(method, "/products/10", "{}", "not-my-token")
 		r.ServeHTTP(rr, req)
@@ -221,15 +196,251 @@ func Test_Unauthorized(t *testing.T) {
 }
 
 func Test_Unauthorized_GET(t *testing.T) {
-	r := createServer("my-secret-token")
+	r := createServer(t, "my-secret-token")
 	req, rr := createRequestTest(http.MethodGet, "/products", "", "not-my-token")
 	r.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusUnauthorized, rr.Code)
 }
 
 func Test_Unauthorized_POST(t *testing.T) {
-	r := createServer("my-secret-token")
+	r := createServer(t, "my-secret-token")
 	req, rr := createRequestTest(http.MethodPost, "/products", "{}", "not-my-token")
 	r.ServeHTTP(rr, req)
 	assert.Equal(t, http.StatusUnauthorized, rr.Code)
 }
+
+func Test_CreateProduct_Concurrent(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p, err := loadProducts(fixture(t, "products_copy.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	const total = 20
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"name":"Concurrent %d","quantity":1,"code_value":"CONC%d","expiration":"15/12/2023","price":1}`, i, i)
+			req, rr := createRequestTest(http.MethodPost, "/products", body, "my-secret-value")
+			r.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusCreated, rr.Code)
+		}(i)
+	}
+	wg.Wait()
+
+	after, err := loadProducts(fixture(t, "products_copy.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, len(p)+total, len(after))
+}
+
+func Test_CreateProduct_AfterDelete_DoesNotReuseID(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p, err := loadProducts(fixture(t, "products_copy.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	req, rr := createRequestTest(http.MethodDelete, fmt.Sprintf("/products/%d", len(p)), "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	body := `{"name":"After delete","quantity":1,"code_value":"AFTERDEL","expiration":"15/12/2023","price":1}`
+	req, rr = createRequestTest(http.MethodPost, "/products", body, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	actual := map[string]domain.Product{}
+	_ = json.Unmarshal(rr.Body.Bytes(), &actual)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, len(p)+1, actual["data"].ID)
+}
+
+func Test_GetProductByID_ReloadsExternalChanges(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p, err := loadProducts(fixture(t, "products_copy.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	edited := make([]domain.Product, len(p))
+	copy(edited, p)
+	edited[0].Name = "Edited outside the API"
+	err = writeProducts(fixture(t, "products_copy.json"), edited)
+	if err != nil {
+		panic(err)
+	}
+
+	req, rr := createRequestTest(http.MethodGet, "/products/1", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	actual := map[string]domain.Product{}
+	_ = json.Unmarshal(rr.Body.Bytes(), &actual)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Edited outside the API", actual["data"].Name)
+}
+
+func Test_FilterProducts_Expression(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	where := url.QueryEscape(`price>=10 AND price<50 AND is_published=true AND expiration<2022-01-01 AND NOT name~"oil"`)
+	req, rr := createRequestTest(http.MethodGet, "/products/filter?where="+where, "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+
+	actual := map[string][]domain.Product{}
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &actual))
+	assert.NotEmpty(t, actual["data"])
+	for _, product := range actual["data"] {
+		assert.True(t, product.Price.Compare(domain.Cents(1000)) >= 0 && product.Price.Compare(domain.Cents(5000)) < 0)
+		assert.True(t, product.IsPublished)
+		assert.NotContains(t, strings.ToLower(product.Name), "oil")
+	}
+}
+
+func Test_FilterProducts_SyntaxError(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	req, rr := createRequestTest(http.MethodGet, "/products/filter?where="+url.QueryEscape("price>=10 AND AND"), "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+
+	actual := map[string]interface{}{}
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &actual))
+	assert.Contains(t, actual["message"], "position 15")
+
+	where := map[string]string{
+		"quantity>=1.5":           "position 11",
+		"id=2.0":                  "whole number",
+		"id=9223372036854775808":  "out of range",
+		"quantity>=10 AND id=one": "position 21",
+	}
+	for expression, message := range where {
+		req, rr := createRequestTest(http.MethodGet, "/products/filter?where="+url.QueryEscape(expression), "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, expression)
+		assert.Contains(t, rr.Body.String(), message, expression)
+	}
+
+	var products []domain.Product
+	rr = serveData(r, http.MethodGet, "/products/filter?where="+url.QueryEscape("id=2"), "", &products)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	if assert.Len(t, products, 1) {
+		assert.Equal(t, 2, products[0].ID)
+	}
+}
+
+func Test_SearchProducts_Fuzzy(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	req, rr := createRequestTest(http.MethodGet, "/products/search?q="+url.QueryEscape("pinapple canned"), "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+
+	actual := map[string][]domain.Product{}
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &actual))
+	assert.NotEmpty(t, actual["data"])
+	assert.Contains(t, actual["data"][0].Name, "Pineapple - Canned")
+}
+
+func Test_SearchProducts_EmptyQuery(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	req, rr := createRequestTest(http.MethodGet, "/products/search?q=", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func Test_GetExpiringProducts_OK(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	req, rr := createRequestTest(http.MethodGet, "/products/expiring?within=7d", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+
+	actual := map[string][]service.ExpiryAlert{}
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), &actual))
+	assert.NotEmpty(t, actual["data"])
+	for _, alert := range actual["data"] {
+		assert.LessOrEqual(t, alert.DaysLeft, 7)
+		assert.Equal(t, alert.DaysLeft < 0, alert.Expired)
+	}
+}
+
+func Test_GetExpiringProducts_BadWindow(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	req, rr := createRequestTest(http.MethodGet, "/products/expiring?within=soon", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func Test_Money_ExactTotals(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	req, rr := createRequestTest(http.MethodGet, "/products/2", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Contains(t, rr.Body.String(), `"price":352.79`)
+
+	req, rr = createRequestTest(http.MethodPost, "/products", `{"name":"Fractional","quantity":1,"code_value":"MONEY1","expiration":"15/12/2023","price":1.005}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D","quantity":3}`, http.StatusOK},
+	})
+	req, rr = createRequestTest(http.MethodGet, "/carts/1/quote", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "71.42", p[0].Price.String())
+	assert.Contains(t, rr.Body.String(), `"total":214.26`)
+}
+
+func Test_Barcodes_Lookup(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPatch, "/products/1", `{"barcodes":[{"gtin":"036000291452"},{"gtin":"10036000291459","packaging":"case","units":12}]}`, http.StatusOK},
+		{http.MethodPatch, "/products/2", `{"barcodes":[{"gtin":"4006381333932"}]}`, http.StatusBadRequest},
+		{http.MethodPatch, "/products/2", `{"barcodes":[{"gtin":"0036000291452"}]}`, http.StatusBadRequest},
+		{http.MethodPatch, "/products/2", `{"barcodes":[{"gtin":"4006381333931","packaging":"case","units":1}]}`, http.StatusBadRequest},
+		{http.MethodPatch, "/products/2", `{"barcodes":[{"gtin":"4006381333931"},{"gtin":"04006381333931"}]}`, http.StatusBadRequest},
+		{http.MethodPatch, "/products/2", `{"barcodes":[{"gtin":"400-6381-33393-1"}]}`, http.StatusOK},
+		{http.MethodGet, "/products/barcode/4006381333932", ``, http.StatusBadRequest},
+		{http.MethodGet, "/products/barcode/ABC", ``, http.StatusBadRequest},
+		{http.MethodGet, "/products/barcode/96385074", ``, http.StatusNotFound},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"10036000291459"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"036000291452","quantity":2}`, http.StatusOK},
+	})
+
+	lookup := func(code string) (domain.PricedProduct, domain.Barcode) {
+		var match struct {
+			Product domain.PricedProduct `json:"product"`
+			Barcode domain.Barcode       `json:"barcode"`
+		}
+		rr := serveData(r, http.MethodGet, "/products/barcode/"+code, "", &match)
+		assert.Equal(t, http.StatusOK, rr.Code, code)
+		return match.Product, match.Barcode
+	}
+	unit := domain.Barcode{GTIN: "00036000291452", EAN13: "0036000291452", UPCA: "036000291452", Packaging: domain.PackagingUnit, Units: 1}
+	for _, code := range []string{"036000291452", "0036000291452", "00036000291452"} {
+		product, barcode := lookup(code)
+		assert.Equal(t, 1, product.ID, code)
+		assert.Equal(t, unit, barcode, code)
+	}
+	product, barcode := lookup("10036000291459")
+	assert.Equal(t, 1, product.ID)
+	assert.Equal(t, domain.Barcode{GTIN: "10036000291459", Packaging: domain.PackagingCase, Units: 12}, barcode)
+	product, barcode = lookup("4006381333931")
+	assert.Equal(t, 2, product.ID)
+	assert.Equal(t, "", barcode.UPCA)
+
+	var cart domain.Cart
+	serveData(r, http.MethodGet, "/carts/1", "", &cart)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Len(t, after[0].Barcodes, 2)
+	if assert.Len(t, cart.Lines, 1) {
+		assert.Equal(t, 14, cart.Lines[0].Quantity)
+		assert.Equal(t, p[0].CodeValue, cart.Lines[0].CodeValue)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+// productQueryFromRequest reads category, sort and pagination parameters. Pagination is
+// only enabled when limit, offset or cursor is present, so plain GET /products
+// keeps returning the whole catalog.
+func productQueryFromRequest(ctx *gin.Context) (service.ProductQuery, error) {
+	query := service.ProductQuery{
+		Category: ctx.Query("category"),
+		Sort:     ctx.Query("sort"),
+		Cursor:   ctx.Query("cursor"),
+	}
+	if limit, ok := ctx.GetQuery("limit"); ok {
+		limitConverted, err := strconv.Atoi(limit)
+		if err != nil {
+			return query, errors.New("limit must be an integer")
+		}
+		query.Limit = limitConverted
+		query.Paginate = true
+	}
+	if offset, ok := ctx.GetQuery("offset"); ok {
+		if query.Cursor != "" {
+			return query, errors.New("offset and cursor cannot be used together")
+		}
+		offsetConverted, err := strconv.Atoi(offset)
+		if err != nil {
+			return query, errors.New("offset must be an integer")
+		}
+		query.Offset = offsetConverted
+		query.Paginate = true
+	}
+	if query.Cursor != "" {
+		query.Paginate = true
+	}
+	return query, nil
+}
+
+// productFields lists the JSON names a client can ask for with ?fields=.
+func productFields() map[string]bool {
+	bytes, _ := json.Marshal(domain.PricedProduct{AppliedRule: &domain.PricingRule{}, PriceListID: 1, ExchangeRate: 1})
+	var all map[string]json.RawMessage
+	_ = json.Unmarshal(bytes, &all)
+	fields := make(map[string]bool, len(all))
+	for name := range all {
+		fields[name] = true
+	}
+	return fields
+}
+
+func parseFields(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	known := productFields()
+	var fields []string
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if !known[field] {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// selectFields returns the products unchanged when no fields were requested,
+// otherwise one object per product holding only the requested fields.
+func selectFields(products []domain.PricedProduct, fields []string) interface{} {
+	if len(fields) == 0 {
+		return products
+	}
+	sparse := make([]map[string]json.RawMessage, 0, len(products))
+	for _, product := range products {
+		bytes, _ := json.Marshal(product)
+		var all map[string]json.RawMessage
+		_ = json.Unmarshal(bytes, &all)
+		selected := make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			selected[field] = all[field]
+		}
+		sparse = append(sparse, selected)
+	}
+	return sparse
+}
+
+// pageLinks builds self/next/prev links that keep the request's other
+// parameters. Cursor requests get cursor links, offset requests offset links.
+func pageLinks(ctx *gin.Context, query service.ProductQuery, page service.ProductPage) web.Links {
+	link := func(set map[string]string) string {
+		values := url.Values{}
+		for key, value := range ctx.Request.URL.Query() {
+			values[key] = value
+		}
+		values.Del("offset")
+		values.Del("cursor")
+		values.Set("limit", strconv.Itoa(page.Limit))
+		for key, value := range set {
+			values.Set(key, value)
+		}
+		return ctx.Request.URL.Path + "?" + values.Encode()
+	}
+
+	links := web.Links{Self: ctx.Request.URL.RequestURI()}
+	if query.Cursor != "" {
+		if page.NextCursor != "" {
+			links.Next = link(map[string]string{"cursor": page.NextCursor})
+		}
+		if page.PrevCursor != "" {
+			links.Prev = link(map[string]string{"cursor": page.PrevCursor})
+		}
+		return links
+	}
+	if next := page.Offset + page.Limit; next < page.Total {
+		links.Next = link(map[string]string{"offset": strconv.Itoa(next)})
+	}
+	if page.Offset > 0 {
+		prev := page.Offset - page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links.Prev = link(map[string]string{"offset": strconv.Itoa(prev)})
+	}
+	return links
+}
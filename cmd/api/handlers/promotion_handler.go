@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionHandler struct {
+	promotionService service.PromotionService
+}
+
+func NewPromotionHandler(promotionService service.PromotionService) *PromotionHandler {
+	return &PromotionHandler{promotionService}
+}
+
+func (h *PromotionHandler) GetAllPromotions(ctx *gin.Context) {
+	promotions, err := h.promotionService.GetAllPromotions()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, promotions)
+}
+
+func (h *PromotionHandler) GetPromotionByID(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	promotion, err := h.promotionService.GetPromotionByID(id)
+	if err != nil {
+		web.Failure(ctx, 404, err)
+		return
+	}
+	web.Success(ctx, 200, promotion)
+}
+
+// CreatePromotion godoc
+// @Summary      Create a promotion
+// @Description  Create a percent_off, fixed_off, buy_x_get_y, multi_buy or mix_and_match promotion for products or categories, running from starts_on to ends_on. A promotion sent without active is active
+// @Tags         pricing
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        promotion body domain.Promotion true "promotion"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /promotions [post]
+func (h *PromotionHandler) CreatePromotion(ctx *gin.Context) {
+	var promotion domain.Promotion
+	if err := ctx.ShouldBindJSON(&promotion); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	created, err := h.promotionService.CreatePromotion(&promotion)
+	if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 201, created)
+}
+
+func (h *PromotionHandler) UpdatePromotion(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var promotion domain.Promotion
+	if err := ctx.ShouldBindJSON(&promotion); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	err := h.promotionService.UpdatePromotion(id, &promotion)
+	if errors.Is(err, repository.ErrPromotionNotFound) {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 200, promotion)
+}
+
+func (h *PromotionHandler) DeletePromotion(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	if err := h.promotionService.DeletePromotion(id); err != nil {
+		web.Failure(ctx, 404, err)
+		return
+	}
+	web.Success(ctx, 204, nil)
+}
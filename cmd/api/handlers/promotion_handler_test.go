@@ -0,0 +1,107 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Promotions_BestPrice(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+	yesterday := domain.Today().AddDays(-1).String()
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/promotions", `{"name":"10% off oil","type":"percent_off","product_ids":[1],"percent":10,"active":true}`, http.StatusCreated},
+		{http.MethodPost, "/promotions", `{"name":"Oil 2+1","type":"buy_x_get_y","product_ids":[1],"buy_quantity":2,"free_quantity":1,"active":true}`, http.StatusCreated},
+		{http.MethodPost, "/promotions", `{"name":"Pineapple 2 for 600","type":"multi_buy","product_ids":[2],"bundle_quantity":2,"bundle_price":600,"active":true}`, http.StatusCreated},
+		{http.MethodPost, "/promotions", `{"name":"Any 2 for 400","type":"mix_and_match","product_ids":[1,2],"bundle_quantity":2,"bundle_price":400,"active":true}`, http.StatusCreated},
+		{http.MethodPost, "/promotions", `{"name":"Half price","type":"percent_off","product_ids":[1,2],"percent":50,"active":true,"ends_on":"` + yesterday + `"}`, http.StatusCreated},
+		{http.MethodPost, "/promotions", `{"name":"Broken","type":"multi_buy","product_ids":[1],"bundle_quantity":1,"bundle_price":5}`, http.StatusBadRequest},
+		{http.MethodPost, "/promotions", `{"name":"Nothing","type":"percent_off","percent":5}`, http.StatusBadRequest},
+		{http.MethodPost, "/promotions", `{"name":"Unknown","type":"percent_off","product_ids":[100000],"percent":5}`, http.StatusBadRequest},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D","quantity":3}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"M4637","quantity":2}`, http.StatusOK},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/2/items", `{"code_value":"S82254D"}`, http.StatusOK},
+		{http.MethodPost, "/carts/2/items", `{"code_value":"M4637"}`, http.StatusOK},
+	})
+
+	var quote domain.Sale
+	rr := serveData(r, http.MethodGet, "/carts/1/quote", "", &quote)
+	var sale domain.Sale
+	rr = serveData(r, http.MethodPost, "/carts/2/checkout", "", &sale)
+
+	lines := quote.Lines
+	if assert.Len(t, lines, 2) && assert.NotNil(t, lines[0].Promotion) && assert.NotNil(t, lines[1].Promotion) {
+		assert.Equal(t, "Oil 2+1", lines[0].Promotion.Name)
+		assert.Equal(t, 3, lines[0].Promotion.Units)
+		assert.Equal(t, p[0].Price.Mul(2), lines[0].Total)
+		assert.Equal(t, "Any 2 for 400", lines[1].Promotion.Name)
+		assert.Equal(t, domain.Cents(40000), lines[1].Total)
+	}
+	assert.Equal(t, p[0].Price.Mul(2).Add(domain.Cents(40000)), quote.Total)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	if assert.Len(t, sale.Lines, 2) {
+		for _, line := range sale.Lines {
+			if assert.NotNil(t, line.Promotion) {
+				assert.Equal(t, "Any 2 for 400", line.Promotion.Name)
+				assert.NotEmpty(t, line.Promotion.Explanation)
+			}
+		}
+	}
+	assert.Equal(t, domain.Cents(40000), sale.Total)
+	assert.Equal(t, p[0].Price.Add(p[1].Price).Sub(domain.Cents(40000)), sale.Discount)
+}
+
+func Test_Promotions_CombineOffers(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+	bundle := p[0].Price.Add(p[1].Price).Sub(domain.Cents(500))
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/promotions", `{"name":"Pair","type":"mix_and_match","product_ids":[1,2],"bundle_quantity":2,"bundle_price":` + bundle.String() + `,"active":true}`, http.StatusCreated},
+		{http.MethodPost, "/promotions", `{"name":"Oil 4 off","type":"fixed_off","product_ids":[1],"amount":4,"active":true}`, http.StatusCreated},
+		{http.MethodPost, "/promotions", `{"name":"Pineapple 4 off","type":"fixed_off","product_ids":[2],"amount":4,"active":true}`, http.StatusCreated},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"M4637"}`, http.StatusOK},
+	})
+
+	var quote domain.Sale
+	serveData(r, http.MethodGet, "/carts/1/quote", "", &quote)
+
+	if assert.Len(t, quote.Lines, 2) && assert.NotNil(t, quote.Lines[0].Promotion) && assert.NotNil(t, quote.Lines[1].Promotion) {
+		assert.Equal(t, "Oil 4 off", quote.Lines[0].Promotion.Name)
+		assert.Equal(t, "Pineapple 4 off", quote.Lines[1].Promotion.Name)
+	}
+	assert.Equal(t, domain.Cents(800), quote.Discount)
+}
+
+func Test_Promotions_DefaultActive(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	var created, paused domain.Promotion
+	serveData(r, http.MethodPost, "/promotions", `{"name":"10% off oil","type":"percent_off","product_ids":[1],"percent":10}`, &created)
+	serveData(r, http.MethodPost, "/promotions", `{"name":"Half price","type":"percent_off","product_ids":[1],"percent":50,"active":false}`, &paused)
+	runSteps(t, r, []step{
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D"}`, http.StatusOK},
+	})
+	var quote domain.Sale
+	serveData(r, http.MethodGet, "/carts/1/quote", "", &quote)
+
+	if assert.NotNil(t, created.Active) && assert.NotNil(t, paused.Active) {
+		assert.True(t, *created.Active)
+		assert.False(t, *paused.Active)
+	}
+	if assert.Len(t, quote.Lines, 1) && assert.NotNil(t, quote.Lines[0].Promotion) {
+		assert.Equal(t, "10% off oil", quote.Lines[0].Promotion.Name)
+	}
+	assert.Equal(t, p[0].Price.Sub(p[0].Price.Percent(10)), quote.Total)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type PurchaseOrderHandler struct {
+	purchaseOrderService service.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(purchaseOrderService service.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{purchaseOrderService}
+}
+
+// purchaseOrderErrorStatus maps a purchase order service error to an HTTP
+// status, using fallback for validation errors.
+func purchaseOrderErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, repository.ErrPurchaseOrderNotFound):
+		return 404
+	case errors.Is(err, service.ErrPurchaseOrderStatus), errors.Is(err, service.ErrOverReceipt):
+		return 409
+	case errors.Is(err, repository.ErrSupplierNotFound):
+		return 400
+	}
+	return fallback
+}
+
+// GetAllPurchaseOrders godoc
+// @Summary      List purchase orders
+// @Tags         purchase-orders
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        status query string false "draft, submitted, partially_received, received or closed"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /purchase-orders [get]
+func (h *PurchaseOrderHandler) GetAllPurchaseOrders(ctx *gin.Context) {
+	orders, err := h.purchaseOrderService.GetAllPurchaseOrders(ctx.Query("status"))
+	if errors.Is(err, service.ErrInvalidQuery) {
+		web.Failure(ctx, 400, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, orders)
+}
+
+func (h *PurchaseOrderHandler) GetPurchaseOrderByID(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	order, err := h.purchaseOrderService.GetPurchaseOrderByID(id)
+	if err != nil {
+		web.Failure(ctx, purchaseOrderErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, order)
+}
+
+// CreatePurchaseOrder godoc
+// @Summary      Create a purchase order
+// @Description  Create a draft order; lines without unit_cost take the supplier's cost price
+// @Tags         purchase-orders
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        order body domain.PurchaseOrder true "supplier_id and lines"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Router       /purchase-orders [post]
+func (h *PurchaseOrderHandler) CreatePurchaseOrder(ctx *gin.Context) {
+	var order domain.PurchaseOrder
+	if err := ctx.ShouldBindJSON(&order); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	created, err := h.purchaseOrderService.CreatePurchaseOrder(&order)
+	if err != nil {
+		web.Failure(ctx, purchaseOrderErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 201, created)
+}
+
+func (h *PurchaseOrderHandler) UpdatePurchaseOrder(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var order domain.PurchaseOrder
+	if err := ctx.ShouldBindJSON(&order); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	updated, err := h.purchaseOrderService.UpdatePurchaseOrder(id, &order)
+	if err != nil {
+		web.Failure(ctx, purchaseOrderErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 200, updated)
+}
+
+func (h *PurchaseOrderHandler) DeletePurchaseOrder(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	if err := h.purchaseOrderService.DeletePurchaseOrder(id); err != nil {
+		web.Failure(ctx, purchaseOrderErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 204, nil)
+}
+
+func (h *PurchaseOrderHandler) SubmitPurchaseOrder(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	order, err := h.purchaseOrderService.SubmitPurchaseOrder(id)
+	if err != nil {
+		web.Failure(ctx, purchaseOrderErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, order)
+}
+
+// ReceivePurchaseOrder godoc
+// @Summary      Receive a delivery
+// @Description  Add delivered units to stock. Every line needs an expiration date and may not exceed the outstanding quantity
+// @Tags         purchase-orders
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "purchase order id"
+// @Param        receipt body domain.PurchaseOrderReceipt true "delivered lines"
+// @Success      200 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Failure      409 {object}  web.errorResponse
+// @Router       /purchase-orders/{id}/receipts [post]
+func (h *PurchaseOrderHandler) ReceivePurchaseOrder(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var receipt domain.PurchaseOrderReceipt
+	if err := ctx.ShouldBindJSON(&receipt); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	order, err := h.purchaseOrderService.ReceivePurchaseOrder(id, &receipt)
+	if err != nil {
+		web.Failure(ctx, purchaseOrderErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 200, order)
+}
+
+func (h *PurchaseOrderHandler) ClosePurchaseOrder(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	order, err := h.purchaseOrderService.ClosePurchaseOrder(id)
+	if err != nil {
+		web.Failure(ctx, purchaseOrderErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, order)
+}
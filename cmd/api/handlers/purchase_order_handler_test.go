@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PurchaseOrders_Receiving(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	order := `{"supplier_id":1,"lines":[{"product_id":7,"quantity":10},{"product_id":8,"quantity":5,"unit_cost":3}]}`
+	runSteps(t, r, []step{
+		{http.MethodPost, "/suppliers", `{"name":"Fresh Farms"}`, http.StatusCreated},
+		{http.MethodPost, "/suppliers/1/products", `{"product_id":7,"cost_price":10,"lead_time_days":2}`, http.StatusCreated},
+		{http.MethodPost, "/purchase-orders", order, http.StatusCreated},
+		{http.MethodPost, "/purchase-orders", `{"supplier_id":1,"lines":[{"product_id":9,"quantity":1}]}`, http.StatusBadRequest},
+	})
+
+	receiving := []step{
+		{http.MethodPost, "/purchase-orders/1/receipts", `{"lines":[{"product_id":7,"quantity":4,"expiration":"2030-01-01"}]}`, http.StatusConflict},
+		{http.MethodPost, "/purchase-orders/1/submit", ``, http.StatusOK},
+		{http.MethodPost, "/purchase-orders/1/receipts", `{"lines":[{"product_id":7,"quantity":4}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/purchase-orders/1/receipts", `{"lines":[{"product_id":7,"quantity":4,"expiration":"2030-01-01"}]}`, http.StatusOK},
+		{http.MethodPost, "/purchase-orders/1/receipts", `{"lines":[{"product_id":7,"quantity":7,"expiration":"2030-01-01"}]}`, http.StatusConflict},
+		{http.MethodPost, "/purchase-orders/1/receipts", `{"lines":[{"product_id":7,"quantity":6,"expiration":"2030-02-01"},{"product_id":8,"quantity":5,"expiration":"2030-01-01"}]}`, http.StatusOK},
+		{http.MethodPost, "/purchase-orders/1/close", ``, http.StatusOK},
+	}
+	statuses := []domain.PurchaseOrderStatus{}
+	for _, step := range receiving {
+		var changed domain.PurchaseOrder
+		rr := serveData(r, step.method, step.path, step.body, &changed)
+		assert.Equal(t, step.status, rr.Code, step.path+" "+step.body)
+		if rr.Code == http.StatusOK {
+			statuses = append(statuses, changed.Status)
+		}
+	}
+	runSteps(t, r, []step{{http.MethodPut, "/purchase-orders/1", order, http.StatusConflict}})
+
+	var received domain.PurchaseOrder
+	serveData(r, http.MethodGet, "/purchase-orders/1", "", &received)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, []domain.PurchaseOrderStatus{
+		domain.PurchaseOrderSubmitted,
+		domain.PurchaseOrderPartiallyReceived,
+		domain.PurchaseOrderReceived,
+		domain.PurchaseOrderClosed,
+	}, statuses)
+	assert.Equal(t, domain.Cents(1000), received.Lines[0].UnitCost)
+	assert.Len(t, received.Receipts, 2)
+	assert.Equal(t, p[6].Quantity+10, after[6].Quantity)
+	assert.Equal(t, p[7].Quantity+5, after[7].Quantity)
+}
+
+func Test_PurchaseOrders_ReceiptBatch(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/products/8/lots", `{"lot_number":"L1","quantity":1,"expiration":"2030-01-01"}`, http.StatusCreated},
+		{http.MethodPost, "/suppliers", `{"name":"Fresh Farms"}`, http.StatusCreated},
+		{http.MethodPost, "/purchase-orders", `{"supplier_id":1,"lines":[{"product_id":7,"quantity":10,"unit_cost":2},{"product_id":8,"quantity":5,"unit_cost":3}]}`, http.StatusCreated},
+		{http.MethodPost, "/purchase-orders/1/submit", ``, http.StatusOK},
+		{http.MethodPost, "/purchase-orders/1/receipts", `{"lines":[{"product_id":7,"quantity":4,"expiration":"2030-01-01"},{"product_id":8,"quantity":5,"lot_number":"L1","expiration":"2031-01-01"}]}`, http.StatusBadRequest},
+	})
+
+	var order domain.PurchaseOrder
+	serveData(r, http.MethodGet, "/purchase-orders/1", "", &order)
+	var lots []domain.Lot
+	serveData(r, http.MethodGet, "/products/7/lots", "", &lots)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, domain.PurchaseOrderSubmitted, order.Status)
+	assert.Empty(t, order.Receipts)
+	assert.Zero(t, order.Lines[0].QuantityReceived)
+	assert.Empty(t, lots)
+	assert.Equal(t, p[6].Quantity, after[6].Quantity)
+	assert.Equal(t, p[7].Quantity+1, after[7].Quantity)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type RefundHandler struct {
+	refundService service.RefundService
+}
+
+func NewRefundHandler(refundService service.RefundService) *RefundHandler {
+	return &RefundHandler{refundService}
+}
+
+// refundErrorStatus maps a refund service error to an HTTP status, using
+// fallback for anything else.
+func refundErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, repository.ErrSaleNotFound),
+		errors.Is(err, repository.ErrRefundNotFound):
+		return 404
+	case errors.Is(err, service.ErrOverReturn),
+		errors.Is(err, service.ErrInsufficientStock):
+		return 409
+	}
+	return fallback
+}
+
+// CreateReturn godoc
+// @Summary      Return goods from a sale
+// @Description  Take back units of receipt lines, restocking them or writing them off as damaged, and record the refund
+// @Tags         pos
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "sale id"
+// @Param        refund body domain.Refund true "returned lines"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Failure      409 {object}  web.errorResponse
+// @Router       /sales/{id}/returns [post]
+func (h *RefundHandler) CreateReturn(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var refund domain.Refund
+	if err := ctx.ShouldBindJSON(&refund); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	created, err := h.refundService.CreateRefund(id, &refund)
+	if err != nil {
+		web.Failure(ctx, refundErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 201, created)
+}
+
+func (h *RefundHandler) GetRefundsBySale(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	refunds, err := h.refundService.GetRefundsBySale(id)
+	if err != nil {
+		web.Failure(ctx, refundErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, refunds)
+}
+
+func (h *RefundHandler) GetRefundByID(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	refund, err := h.refundService.GetRefundByID(id)
+	if err != nil {
+		web.Failure(ctx, refundErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, refund)
+}
@@ -0,0 +1,124 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Refunds_Returns(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D","quantity":3}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"M4637"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/checkout", ``, http.StatusCreated},
+		{http.MethodPost, "/sales/1/returns", `{"reason":"Changed mind","lines":[{"line":1,"quantity":2}]}`, http.StatusCreated},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":1,"quantity":2}]}`, http.StatusConflict},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":2,"quantity":1,"disposition":"damaged"}]}`, http.StatusCreated},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":3,"quantity":1}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":1,"quantity":1,"disposition":"lost"}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/sales/9/returns", `{"lines":[{"line":1,"quantity":1}]}`, http.StatusNotFound},
+		{http.MethodGet, "/refunds/1", ``, http.StatusOK},
+		{http.MethodGet, "/refunds/9", ``, http.StatusNotFound},
+	})
+
+	var refunds []domain.Refund
+	rr := serveData(r, http.MethodGet, "/sales/1/refunds", "", &refunds)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, refunds, 2)
+	assert.Equal(t, 1, refunds[0].SaleID)
+	assert.Equal(t, p[0].ID, refunds[0].Lines[0].ProductID)
+	assert.Equal(t, p[0].Price.Mul(2), refunds[0].Total)
+	assert.Equal(t, domain.ReturnDamaged, refunds[1].Lines[0].Disposition)
+	assert.Equal(t, p[0].Quantity-1, after[0].Quantity)
+	assert.Equal(t, p[1].Quantity-1, after[1].Quantity)
+}
+
+func Test_Refunds_Remainder(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/promotions", `{"name":"3 for 10","type":"multi_buy","product_ids":[1],"bundle_quantity":3,"bundle_price":10,"active":true}`, http.StatusCreated},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D","quantity":3}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/checkout", ``, http.StatusCreated},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":1,"quantity":1}]}`, http.StatusCreated},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":1,"quantity":1},{"line":1,"quantity":1}]}`, http.StatusCreated},
+	})
+
+	var refunds []domain.Refund
+	serveData(r, http.MethodGet, "/sales/1/refunds", "", &refunds)
+
+	if assert.Len(t, refunds, 2) && assert.Len(t, refunds[1].Lines, 2) {
+		assert.Equal(t, domain.Cents(333), refunds[0].Total)
+		assert.Equal(t, domain.Cents(333), refunds[1].Lines[0].Amount)
+		assert.Equal(t, domain.Cents(334), refunds[1].Lines[1].Amount)
+		assert.Equal(t, domain.Cents(1000), refunds[0].Total.Add(refunds[1].Total))
+	}
+}
+
+func Test_Refunds_Failure(t *testing.T) {
+	r := createServer(t, "my-secret-value", "refunds.json")
+	p := fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D","quantity":3}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/checkout", ``, http.StatusCreated},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":1,"quantity":2}]}`, http.StatusBadRequest},
+	})
+
+	var movements []domain.StockMovement
+	serveData(r, http.MethodGet, "/products/1/movements", "", &movements)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, p[0].Quantity-3, after[0].Quantity)
+	if assert.Len(t, movements, 4) {
+		assert.Equal(t, domain.MovementReturn, movements[2].Type)
+		assert.Equal(t, service.ReasonReversal, movements[3].Reason)
+		assert.Equal(t, -2, movements[3].Quantity)
+	}
+}
+
+func Test_Refunds_Weighed(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/products", `{"name":"Bananas","measure":5,"code_value":"BAN-KG","is_published":true,"expiration":"2099-12-31","price":2.4,"unit":"kg","plu":"4011"}`, http.StatusCreated},
+		{http.MethodPost, "/carts", ``, http.StatusCreated},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"4011","measure":1.5}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/items", `{"code_value":"S82254D"}`, http.StatusOK},
+		{http.MethodPost, "/carts/1/checkout", ``, http.StatusCreated},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":1,"quantity":500}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":1,"measure":0.0005}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":2,"measure":1}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":1,"measure":0.5}]}`, http.StatusCreated},
+		{http.MethodPost, "/sales/1/returns", `{"lines":[{"line":1,"measure":1.5}]}`, http.StatusConflict},
+	})
+
+	var refund domain.Refund
+	rr := serveData(r, http.MethodGet, "/refunds/1", "", &refund)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	if assert.Len(t, refund.Lines, 1) {
+		assert.Equal(t, 500, refund.Lines[0].Quantity)
+		if assert.NotNil(t, refund.Lines[0].Measure) {
+			assert.Equal(t, "0.5", refund.Lines[0].Measure.String())
+		}
+	}
+	assert.Equal(t, domain.Cents(120), refund.Total)
+	if assert.Len(t, after, 501) {
+		assert.Equal(t, 4000, after[500].Quantity)
+	}
+}
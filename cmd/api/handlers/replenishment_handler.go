@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type ReplenishmentHandler struct {
+	replenishmentService service.ReplenishmentService
+}
+
+func NewReplenishmentHandler(replenishmentService service.ReplenishmentService) *ReplenishmentHandler {
+	return &ReplenishmentHandler{replenishmentService}
+}
+
+// GetSuggestions godoc
+// @Summary      Replenishment suggestions
+// @Description  Products at or below their reorder point, counting units already on order, grouped by their cheapest supplier
+// @Tags         replenishment
+// @Produce      json
+// @Param        token header string true "token"
+// @Success      200 {object}  web.response
+// @Router       /replenishment/suggestions [get]
+func (h *ReplenishmentHandler) GetSuggestions(ctx *gin.Context) {
+	suggestions, err := h.replenishmentService.GetSuggestions()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, suggestions)
+}
+
+// CreateDraftOrders godoc
+// @Summary      Order the suggestions
+// @Description  Create one draft purchase order per supplier from the current suggestions
+// @Tags         replenishment
+// @Produce      json
+// @Param        token header string true "token"
+// @Success      201 {object}  web.response
+// @Router       /replenishment/purchase-orders [post]
+func (h *ReplenishmentHandler) CreateDraftOrders(ctx *gin.Context) {
+	orders, err := h.replenishmentService.CreateDraftOrders()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 201, orders)
+}
@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Replenishment_Suggestions(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	setup := [][3]string{
+		{http.MethodPatch, "/products/1", fmt.Sprintf(`{"reorder_point":%d,"reorder_quantity":50}`, p[0].Quantity)},
+		{http.MethodPatch, "/products/2", fmt.Sprintf(`{"min_stock":%d}`, p[1].Quantity+1)},
+		{http.MethodPatch, "/products/3", fmt.Sprintf(`{"reorder_point":%d}`, p[2].Quantity-1)},
+		{http.MethodPost, "/suppliers", `{"name":"Fresh Farms"}`},
+		{http.MethodPost, "/suppliers/1/products", `{"product_id":1,"cost_price":2.5,"lead_time_days":2}`},
+	}
+	for _, step := range setup {
+		req, rr := createRequestTest(step[0], step[1], step[2], "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Contains(t, []int{http.StatusOK, http.StatusCreated}, rr.Code, step[2])
+	}
+	req, rr := createRequestTest(http.MethodPatch, "/products/4", `{"min_stock":10,"reorder_point":5}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	getSuggestions := func() []service.SupplierSuggestions {
+		req, rr := createRequestTest(http.MethodGet, "/replenishment/suggestions", "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		suggestions := map[string][]service.SupplierSuggestions{}
+		_ = json.Unmarshal(rr.Body.Bytes(), &suggestions)
+		return suggestions["data"]
+	}
+	before := getSuggestions()
+	var orders []domain.PurchaseOrder
+	rr = serveData(r, http.MethodPost, "/replenishment/purchase-orders", "", &orders)
+	after := getSuggestions()
+
+	assert.Len(t, before, 2)
+	assert.Equal(t, "Fresh Farms", before[0].Supplier.Name)
+	assert.Equal(t, 50, before[0].Lines[0].SuggestedQuantity)
+	assert.Equal(t, domain.Cents(12500), before[0].TotalCost)
+	assert.Nil(t, before[1].Supplier)
+	assert.Equal(t, 2, before[1].Lines[0].ProductID)
+	assert.True(t, before[1].Lines[0].Critical)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Len(t, orders, 1)
+	assert.Equal(t, domain.PurchaseOrderDraft, orders[0].Status)
+	assert.Len(t, after, 1)
+	assert.Nil(t, after[0].Supplier)
+}
+
+func Test_Replenishment_WeighedCost(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	runSteps(t, r, []step{
+		{http.MethodPost, "/products", `{"name":"Bananas","measure":0.5,"code_value":"BAN-KG","is_published":true,"expiration":"2099-12-31","price":2.4,"unit":"kg","reorder_point":1000,"reorder_quantity":5000}`, http.StatusCreated},
+		{http.MethodPost, "/suppliers", `{"name":"Fresh Farms"}`, http.StatusCreated},
+		{http.MethodPost, "/suppliers/1/products", `{"product_id":501,"cost_price":1.5,"lead_time_days":2}`, http.StatusCreated},
+	})
+
+	var suggestions []service.SupplierSuggestions
+	serveData(r, http.MethodGet, "/replenishment/suggestions", "", &suggestions)
+
+	if assert.NotEmpty(t, suggestions) && assert.NotNil(t, suggestions[0].Supplier) {
+		assert.Equal(t, 5000, suggestions[0].Lines[0].SuggestedQuantity)
+		assert.Equal(t, domain.Cents(750), suggestions[0].TotalCost)
+	}
+}
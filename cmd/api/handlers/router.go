@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/middleware"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+	"github.com/gin-gonic/gin"
+)
+
+// Services are the repositories and services behind the API.
+type Services struct {
+	ProductRepo   repository.ProductRepository
+	Product       service.ProductService
+	Stock         service.StockService
+	Category      service.CategoryService
+	Supplier      service.SupplierService
+	PurchaseOrder service.PurchaseOrderService
+	Replenishment service.ReplenishmentService
+	Pricing       service.PricingService
+	Promotion     service.PromotionService
+	Cart          service.CartService
+	Refund        service.RefundService
+	Currency      service.CurrencyService
+	PriceHistory  service.PriceHistoryService
+}
+
+// NewServices wires every service over storage, the product store. Exchange
+// rates are kept in exchangeRatesPath and the other records in the file path
+// returns for each collection name, such as "carts.json".
+func NewServices(storage store.Store, path func(name string) string, taxPolicy service.TaxPolicy, exchangeRatesPath string) (Services, error) {
+	productRepo, err := repository.NewProductRepository(storage)
+	if err != nil {
+		return Services{}, err
+	}
+	movementRepo := repository.NewStockMovementRepository(store.NewCollection[domain.StockMovement](path("stock_movements.json")))
+	lotRepo := repository.NewLotRepository(store.NewCollection[domain.Lot](path("lots.json")))
+	stockService := service.NewStockService(productRepo, movementRepo, lotRepo)
+	categoryRepo := repository.NewCategoryRepository(store.NewCollection[domain.Category](path("categories.json")))
+	priceChangeRepo := repository.NewPriceChangeRepository(store.NewCollection[domain.PriceChange](path("price_changes.json")))
+	priceHistoryService := service.NewPriceHistoryService(priceChangeRepo, productRepo)
+	supplierRepo := repository.NewSupplierRepository(store.NewCollection[domain.Supplier](path("suppliers.json")))
+	supplierProductRepo := repository.NewSupplierProductRepository(store.NewCollection[domain.SupplierProduct](path("supplier_products.json")))
+	supplierService := service.NewSupplierService(supplierRepo, supplierProductRepo, productRepo)
+	purchaseOrderRepo := repository.NewPurchaseOrderRepository(store.NewCollection[domain.PurchaseOrder](path("purchase_orders.json")))
+	purchaseOrderService := service.NewPurchaseOrderService(purchaseOrderRepo, supplierRepo, supplierProductRepo, productRepo, stockService)
+	pricingRuleRepo := repository.NewPricingRuleRepository(store.NewCollection[domain.PricingRule](path("pricing_rules.json")))
+	taxCalculator := service.NewTaxCalculator(taxPolicy)
+	pricingService := service.NewPricingService(pricingRuleRepo, productRepo, taxCalculator)
+	cartRepo := repository.NewCartRepository(store.NewCollection[domain.Cart](path("carts.json")))
+	saleRepo := repository.NewSaleRepository(store.NewCollection[domain.Sale](path("sales.json")))
+	promotionRepo := repository.NewPromotionRepository(store.NewCollection[domain.Promotion](path("promotions.json")))
+	promotionService := service.NewPromotionService(promotionRepo, productRepo, categoryRepo)
+	refundRepo := repository.NewRefundRepository(store.NewCollection[domain.Refund](path("refunds.json")))
+	exchangeRateRepo := repository.NewExchangeRateRepository(store.NewCollection[domain.ExchangeRate](exchangeRatesPath))
+	priceListRepo := repository.NewPriceListRepository(store.NewCollection[domain.PriceList](path("price_lists.json")))
+	return Services{
+		ProductRepo:   productRepo,
+		Product:       service.NewProductService(productRepo, stockService, categoryRepo, priceHistoryService),
+		Stock:         stockService,
+		Category:      service.NewCategoryService(categoryRepo, productRepo),
+		Supplier:      supplierService,
+		PurchaseOrder: purchaseOrderService,
+		Replenishment: service.NewReplenishmentService(productRepo, supplierService, purchaseOrderService),
+		Pricing:       pricingService,
+		Promotion:     promotionService,
+		Cart:          service.NewCartService(cartRepo, saleRepo, productRepo, stockService, pricingService, promotionService, taxCalculator),
+		Refund:        service.NewRefundService(saleRepo, refundRepo, stockService),
+		Currency:      service.NewCurrencyService(exchangeRateRepo, priceListRepo, productRepo, taxCalculator),
+		PriceHistory:  priceHistoryService,
+	}, nil
+}
+
+// RegisterRoutes mounts every endpoint of the API on router, each group behind
+// the token check.
+func RegisterRoutes(router gin.IRouter, services Services) {
+	productHandler := NewProductHandler(services.Product, services.Pricing, services.Currency)
+	pricingHandler := NewPricingHandler(services.Pricing)
+	promotionHandler := NewPromotionHandler(services.Promotion)
+	stockHandler := NewStockHandler(services.Stock)
+	lotHandler := NewLotHandler(services.Stock)
+	categoryHandler := NewCategoryHandler(services.Category)
+	supplierHandler := NewSupplierHandler(services.Supplier)
+	purchaseOrderHandler := NewPurchaseOrderHandler(services.PurchaseOrder)
+	replenishmentHandler := NewReplenishmentHandler(services.Replenishment)
+	cartHandler := NewCartHandler(services.Cart)
+	refundHandler := NewRefundHandler(services.Refund)
+	priceListHandler := NewPriceListHandler(services.Currency)
+	priceHistoryHandler := NewPriceHistoryHandler(services.PriceHistory)
+
+	products := router.Group("/products")
+	products.Use(middleware.VerifyToken())
+	{
+		products.GET("", productHandler.GetAllProducts)
+		products.GET("/:id", productHandler.GetProductByID)
+		products.GET("/filter", productHandler.GetProductByPriceGreaterThan)
+		products.GET("/search", productHandler.SearchProducts)
+		products.GET("/expiring", productHandler.GetExpiringProducts)
+		products.GET("/barcode/:gtin", productHandler.GetProductByBarcode)
+		products.POST("", productHandler.CreateProduct)
+		products.PATCH("/:id", productHandler.PatchProduct)
+		products.PUT("/:id", productHandler.UpdateProduct)
+		products.DELETE("/:id", productHandler.DeleteProduct)
+		products.GET("/:id/movements", stockHandler.GetMovements)
+		products.POST("/:id/movements", stockHandler.CreateMovement)
+		products.GET("/:id/lots", lotHandler.GetLots)
+		products.POST("/:id/lots", lotHandler.CreateLot)
+		products.PUT("/:id/lots/:lot_id", lotHandler.UpdateLot)
+		products.GET("/:id/suppliers", supplierHandler.GetProductSuppliers)
+		products.GET("/:id/suppliers/cheapest", supplierHandler.GetCheapestSupplier)
+		products.GET("/:id/suppliers/fastest", supplierHandler.GetFastestSupplier)
+		products.GET("/:id/prices", priceHistoryHandler.GetPriceHistory)
+		products.POST("/:id/prices", priceHistoryHandler.SchedulePriceChange)
+		products.DELETE("/:id/prices/:change_id", priceHistoryHandler.CancelPriceChange)
+	}
+	pricing := router.Group("/pricing")
+	pricing.Use(middleware.VerifyToken())
+	{
+		pricing.GET("/rules", pricingHandler.GetAllRules)
+		pricing.GET("/rules/:id", pricingHandler.GetRuleByID)
+		pricing.POST("/rules", pricingHandler.CreateRule)
+		pricing.PUT("/rules/:id", pricingHandler.UpdateRule)
+		pricing.DELETE("/rules/:id", pricingHandler.DeleteRule)
+		pricing.POST("/preview", pricingHandler.Preview)
+	}
+	promotions := router.Group("/promotions")
+	promotions.Use(middleware.VerifyToken())
+	{
+		promotions.GET("", promotionHandler.GetAllPromotions)
+		promotions.GET("/:id", promotionHandler.GetPromotionByID)
+		promotions.POST("", promotionHandler.CreatePromotion)
+		promotions.PUT("/:id", promotionHandler.UpdatePromotion)
+		promotions.DELETE("/:id", promotionHandler.DeletePromotion)
+	}
+	exchangeRates := router.Group("/exchange-rates")
+	exchangeRates.Use(middleware.VerifyToken())
+	{
+		exchangeRates.GET("", priceListHandler.GetExchangeRates)
+		exchangeRates.PUT("/:currency", priceListHandler.SetExchangeRate)
+		exchangeRates.DELETE("/:currency", priceListHandler.DeleteExchangeRate)
+	}
+	priceLists := router.Group("/price-lists")
+	priceLists.Use(middleware.VerifyToken())
+	{
+		priceLists.GET("", priceListHandler.GetAllPriceLists)
+		priceLists.GET("/:id", priceListHandler.GetPriceListByID)
+		priceLists.POST("", priceListHandler.CreatePriceList)
+		priceLists.PUT("/:id", priceListHandler.UpdatePriceList)
+		priceLists.DELETE("/:id", priceListHandler.DeletePriceList)
+	}
+	categories := router.Group("/categories")
+	categories.Use(middleware.VerifyToken())
+	{
+		categories.GET("", categoryHandler.GetAllCategories)
+		categories.GET("/tree", categoryHandler.GetCategoryTree)
+		categories.GET("/summary", categoryHandler.GetCategorySummary)
+		categories.GET("/:id", categoryHandler.GetCategoryByID)
+		categories.POST("", categoryHandler.CreateCategory)
+		categories.PUT("/:id", categoryHandler.UpdateCategory)
+		categories.DELETE("/:id", categoryHandler.DeleteCategory)
+	}
+	suppliers := router.Group("/suppliers")
+	suppliers.Use(middleware.VerifyToken())
+	{
+		suppliers.GET("", supplierHandler.GetAllSuppliers)
+		suppliers.GET("/:id", supplierHandler.GetSupplierByID)
+		suppliers.POST("", supplierHandler.CreateSupplier)
+		suppliers.PUT("/:id", supplierHandler.UpdateSupplier)
+		suppliers.DELETE("/:id", supplierHandler.DeleteSupplier)
+		suppliers.GET("/:id/products", supplierHandler.GetSupplierProducts)
+		suppliers.POST("/:id/products", supplierHandler.LinkProduct)
+		suppliers.PUT("/:id/products/:product_id", supplierHandler.UpdateLink)
+		suppliers.DELETE("/:id/products/:product_id", supplierHandler.UnlinkProduct)
+	}
+	purchaseOrders := router.Group("/purchase-orders")
+	purchaseOrders.Use(middleware.VerifyToken())
+	{
+		purchaseOrders.GET("", purchaseOrderHandler.GetAllPurchaseOrders)
+		purchaseOrders.GET("/:id", purchaseOrderHandler.GetPurchaseOrderByID)
+		purchaseOrders.POST("", purchaseOrderHandler.CreatePurchaseOrder)
+		purchaseOrders.PUT("/:id", purchaseOrderHandler.UpdatePurchaseOrder)
+		purchaseOrders.DELETE("/:id", purchaseOrderHandler.DeletePurchaseOrder)
+		purchaseOrders.POST("/:id/submit", purchaseOrderHandler.SubmitPurchaseOrder)
+		purchaseOrders.POST("/:id/receipts", purchaseOrderHandler.ReceivePurchaseOrder)
+		purchaseOrders.POST("/:id/close", purchaseOrderHandler.ClosePurchaseOrder)
+	}
+	lots := router.Group("/lots")
+	lots.Use(middleware.VerifyToken())
+	{
+		lots.GET("/recall", lotHandler.RecallLots)
+	}
+	replenishment := router.Group("/replenishment")
+	replenishment.Use(middleware.VerifyToken())
+	{
+		replenishment.GET("/suggestions", replenishmentHandler.GetSuggestions)
+		replenishment.POST("/purchase-orders", replenishmentHandler.CreateDraftOrders)
+	}
+	carts := router.Group("/carts")
+	carts.Use(middleware.VerifyToken())
+	{
+		carts.POST("", cartHandler.CreateCart)
+		carts.GET("/:id", cartHandler.GetCartByID)
+		carts.POST("/:id/items", cartHandler.AddItem)
+		carts.DELETE("/:id/items/:code_value", cartHandler.RemoveItem)
+		carts.GET("/:id/quote", cartHandler.Quote)
+		carts.POST("/:id/checkout", cartHandler.Checkout)
+	}
+	sales := router.Group("/sales")
+	sales.Use(middleware.VerifyToken())
+	{
+		sales.GET("", cartHandler.GetAllSales)
+		sales.GET("/:id", cartHandler.GetSaleByID)
+		sales.POST("/:id/returns", refundHandler.CreateReturn)
+		sales.GET("/:id/refunds", refundHandler.GetRefundsBySale)
+	}
+	refunds := router.Group("/refunds")
+	refunds.Use(middleware.VerifyToken())
+	{
+		refunds.GET("/:id", refundHandler.GetRefundByID)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type StockHandler struct {
+	stockService service.StockService
+}
+
+func NewStockHandler(stockService service.StockService) *StockHandler {
+	return &StockHandler{stockService}
+}
+
+// GetMovements godoc
+// @Summary      List stock movements
+// @Description  List the stock ledger of a product, oldest first
+// @Tags         stock
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "product id"
+// @Success      200 {object}  web.response
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/{id}/movements [get]
+func (h *StockHandler) GetMovements(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	movements, err := h.stockService.GetMovements(id)
+	if err != nil && err.Error() == "Product not found" {
+		web.Failure(ctx, 404, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, movements)
+}
+
+// CreateMovement godoc
+// @Summary      Record a stock movement
+// @Description  Append a receipt, sale, adjustment, shrinkage, return or transfer to the product's ledger and update its quantity
+// @Tags         stock
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "product id"
+// @Param        movement body domain.StockMovement true "movement"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Failure      409 {object}  web.errorResponse
+// @Router       /products/{id}/movements [post]
+func (h *StockHandler) CreateMovement(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var movement domain.StockMovement
+	if err := ctx.ShouldBindJSON(&movement); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	recorded, err := h.stockService.RecordMovement(id, &movement)
+	if err != nil && err.Error() == "Product not found" {
+		web.Failure(ctx, 404, err)
+		return
+	} else if errors.Is(err, service.ErrInsufficientStock) {
+		web.Failure(ctx, 409, err)
+		return
+	} else if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 201, recorded)
+}
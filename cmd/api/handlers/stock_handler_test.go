@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StockMovements_Ledger(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	p := fixtureProducts(t)
+
+	bodies := []string{
+		`{"type":"receipt","quantity":10,"reference":"INV-1"}`,
+		`{"type":"sale","quantity":4}`,
+		`{"type":"shrinkage","quantity":1,"reason":"damaged"}`,
+	}
+	for _, body := range bodies {
+		req, rr := createRequestTest(http.MethodPost, "/products/1/movements", body, "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, body)
+	}
+	req, rr := createRequestTest(http.MethodPatch, "/products/1", `{"quantity":500}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var movements []domain.StockMovement
+	rr = serveData(r, http.MethodGet, "/products/1/movements", "", &movements)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, movements, 5)
+	assert.Equal(t, domain.MovementAdjustment, movements[0].Type)
+	assert.Equal(t, p[0].Quantity, movements[0].Quantity)
+	assert.Equal(t, -4, movements[2].Quantity)
+	assert.Equal(t, service.ReasonManualEdit, movements[4].Reason)
+	assert.Equal(t, 500, movements[4].Balance)
+	assert.Equal(t, 500, after[0].Quantity)
+}
+
+func Test_StockMovements_Rejected(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	tests := map[string]int{
+		`{"type":"sale","quantity":100000}`:        http.StatusConflict,
+		`{"type":"shrinkage","quantity":1}`:        http.StatusBadRequest,
+		`{"type":"sale","quantity":-1}`:            http.StatusBadRequest,
+		`{"type":"teleport","quantity":1}`:         http.StatusBadRequest,
+		`{"type":"adjustment","quantity":0}`:       http.StatusBadRequest,
+		`{"type":"receipt","quantity":1,"x":true}`: http.StatusCreated,
+	}
+	fixtureProducts(t)
+	for body, status := range tests {
+		req, rr := createRequestTest(http.MethodPost, "/products/2/movements", body, "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, status, rr.Code, body)
+	}
+
+	req, rr := createRequestTest(http.MethodPost, "/products/800/movements", `{"type":"receipt","quantity":1}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func Test_StockMovements_Measure(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+	fixtureProducts(t)
+
+	bananas := `"name":"Bananas","code_value":"BAN-KG","is_published":true,"expiration":"2099-12-31","price":2.4,"unit":"kg"`
+	runSteps(t, r, []step{
+		{http.MethodPost, "/products", `{` + bananas + `,"quantity":5}`, http.StatusBadRequest},
+		{http.MethodPost, "/products", `{` + bananas + `,"measure":5}`, http.StatusCreated},
+		{http.MethodPost, "/products", `{"name":"Pears","code_value":"PEAR","is_published":true,"expiration":"2099-12-31","price":2.4,"measure":5}`, http.StatusBadRequest},
+		{http.MethodPatch, "/products/501", `{"quantity":6}`, http.StatusBadRequest},
+		{http.MethodPatch, "/products/501", `{"measure":6}`, http.StatusOK},
+		{http.MethodPut, "/products/501", `{` + bananas + `,"quantity":6000}`, http.StatusOK},
+		{http.MethodPut, "/products/501", `{` + bananas + `,"quantity":7}`, http.StatusBadRequest},
+		{http.MethodPost, "/products/501/movements", `{"type":"shrinkage","reason":"spoilage","quantity":250}`, http.StatusBadRequest},
+		{http.MethodPost, "/products/501/movements", `{"type":"shrinkage","reason":"spoilage","measure":0.25,"quantity":250}`, http.StatusBadRequest},
+		{http.MethodPost, "/products/501/movements", `{"type":"shrinkage","reason":"spoilage","measure":0.25}`, http.StatusCreated},
+		{http.MethodPost, "/products/1/movements", `{"type":"receipt","measure":1}`, http.StatusBadRequest},
+		{http.MethodPost, "/products/501/lots", `{"lot_number":"L1","quantity":1500,"expiration":"2099-01-01"}`, http.StatusBadRequest},
+		{http.MethodPost, "/products/501/lots", `{"lot_number":"L1","measure":1.5,"expiration":"2099-01-01"}`, http.StatusCreated},
+		{http.MethodPost, "/suppliers", `{"name":"Fresh Farms"}`, http.StatusCreated},
+		{http.MethodPost, "/purchase-orders", `{"supplier_id":1,"lines":[{"product_id":501,"quantity":10,"unit_cost":1}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/purchase-orders", `{"supplier_id":1,"lines":[{"product_id":501,"measure":10,"unit_cost":1}]}`, http.StatusCreated},
+		{http.MethodPost, "/purchase-orders/1/submit", ``, http.StatusOK},
+		{http.MethodPost, "/purchase-orders/1/receipts", `{"lines":[{"product_id":501,"quantity":2500,"expiration":"2099-06-01"}]}`, http.StatusBadRequest},
+		{http.MethodPost, "/purchase-orders/1/receipts", `{"lines":[{"product_id":501,"measure":2.5,"expiration":"2099-06-01"}]}`, http.StatusOK},
+	})
+
+	var order domain.PurchaseOrder
+	serveData(r, http.MethodGet, "/purchase-orders/1", "", &order)
+	var product domain.Product
+	serveData(r, http.MethodGet, "/products/501", "", &product)
+
+	if assert.Len(t, order.Lines, 1) {
+		assert.Equal(t, 10000, order.Lines[0].Quantity)
+		assert.Equal(t, 2500, order.Lines[0].QuantityReceived)
+		assert.Nil(t, order.Lines[0].Measure)
+	}
+	assert.Equal(t, 6000-250+1500+2500, product.Quantity)
+	assert.Nil(t, product.Measure)
+}
+
+func Test_StockMovements_EditFailure(t *testing.T) {
+	r := createServer(t, "my-secret-value", "price_changes.json")
+	p := fixtureProducts(t)
+
+	updated := p[0]
+	updated.Price = domain.Cents(7500)
+	updated.Quantity = 500
+	body, _ := json.Marshal(updated)
+	runSteps(t, r, []step{
+		{http.MethodPut, "/products/1", string(body), http.StatusBadRequest},
+		{http.MethodPatch, "/products/1", `{"price":76.5,"quantity":400}`, http.StatusBadRequest},
+		{http.MethodPatch, "/products/1", `{"quantity":300}`, http.StatusOK},
+	})
+
+	var movements []domain.StockMovement
+	serveData(r, http.MethodGet, "/products/1/movements", "", &movements)
+	after, _ := loadProducts(fixture(t, "products_copy.json"))
+
+	assert.Equal(t, p[0].Price, after[0].Price)
+	assert.Equal(t, 300, after[0].Quantity)
+	if assert.Len(t, movements, 6) {
+		assert.Equal(t, 500-p[0].Quantity, movements[1].Quantity)
+		assert.Equal(t, service.ReasonReversal, movements[2].Reason)
+		assert.Equal(t, p[0].Quantity, movements[2].Balance)
+		assert.Equal(t, service.ReasonReversal, movements[4].Reason)
+		assert.Equal(t, p[0].Quantity, movements[4].Balance)
+		assert.Equal(t, 300, movements[5].Balance)
+	}
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/web"
+	"github.com/gin-gonic/gin"
+)
+
+type SupplierHandler struct {
+	supplierService service.SupplierService
+}
+
+func NewSupplierHandler(supplierService service.SupplierService) *SupplierHandler {
+	return &SupplierHandler{supplierService}
+}
+
+// supplierErrorStatus maps a supplier service error to an HTTP status, using
+// fallback for validation errors.
+func supplierErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, repository.ErrSupplierNotFound),
+		errors.Is(err, repository.ErrSupplierProductNotFound),
+		errors.Is(err, service.ErrNoSupplier),
+		err.Error() == "Product not found":
+		return 404
+	}
+	return fallback
+}
+
+func (h *SupplierHandler) GetAllSuppliers(ctx *gin.Context) {
+	suppliers, err := h.supplierService.GetAllSuppliers()
+	if err != nil {
+		web.Failure(ctx, 500, err)
+		return
+	}
+	web.Success(ctx, 200, suppliers)
+}
+
+func (h *SupplierHandler) GetSupplierByID(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	supplier, err := h.supplierService.GetSupplierByID(id)
+	if err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, supplier)
+}
+
+func (h *SupplierHandler) CreateSupplier(ctx *gin.Context) {
+	var supplier domain.Supplier
+	if err := ctx.ShouldBindJSON(&supplier); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	created, err := h.supplierService.CreateSupplier(&supplier)
+	if err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	web.Success(ctx, 201, created)
+}
+
+func (h *SupplierHandler) UpdateSupplier(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var supplier domain.Supplier
+	if err := ctx.ShouldBindJSON(&supplier); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	if err := h.supplierService.UpdateSupplier(id, &supplier); err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 200, supplier)
+}
+
+// DeleteSupplier godoc
+// @Summary      Delete a supplier
+// @Description  Delete a supplier together with its product links
+// @Tags         suppliers
+// @Param        token header string true "token"
+// @Param        id path int true "supplier id"
+// @Success      204
+// @Failure      404 {object}  web.errorResponse
+// @Router       /suppliers/{id} [delete]
+func (h *SupplierHandler) DeleteSupplier(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	if err := h.supplierService.DeleteSupplier(id); err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 204, nil)
+}
+
+func (h *SupplierHandler) GetSupplierProducts(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	links, err := h.supplierService.GetSupplierProducts(id)
+	if err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, links)
+}
+
+// LinkProduct godoc
+// @Summary      Add a product to a supplier
+// @Description  Record that the supplier sells a product, with its cost price, lead time and supplier SKU
+// @Tags         suppliers
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "supplier id"
+// @Param        link body domain.SupplierProduct true "supplier terms for the product"
+// @Success      201 {object}  web.response
+// @Failure      400 {object}  web.errorResponse
+// @Failure      404 {object}  web.errorResponse
+// @Router       /suppliers/{id}/products [post]
+func (h *SupplierHandler) LinkProduct(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var link domain.SupplierProduct
+	if err := ctx.ShouldBindJSON(&link); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	created, err := h.supplierService.LinkProduct(id, &link)
+	if err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 201, created)
+}
+
+func (h *SupplierHandler) UpdateLink(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	productID, errConverted := strconv.Atoi(ctx.Param("product_id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	var link domain.SupplierProduct
+	if err := ctx.ShouldBindJSON(&link); err != nil {
+		web.Failure(ctx, 400, err)
+		return
+	}
+	if err := h.supplierService.UpdateLink(id, productID, &link); err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 400), err)
+		return
+	}
+	web.Success(ctx, 200, link)
+}
+
+func (h *SupplierHandler) UnlinkProduct(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	productID, errConverted := strconv.Atoi(ctx.Param("product_id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	if err := h.supplierService.UnlinkProduct(id, productID); err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 204, nil)
+}
+
+// GetProductSuppliers godoc
+// @Summary      List the suppliers of a product
+// @Description  List every supplier offer for a product, cheapest first
+// @Tags         suppliers
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "product id"
+// @Success      200 {object}  web.response
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/{id}/suppliers [get]
+func (h *SupplierHandler) GetProductSuppliers(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	offers, err := h.supplierService.GetProductSuppliers(id)
+	if err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, offers)
+}
+
+// GetCheapestSupplier godoc
+// @Summary      Cheapest supplier of a product
+// @Description  The offer with the lowest cost price, the shortest lead time winning ties
+// @Tags         suppliers
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "product id"
+// @Success      200 {object}  web.response
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/{id}/suppliers/cheapest [get]
+func (h *SupplierHandler) GetCheapestSupplier(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	offer, err := h.supplierService.GetCheapestSupplier(id)
+	if err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, offer)
+}
+
+// GetFastestSupplier godoc
+// @Summary      Fastest supplier of a product
+// @Description  The offer with the shortest lead time, the lowest cost price winning ties
+// @Tags         suppliers
+// @Produce      json
+// @Param        token header string true "token"
+// @Param        id path int true "product id"
+// @Success      200 {object}  web.response
+// @Failure      404 {object}  web.errorResponse
+// @Router       /products/{id}/suppliers/fastest [get]
+func (h *SupplierHandler) GetFastestSupplier(ctx *gin.Context) {
+	id, errConverted := strconv.Atoi(ctx.Param("id"))
+	if errConverted != nil {
+		web.Failure(ctx, 400, errConverted)
+		return
+	}
+	offer, err := h.supplierService.GetFastestSupplier(id)
+	if err != nil {
+		web.Failure(ctx, supplierErrorStatus(err, 500), err)
+		return
+	}
+	web.Success(ctx, 200, offer)
+}
@@ -0,0 +1,58 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Suppliers_BestOffer(t *testing.T) {
+	r := createServer(t, "my-secret-value")
+
+	for _, body := range []string{`{"name":"Fresh Farms"}`, `{"name":"Quick Foods"}`, `{"name":"Bulk Co"}`} {
+		req, rr := createRequestTest(http.MethodPost, "/suppliers", body, "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, body)
+	}
+	links := map[string]string{
+		"1": `{"product_id":5,"supplier_sku":"FF-5","cost_price":40,"lead_time_days":3}`,
+		"2": `{"product_id":5,"supplier_sku":"QF-5","cost_price":45.5,"lead_time_days":1}`,
+		"3": `{"product_id":5,"cost_price":40,"lead_time_days":7}`,
+	}
+	for supplier, body := range links {
+		req, rr := createRequestTest(http.MethodPost, "/suppliers/"+supplier+"/products", body, "my-secret-value")
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, body)
+	}
+	req, rr := createRequestTest(http.MethodPost, "/suppliers/1/products", links["1"], "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	req, rr = createRequestTest(http.MethodPost, "/suppliers/1/products", `{"product_id":900,"cost_price":1}`, "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	best := map[string]int{}
+	for _, path := range []string{"cheapest", "fastest"} {
+		req, rr := createRequestTest(http.MethodGet, "/products/5/suppliers/"+path, "", "my-secret-value")
+		r.ServeHTTP(rr, req)
+		offer := map[string]service.ProductSupplier{}
+		_ = json.Unmarshal(rr.Body.Bytes(), &offer)
+		best[path] = offer["data"].Supplier.ID
+	}
+	assert.Equal(t, map[string]int{"cheapest": 1, "fastest": 2}, best)
+
+	req, rr = createRequestTest(http.MethodDelete, "/suppliers/1", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	var offers []service.ProductSupplier
+	rr = serveData(r, http.MethodGet, "/products/5/suppliers", "", &offers)
+	assert.Len(t, offers, 2)
+	assert.Equal(t, 3, offers[0].SupplierID)
+
+	req, rr = createRequestTest(http.MethodGet, "/products/6/suppliers/cheapest", "", "my-secret-value")
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/NPG27/supermarket_dop/cmd/api/handlers"
 	"github.com/NPG27/supermarket_dop/internal/middleware"
@@ -17,30 +22,122 @@ func main() {
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
-	storage := store.NewStore("./data/products.json")
-	productRepo, errProductRepo := repository.NewProductRepository(storage)
-	if errProductRepo != nil {
-		log.Fatalf("Error initializing repository: %v", errProductRepo)
+	storage, errStorage := store.Open(os.Getenv("STORE_DRIVER"), os.Getenv("STORE_PATH"))
+	if errStorage != nil {
+		log.Fatalf("Error initializing storage: %v", errStorage)
+	}
+	if err := store.CheckIntegrity(storage); err != nil {
+		log.Fatalf("Storage integrity check failed: %v", err)
+	}
+	taxPolicy, errTax := newTaxPolicy()
+	if errTax != nil {
+		log.Fatalf("Error configuring taxes: %v", errTax)
+	}
+	dataPath := func(name string) string {
+		return "./data/" + name
+	}
+	exchangeRatesPath := os.Getenv("EXCHANGE_RATES_PATH")
+	if exchangeRatesPath == "" {
+		exchangeRatesPath = dataPath("exchange_rates.json")
+	}
+	services, errServices := handlers.NewServices(storage, dataPath, taxPolicy, exchangeRatesPath)
+	if errServices != nil {
+		log.Fatalf("Error initializing repository: %v", errServices)
+	}
+	if scheduler, errScheduler := newPriceScheduler(services.PriceHistory); errScheduler != nil {
+		log.Fatalf("Error configuring price scheduler: %v", errScheduler)
+	} else {
+		defer scheduler.Stop()
+	}
+	if monitor, errMonitor := newExpiryMonitor(services.ProductRepo); errMonitor != nil {
+		log.Fatalf("Error configuring expiry monitor: %v", errMonitor)
+	} else if monitor != nil {
+		defer monitor.Stop()
 	}
-	productService := service.NewProductService(productRepo)
-	productHandler := handlers.NewProductHandler(productService)
 
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logger())
-	products := router.Group("/products")
-	products.Use(middleware.VerifyToken())
-	{
-		products.GET("", productHandler.GetAllProducts)
-		products.GET("/:id", productHandler.GetProductByID)
-		products.GET("/filter", productHandler.GetProductByPriceGreaterThan)
-		products.POST("", productHandler.CreateProduct)
-		products.PATCH("/:id", productHandler.PatchProduct)
-		products.PUT("/:id", productHandler.UpdateProduct)
-		products.DELETE("/:id", productHandler.DeleteProduct)
-	}
+	handlers.RegisterRoutes(router, services)
 
 	if err := router.Run(); err != nil {
 		panic(err)
 	}
 }
+
+// newTaxPolicy reads TAX_STANDARD_RATE and TAX_REDUCED_RATE, in percent, and
+// TAX_PRICES_INCLUDE_TAX over the defaults.
+func newTaxPolicy() (service.TaxPolicy, error) {
+	policy := service.DefaultTaxPolicy()
+	var err error
+	if raw := os.Getenv("TAX_STANDARD_RATE"); raw != "" {
+		if policy.StandardRate, err = strconv.ParseFloat(raw, 64); err != nil {
+			return policy, fmt.Errorf("TAX_STANDARD_RATE: %w", err)
+		}
+	}
+	if raw := os.Getenv("TAX_REDUCED_RATE"); raw != "" {
+		if policy.ReducedRate, err = strconv.ParseFloat(raw, 64); err != nil {
+			return policy, fmt.Errorf("TAX_REDUCED_RATE: %w", err)
+		}
+	}
+	if raw := os.Getenv("TAX_PRICES_INCLUDE_TAX"); raw != "" {
+		if policy.PricesIncludeTax, err = strconv.ParseBool(raw); err != nil {
+			return policy, fmt.Errorf("TAX_PRICES_INCLUDE_TAX: %w", err)
+		}
+	}
+	if policy.StandardRate < 0 || policy.ReducedRate < 0 {
+		return policy, errors.New("tax rates must not be negative")
+	}
+	return policy, nil
+}
+
+// newPriceScheduler starts applying scheduled price changes every
+// PRICE_SCHEDULE_INTERVAL, one minute by default.
+func newPriceScheduler(history service.PriceHistoryService) (*service.PriceScheduler, error) {
+	interval := time.Minute
+	if raw := os.Getenv("PRICE_SCHEDULE_INTERVAL"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("PRICE_SCHEDULE_INTERVAL: %w", err)
+		}
+		if parsed <= 0 {
+			return nil, errors.New("PRICE_SCHEDULE_INTERVAL must be positive")
+		}
+		interval = parsed
+	}
+	scheduler := service.NewPriceScheduler(history)
+	scheduler.Start(interval)
+	return scheduler, nil
+}
+
+// newExpiryMonitor starts the expiry monitor when EXPIRY_CHECK_INTERVAL is set.
+// EXPIRY_WINDOW, EXPIRY_AUTO_UNPUBLISH and EXPIRY_WEBHOOK_URL tune it.
+func newExpiryMonitor(productRepo repository.ProductRepository) (*service.ExpiryMonitor, error) {
+	rawInterval := os.Getenv("EXPIRY_CHECK_INTERVAL")
+	if rawInterval == "" {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(rawInterval)
+	if err != nil {
+		return nil, fmt.Errorf("EXPIRY_CHECK_INTERVAL: %w", err)
+	}
+	windowDays := 7
+	if rawWindow := os.Getenv("EXPIRY_WINDOW"); rawWindow != "" {
+		if windowDays, err = service.ParseDays(rawWindow); err != nil {
+			return nil, fmt.Errorf("EXPIRY_WINDOW: %w", err)
+		}
+	}
+	autoUnpublish := false
+	if rawAutoUnpublish := os.Getenv("EXPIRY_AUTO_UNPUBLISH"); rawAutoUnpublish != "" {
+		if autoUnpublish, err = strconv.ParseBool(rawAutoUnpublish); err != nil {
+			return nil, fmt.Errorf("EXPIRY_AUTO_UNPUBLISH: %w", err)
+		}
+	}
+	notifier := service.MultiNotifier{service.LogNotifier{}}
+	if url := os.Getenv("EXPIRY_WEBHOOK_URL"); url != "" {
+		notifier = append(notifier, service.NewWebhookNotifier(url))
+	}
+	monitor := service.NewExpiryMonitor(productRepo, notifier, windowDays, autoUnpublish)
+	monitor.Start(interval)
+	return monitor, nil
+}
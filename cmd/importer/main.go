@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+// importer loads the legacy products.json file into a SQLite database so the
+// API can be started with STORE_DRIVER=sqlite.
+func main() {
+	jsonPath := flag.String("json", "./data/products.json", "path to the JSON products file")
+	dbPath := flag.String("db", "./data/products.db", "path to the SQLite database")
+	flag.Parse()
+
+	db, err := store.NewSQLiteStore(*dbPath)
+	if err != nil {
+		log.Fatalf("Error opening database: %v", err)
+	}
+	count, err := store.ImportJSON(*jsonPath, db)
+	if err != nil {
+		log.Fatalf("Error importing products: %v", err)
+	}
+	log.Printf("Imported %d products from %s into %s", count, *jsonPath, *dbPath)
+}
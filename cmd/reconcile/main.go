@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+	"github.com/NPG27/supermarket_dop/internal/service"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+	"github.com/joho/godotenv"
+)
+
+// reconcile rebuilds product quantities from the stock movement ledger. Run
+// it after a crash or after editing the products file by hand.
+func main() {
+	movementsPath := flag.String("movements", "./data/stock_movements.json", "path to the stock movements file")
+	lotsPath := flag.String("lots", "./data/lots.json", "path to the lots file")
+	flag.Parse()
+	_ = godotenv.Load()
+
+	storage, err := store.Open(os.Getenv("STORE_DRIVER"), os.Getenv("STORE_PATH"))
+	if err != nil {
+		log.Fatalf("Error initializing storage: %v", err)
+	}
+	productRepo, err := repository.NewProductRepository(storage)
+	if err != nil {
+		log.Fatalf("Error initializing repository: %v", err)
+	}
+	movementRepo := repository.NewStockMovementRepository(store.NewCollection[domain.StockMovement](*movementsPath))
+	lotRepo := repository.NewLotRepository(store.NewCollection[domain.Lot](*lotsPath))
+	stockService := service.NewStockService(productRepo, movementRepo, lotRepo)
+
+	corrections, err := stockService.Reconcile()
+	for _, c := range corrections {
+		if c.Before != c.After {
+			log.Printf("Product %d: quantity %d -> %d", c.ProductID, c.Before, c.After)
+		}
+		if c.LotQuantity != nil {
+			log.Printf("Product %d: lots hold %d but the ledger %d, count the lots and correct them", c.ProductID, *c.LotQuantity, c.After)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Error reconciling stock: %v", err)
+	}
+	log.Printf("Reconciled stock, %d product(s) reported", len(corrections))
+}
@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidGTIN is returned for barcodes that are not a GS1 trade item
+// number or whose check digit does not match.
+var ErrInvalidGTIN = errors.New("Invalid GTIN")
+
+// BarcodePackaging tells what one scan of a barcode stands for.
+type BarcodePackaging string
+
+const (
+	// PackagingUnit is a barcode printed on a single unit.
+	PackagingUnit BarcodePackaging = "unit"
+	// PackagingCase is a barcode printed on a case holding several units.
+	PackagingCase BarcodePackaging = "case"
+)
+
+// Barcode is a GS1 trade item number identifying a product or a case of it.
+// swagger:model
+type Barcode struct {
+	// The GTIN as EAN-8, UPC-A, EAN-13 or GTIN-14. It is stored as 14 digits.
+	//
+	// required: true
+	// example: "07501031311309"
+	GTIN string `json:"gtin"`
+
+	// The same number as EAN-13, when it fits, set by the system.
+	//
+	// example: "7501031311309"
+	EAN13 string `json:"ean13,omitempty"`
+
+	// The same number as UPC-A, when it fits, set by the system.
+	//
+	// example: "012345678905"
+	UPCA string `json:"upc_a,omitempty"`
+
+	// unit or case. Empty means unit.
+	//
+	// required: false
+	// example: "case"
+	Packaging BarcodePackaging `json:"packaging,omitempty"`
+
+	// How many units one scan stands for: 1 for a unit, at least 2 for a case.
+	//
+	// required: false
+	// example: 12
+	Units int `json:"units,omitempty"`
+}
+
+// GS1CheckDigit returns the check digit for digits, a GTIN without its last
+// digit: from the right, digits are weighted 3, 1, 3, ... and the check digit
+// brings the sum up to a multiple of 10.
+func GS1CheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// NormalizeGTIN checks an EAN-8, UPC-A, EAN-13 or GTIN-14 and returns it as
+// the 14 digit GTIN, padded with leading zeros. Spaces and hyphens are
+// ignored.
+func NormalizeGTIN(code string) (string, error) {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	switch len(digits) {
+	case 8, 12, 13, 14:
+	default:
+		return "", fmt.Errorf("%w %q: expected 8, 12, 13 or 14 digits", ErrInvalidGTIN, code)
+	}
+	if !digitsOnly(digits) {
+		return "", fmt.Errorf("%w %q: expected digits only", ErrInvalidGTIN, code)
+	}
+	if check := GS1CheckDigit(digits[:len(digits)-1]); digits[len(digits)-1] != check {
+		return "", fmt.Errorf("%w %q: check digit should be %c", ErrInvalidGTIN, code, check)
+	}
+	return strings.Repeat("0", 14-len(digits)) + digits, nil
+}
+
+// Normalize validates the GTIN and fills in its other forms, the packaging
+// and the units.
+func (b *Barcode) Normalize() error {
+	gtin, err := NormalizeGTIN(b.GTIN)
+	if err != nil {
+		return err
+	}
+	b.GTIN = gtin
+	b.EAN13, b.UPCA = "", ""
+	if strings.HasPrefix(gtin, "0") {
+		b.EAN13 = gtin[1:]
+	}
+	if strings.HasPrefix(gtin, "00") {
+		b.UPCA = gtin[2:]
+	}
+	switch b.Packaging {
+	case "", PackagingUnit:
+		b.Packaging = PackagingUnit
+		if b.Units == 0 {
+			b.Units = 1
+		}
+		if b.Units != 1 {
+			return fmt.Errorf("barcode %s: a unit barcode stands for 1 unit", gtin)
+		}
+	case PackagingCase:
+		if b.Units < 2 {
+			return fmt.Errorf("barcode %s: a case barcode needs units of at least 2", gtin)
+		}
+	default:
+		return fmt.Errorf("barcode %s: packaging must be %s or %s", gtin, PackagingUnit, PackagingCase)
+	}
+	return nil
+}
+
+// EmbeddedBarcode is a label printed in store by a scale: an EAN-13 whose
+// prefix 20 to 29 is reserved for in-store use. The next five digits are the
+// PLU of the item and the five after them a value, with its own check digit
+// last. Prefixes 20 to 24 carry the price of the label in cents and 25 to 29
+// its weight in grams, or volume in millilitres.
+type EmbeddedBarcode struct {
+	PLU string
+	// Price is set for price-embedded labels.
+	Price Money
+	// Weight is set for weight-embedded labels, in grams or millilitres.
+	Weight int
+}
+
+// ParseEmbeddedBarcode reads a price- or weight-embedded EAN-13. ok is false
+// for codes that are not one; err is set for ones that are but do not check.
+func ParseEmbeddedBarcode(code string) (embedded EmbeddedBarcode, ok bool, err error) {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	if len(digits) != 13 || digits[0] != '2' || !digitsOnly(digits) {
+		return EmbeddedBarcode{}, false, nil
+	}
+	if check := GS1CheckDigit(digits[:12]); digits[12] != check {
+		return EmbeddedBarcode{}, true, fmt.Errorf("%w %q: check digit should be %c", ErrInvalidGTIN, code, check)
+	}
+	plu, err := NormalizePLU(digits[2:7])
+	if err != nil {
+		return EmbeddedBarcode{}, true, fmt.Errorf("%w %q: %v", ErrInvalidGTIN, code, err)
+	}
+	value, _ := strconv.ParseInt(digits[7:12], 10, 64)
+	embedded = EmbeddedBarcode{PLU: plu}
+	if digits[1] < '5' {
+		embedded.Price = Cents(value)
+	} else {
+		embedded.Weight = int(value)
+	}
+	if value == 0 {
+		return EmbeddedBarcode{}, true, fmt.Errorf("%w %q: the label carries no price or weight", ErrInvalidGTIN, code)
+	}
+	return embedded, true, nil
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGS1CheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   byte
+	}{
+		{"9638507", '4'},
+		{"03600029145", '2'},
+		{"400638133393", '1'},
+		{"1003600029145", '9'},
+		{"250401101250", '5'},
+		{"200401100600", '4'},
+		{"0000000", '0'},
+	}
+	for _, test := range tests {
+		assert.Equal(t, string(test.want), string(GS1CheckDigit(test.digits)), test.digits)
+	}
+}
+
+func TestNormalizeGTIN(t *testing.T) {
+	tests := []struct {
+		code  string
+		want  string
+		valid bool
+	}{
+		{"96385074", "00000096385074", true},
+		{"036000291452", "00036000291452", true},
+		{"4006381333931", "04006381333931", true},
+		{"10036000291459", "10036000291459", true},
+		{" 400-6381-33393-1 ", "04006381333931", true},
+		{"0036 0002 9145 2", "00036000291452", true},
+		{"96385075", "", false},
+		{"036000291453", "", false},
+		{"4006381333932", "", false},
+		{"10036000291458", "", false},
+		{"", "", false},
+		{"1234567", "", false},
+		{"123456789", "", false},
+		{"400638133393A", "", false},
+		{"100360002914590", "", false},
+	}
+	for _, test := range tests {
+		gtin, err := NormalizeGTIN(test.code)
+		if !test.valid {
+			assert.True(t, errors.Is(err, ErrInvalidGTIN), test.code)
+			continue
+		}
+		if assert.NoError(t, err, test.code) {
+			assert.Equal(t, test.want, gtin, test.code)
+		}
+	}
+}
+
+func TestParseEmbeddedBarcode(t *testing.T) {
+	tests := []struct {
+		code     string
+		want     EmbeddedBarcode
+		embedded bool
+		valid    bool
+	}{
+		{"2504011012505", EmbeddedBarcode{PLU: "4011", Weight: 1250}, true, true},
+		{"2004011006004", EmbeddedBarcode{PLU: "4011", Price: Cents(600)}, true, true},
+		{"2504011012506", EmbeddedBarcode{}, true, false},
+		{"2004011006005", EmbeddedBarcode{}, true, false},
+		{"2504011000007", EmbeddedBarcode{}, true, false},
+		{"4006381333931", EmbeddedBarcode{}, false, true},
+		{"036000291452", EmbeddedBarcode{}, false, true},
+		{"4011", EmbeddedBarcode{}, false, true},
+		{"S82254D", EmbeddedBarcode{}, false, true},
+	}
+	for _, test := range tests {
+		embedded, ok, err := ParseEmbeddedBarcode(test.code)
+		assert.Equal(t, test.embedded, ok, test.code)
+		if !test.valid {
+			assert.True(t, errors.Is(err, ErrInvalidGTIN), test.code)
+			continue
+		}
+		if assert.NoError(t, err, test.code) {
+			assert.Equal(t, test.want, embedded, test.code)
+		}
+	}
+}
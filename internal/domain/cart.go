@@ -0,0 +1,84 @@
+package domain
+
+import "time"
+
+// CartStatus tells whether a cart can still be changed.
+type CartStatus string
+
+const (
+	CartOpen       CartStatus = "open"
+	CartCheckedOut CartStatus = "checked_out"
+)
+
+// Cart holds the products scanned at a till until checkout.
+// swagger:model
+type Cart struct {
+	// The ID of the cart.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// Whether the cart is open or checked out, set by the system.
+	//
+	// example: "open"
+	Status CartStatus `json:"status"`
+
+	// The scanned products, one line per product, except that every weighed
+	// or scale labelled item gets a line of its own.
+	Lines []CartLine `json:"lines"`
+
+	// The sale created at checkout.
+	//
+	// example: 7
+	SaleID int `json:"sale_id,omitempty"`
+
+	// When the cart was opened, in UTC.
+	CreatedAt time.Time `json:"created_at"`
+
+	// When the cart was last changed, in UTC.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CartLine is a scanned product and how many units of it are in the cart.
+type CartLine struct {
+	// The scanned product.
+	//
+	// example: 12
+	ProductID int `json:"product_id"`
+
+	// The code value of the product. Lines are removed by it or by any
+	// other code of the product: a barcode, its PLU or the scale label
+	// that added the line.
+	//
+	// required: true
+	// example: "S82254D"
+	CodeValue string `json:"code_value"`
+
+	// The name of the product when it was scanned.
+	//
+	// example: "Milk"
+	Name string `json:"name"`
+
+	// How many units are in the cart. For products sold by the kilogram or
+	// litre, how many grams or millilitres.
+	//
+	// required: false
+	// example: 2
+	Quantity int `json:"quantity"`
+
+	// The unit the product is sold in, for weighed items.
+	//
+	// example: "kg"
+	Unit UnitOfMeasure `json:"unit,omitempty"`
+
+	// How much of the unit was weighed, for weighed items.
+	//
+	// example: 0.355
+	Measure *Measure `json:"measure,omitempty" swaggertype:"number"`
+
+	// The price printed on a price-embedded scale label, which is charged
+	// as it is.
+	//
+	// example: 6
+	LabelPrice *Money `json:"label_price,omitempty" swaggertype:"number"`
+}
@@ -0,0 +1,28 @@
+package domain
+
+// Category is a node of the product taxonomy, e.g. "Dairy" under "Fresh".
+// swagger:model
+type Category struct {
+	// The ID of the category.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// The display name of the category.
+	//
+	// required: true
+	// example: "Dairy"
+	Name string `json:"name"`
+
+	// A unique URL friendly identifier, derived from the name when omitted.
+	//
+	// required: false
+	// example: "dairy"
+	Slug string `json:"slug"`
+
+	// The parent category, null for top level categories.
+	//
+	// required: false
+	// example: 3
+	ParentID *int `json:"parent_id"`
+}
@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateLayout is the canonical format used whenever a Date is written out.
+const DateLayout = "2006-01-02"
+
+// legacyDateLayout is the dd/mm/yyyy format found in the original data files.
+const legacyDateLayout = "02/01/2006"
+
+// Date is a calendar day without time of day or time zone. It reads ISO-8601
+// dates (optionally with a time part, which is dropped) as well as the legacy
+// dd/mm/yyyy format, and always writes YYYY-MM-DD.
+type Date struct {
+	t time.Time
+}
+
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf returns the calendar day of t in t's own location.
+func DateOf(t time.Time) Date {
+	return NewDate(t.Year(), t.Month(), t.Day())
+}
+
+// Today returns the current calendar day in local time.
+func Today() Date {
+	return DateOf(time.Now())
+}
+
+func ParseDate(value string) (Date, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{DateLayout, legacyDateLayout} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return Date{t}, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return DateOf(t), nil
+	}
+	return Date{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD or DD/MM/YYYY", value)
+}
+
+func (d Date) IsZero() bool {
+	return d.t.IsZero()
+}
+
+// Time returns midnight UTC of the day.
+func (d Date) Time() time.Time {
+	return d.t
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.t.Format(DateLayout)
+}
+
+// Compare returns -1, 0 or 1 depending on whether d is before, equal to or
+// after other.
+func (d Date) Compare(other Date) int {
+	return d.t.Compare(other.t)
+}
+
+func (d Date) Before(other Date) bool {
+	return d.t.Before(other.t)
+}
+
+func (d Date) After(other Date) bool {
+	return d.t.After(other.t)
+}
+
+func (d Date) AddDays(days int) Date {
+	return Date{d.t.AddDate(0, 0, days)}
+}
+
+// DaysUntil returns the number of days from d to other, negative when other
+// is in the past.
+func (d Date) DaysUntil(other Date) int {
+	return int(other.t.Sub(d.t).Hours() / 24)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = Date{}
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid date %s: expected a string", data)
+	}
+	if value == "" {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value stores the date as canonical text.
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan reads dates stored as text in either supported format.
+func (d *Date) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*d = Date{}
+		return nil
+	case string:
+		return d.scanText(value)
+	case []byte:
+		return d.scanText(string(value))
+	case time.Time:
+		*d = DateOf(value)
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into Date", src)
+}
+
+func (d *Date) scanText(value string) error {
+	if value == "" {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
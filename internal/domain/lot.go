@@ -0,0 +1,55 @@
+package domain
+
+// Lot is a batch of a product received together and sharing an expiration
+// date. A product tracked by lots takes its quantity and expiration from
+// them.
+// swagger:model
+type Lot struct {
+	// The ID of the lot.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// The product the lot belongs to.
+	//
+	// example: 12
+	ProductID int `json:"product_id"`
+
+	// The lot number printed by the manufacturer, unique per product.
+	//
+	// required: true
+	// example: "L2304-118"
+	LotNumber string `json:"lot_number"`
+
+	// The units of the lot still in stock, set by the system.
+	//
+	// example: 24
+	Quantity int `json:"quantity"`
+
+	// The units received when creating the lot, in the product's unit, for
+	// products sold by weight or volume. It is only read on requests.
+	//
+	// required: false
+	// example: 12.5
+	Measure *Measure `json:"measure,omitempty" swaggertype:"number"`
+
+	// The expiration date of every unit in the lot.
+	//
+	// required: true
+	// example: "2024-03-15"
+	Expiration Date `json:"expiration" swaggertype:"string"`
+
+	// The day the lot arrived.
+	//
+	// required: false
+	// example: "2023-04-10"
+	ReceivedAt Date `json:"received_at" swaggertype:"string"`
+}
+
+// LotAllocation is the part of a stock movement that affected one lot.
+type LotAllocation struct {
+	LotID     int    `json:"lot_id"`
+	LotNumber string `json:"lot_number"`
+	// The signed change in the lot's stock.
+	Quantity int `json:"quantity"`
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnitOfMeasure is the unit a product is sold and priced in.
+type UnitOfMeasure string
+
+const (
+	UnitEach     UnitOfMeasure = "each"
+	UnitKilogram UnitOfMeasure = "kg"
+	UnitGram     UnitOfMeasure = "g"
+	UnitLitre    UnitOfMeasure = "l"
+)
+
+// ParseUnit reads a unit in any case. Empty means each.
+func ParseUnit(text string) (UnitOfMeasure, error) {
+	switch unit := UnitOfMeasure(strings.ToLower(strings.TrimSpace(text))); unit {
+	case "", UnitEach:
+		return UnitEach, nil
+	case UnitKilogram, UnitGram, UnitLitre:
+		return unit, nil
+	}
+	return "", fmt.Errorf("Invalid unit %q, one of: %s, %s, %s, %s", text, UnitEach, UnitKilogram, UnitGram, UnitLitre)
+}
+
+// Weighed tells whether the unit is a weight or a volume rather than a count.
+func (u UnitOfMeasure) Weighed() bool {
+	return u != "" && u != UnitEach
+}
+
+// BaseUnits returns how many stock units make one u. Stock of products sold
+// by the kilogram or litre is counted in grams or millilitres, so it stays a
+// whole number.
+func (u UnitOfMeasure) BaseUnits() int64 {
+	switch u {
+	case UnitKilogram, UnitLitre:
+		return 1000
+	}
+	return 1
+}
+
+// Cost returns what quantity stock units cost at price per u, rounded to the
+// nearest cent.
+func (u UnitOfMeasure) Cost(price Money, quantity int) Money {
+	return price.MulRatio(int64(quantity), u.BaseUnits())
+}
+
+// Measure is an exact amount of a unit of measure with up to three decimals,
+// such as 0.355 kg. Its JSON form is a plain number.
+type Measure struct {
+	milli int64
+}
+
+// ParseMeasure reads a decimal with at most three decimals.
+func ParseMeasure(text string) (Measure, error) {
+	milli, ok := parseFixed(text, 3)
+	if !ok {
+		return Measure{}, fmt.Errorf("invalid measure %q: expected a number with at most 3 decimals", strings.TrimSpace(text))
+	}
+	return Measure{milli: milli}, nil
+}
+
+// MeasureOf returns quantity stock units of a product sold in unit, in unit.
+func MeasureOf(quantity int, unit UnitOfMeasure) Measure {
+	return Measure{milli: int64(quantity) * 1000 / unit.BaseUnits()}
+}
+
+// Quantity returns the measure as stock units of a product sold in unit. It
+// fails when the measure is finer than a stock unit, such as 0.5 g.
+func (m Measure) Quantity(unit UnitOfMeasure) (int, error) {
+	base := unit.BaseUnits()
+	if m.milli*base%1000 != 0 {
+		return 0, fmt.Errorf("%s %s is finer than the product is counted in", m, unit)
+	}
+	return int(m.milli * base / 1000), nil
+}
+
+func (m Measure) IsZero() bool {
+	return m.milli == 0
+}
+
+func (m Measure) Sign() int {
+	switch {
+	case m.milli < 0:
+		return -1
+	case m.milli > 0:
+		return 1
+	}
+	return 0
+}
+
+// String writes the shortest exact decimal, as in "0.355" or "2".
+func (m Measure) String() string {
+	return shortestFixed(m.milli, 3)
+}
+
+func (m Measure) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON reads a number or a numeric string.
+func (m *Measure) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Measure{}
+		return nil
+	}
+	text, err := jsonDecimal(data, 3)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseMeasure(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// NormalizePLU checks a price look-up code, the 4 or 5 digit number keyed in
+// for loose produce, and returns it without extra leading zeros, as in "4011"
+// for "04011".
+func NormalizePLU(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if len(code) < 4 || len(code) > 5 || !digitsOnly(code) {
+		return "", fmt.Errorf("Invalid PLU %q: expected 4 or 5 digits", code)
+	}
+	trimmed := strings.TrimLeft(code, "0")
+	if len(trimmed) < 4 {
+		trimmed = strings.Repeat("0", 4-len(trimmed)) + trimmed
+	}
+	return trimmed, nil
+}
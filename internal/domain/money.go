@@ -0,0 +1,345 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency code.
+type Currency string
+
+// DefaultCurrency is the currency prices are kept in.
+const DefaultCurrency Currency = "USD"
+
+// ParseCurrency reads a three letter currency code in any case, as in "eur".
+func ParseCurrency(text string) (Currency, error) {
+	code := strings.ToUpper(strings.TrimSpace(text))
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency %q: expected a three letter code", text)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency %q: expected a three letter code", text)
+		}
+	}
+	return Currency(code), nil
+}
+
+// Money is an exact amount of a currency, kept as a whole number of
+// hundredths. Its JSON form is a plain number such as 352.79, so files and
+// clients that predate Money keep working; the currency is not part of it and
+// records that can hold amounts in other currencies report theirs alongside.
+// The zero value is zero in DefaultCurrency.
+type Money struct {
+	cents    int64
+	currency Currency
+}
+
+// NewMoney returns cents hundredths of currency.
+func NewMoney(cents int64, currency Currency) Money {
+	return Money{cents: cents, currency: currency}.normalized()
+}
+
+// Cents returns cents hundredths of DefaultCurrency.
+func Cents(cents int64) Money {
+	return Money{cents: cents}
+}
+
+// ParseMoney reads a decimal amount of DefaultCurrency such as "352.79",
+// "-3" or "0.5". More than two decimals are rejected rather than rounded.
+func ParseMoney(text string) (Money, error) {
+	cents, ok := parseFixed(text, 2)
+	if !ok {
+		return Money{}, fmt.Errorf("invalid amount %q: expected a number with at most 2 decimals", strings.TrimSpace(text))
+	}
+	return Cents(cents), nil
+}
+
+// parseFixed reads a decimal with at most places decimals as a whole number
+// of 10^-places.
+func parseFixed(text string, places int) (int64, bool) {
+	text = strings.TrimSpace(text)
+	unsigned := strings.TrimPrefix(strings.TrimPrefix(text, "-"), "+")
+	whole, fraction, _ := strings.Cut(unsigned, ".")
+	if whole == "" && fraction == "" || len(fraction) > places || !digitsOnly(whole) || !digitsOnly(fraction) {
+		return 0, false
+	}
+	fraction += strings.Repeat("0", places-len(fraction))
+	value, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if strings.HasPrefix(text, "-") {
+		value = -value
+	}
+	return value, true
+}
+
+// formatFixed writes value, a whole number of 10^-places, with places
+// decimals.
+func formatFixed(value int64, places int) string {
+	sign := ""
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	unit := int64(math.Pow10(places))
+	return fmt.Sprintf("%s%d.%0*d", sign, value/unit, places, value%unit)
+}
+
+// shortestFixed is formatFixed without trailing zeros, as in 71.4 or 3.
+func shortestFixed(value int64, places int) string {
+	return strings.TrimSuffix(strings.TrimRight(formatFixed(value, places), "0"), ".")
+}
+
+// jsonDecimal returns the text of a JSON number or numeric string. Exponents
+// are valid JSON numbers and are expanded exactly to places decimals.
+func jsonDecimal(data []byte, places int) (string, error) {
+	text := string(data)
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return "", err
+		}
+	} else if strings.ContainsAny(text, "eE") {
+		r, ok := new(big.Rat).SetString(text)
+		if !ok {
+			return "", fmt.Errorf("invalid number %s", data)
+		}
+		text = r.FloatString(places)
+		if back, _ := new(big.Rat).SetString(text); back.Cmp(r) != 0 {
+			return "", fmt.Errorf("invalid number %s: more than %d decimals", data, places)
+		}
+	}
+	return text, nil
+}
+
+func digitsOnly(text string) bool {
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) normalized() Money {
+	if m.currency == DefaultCurrency {
+		m.currency = ""
+	}
+	return m
+}
+
+// Cents returns the amount in hundredths.
+func (m Money) Cents() int64 {
+	return m.cents
+}
+
+func (m Money) Currency() Currency {
+	if m.currency == "" {
+		return DefaultCurrency
+	}
+	return m.currency
+}
+
+// In returns the same amount labelled with currency. It does not convert.
+func (m Money) In(currency Currency) Money {
+	return NewMoney(m.cents, currency)
+}
+
+func (m Money) IsZero() bool {
+	return m.cents == 0
+}
+
+// Sign returns -1, 0 or 1.
+func (m Money) Sign() int {
+	switch {
+	case m.cents < 0:
+		return -1
+	case m.cents > 0:
+		return 1
+	}
+	return 0
+}
+
+// mustMatch panics when m and other are in different currencies, which is
+// always a programming error: amounts must be converted first.
+func (m Money) mustMatch(other Money) {
+	if m.Currency() != other.Currency() {
+		panic(fmt.Sprintf("domain: mixing %s and %s amounts", m.Currency(), other.Currency()))
+	}
+}
+
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	m.cents += other.cents
+	return m
+}
+
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	m.cents -= other.cents
+	return m
+}
+
+func (m Money) Neg() Money {
+	m.cents = -m.cents
+	return m
+}
+
+// Mul multiplies by a whole quantity.
+func (m Money) Mul(quantity int) Money {
+	m.cents *= int64(quantity)
+	return m
+}
+
+// MulRat multiplies by an exact fraction, rounding half away from zero to the
+// nearest hundredth.
+func (m Money) MulRat(factor *big.Rat) Money {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(m.cents), factor)
+	num, den := product.Num(), product.Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	m.cents = quotient.Int64()
+	return m
+}
+
+// MulRatio multiplies by num/den, rounding like MulRat.
+func (m Money) MulRatio(num, den int64) Money {
+	return m.MulRat(big.NewRat(num, den))
+}
+
+// ExactRat returns the decimal value of f as an exact fraction, so that 10.5
+// is 21/2 rather than the nearest binary fraction.
+func ExactRat(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	return r
+}
+
+// Percent returns percent percent of m, rounded like MulRat.
+func (m Money) Percent(percent float64) Money {
+	return m.MulRat(new(big.Rat).Quo(ExactRat(percent), big.NewRat(100, 1)))
+}
+
+// Allocate splits m in proportion to weights. The hundredths left over after
+// rounding down go to the largest remainders, earliest first, so the parts
+// always add up to m. Zero total weight gives zero parts.
+func (m Money) Allocate(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+	var total int64
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 {
+		for i := range parts {
+			parts[i] = Money{currency: m.currency}
+		}
+		return parts
+	}
+	sign := int64(1)
+	cents := m.cents
+	if cents < 0 {
+		sign, cents = -1, -cents
+	}
+	remainders := make([]*big.Int, len(weights))
+	given := int64(0)
+	for i, weight := range weights {
+		quotient, remainder := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(cents), big.NewInt(weight)), big.NewInt(total), new(big.Int))
+		parts[i] = Money{cents: quotient.Int64(), currency: m.currency}
+		remainders[i] = remainder
+		given += quotient.Int64()
+	}
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]].Cmp(remainders[order[b]]) > 0 })
+	for k := 0; given < cents && k < len(order); k++ {
+		parts[order[k]].cents++
+		given++
+	}
+	for i := range parts {
+		parts[i].cents *= sign
+	}
+	return parts
+}
+
+// Compare returns -1, 0 or 1 depending on whether m is less than, equal to or
+// greater than other.
+func (m Money) Compare(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.cents < other.cents:
+		return -1
+	case m.cents > other.cents:
+		return 1
+	}
+	return 0
+}
+
+// Float64 approximates the amount for code that only needs a magnitude, such
+// as rates and scores. Never compute amounts with it.
+func (m Money) Float64() float64 {
+	return float64(m.cents) / 100
+}
+
+// String writes the amount with two decimals, as in "71.40".
+func (m Money) String() string {
+	return formatFixed(m.cents, 2)
+}
+
+// MarshalJSON writes the shortest exact number, as in 352.79 or 71.4.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(shortestFixed(m.cents, 2)), nil
+}
+
+// UnmarshalJSON reads a number or a numeric string.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Money{}
+		return nil
+	}
+	text, err := jsonDecimal(data, 2)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as a whole number of cents, so it never goes
+// through a float.
+func (m Money) Value() (driver.Value, error) {
+	return m.cents, nil
+}
+
+// Scan reads an amount stored as whole cents, or as decimal text.
+func (m *Money) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case int64:
+		*m = Cents(value)
+	case []byte:
+		return m.UnmarshalJSON(value)
+	case string:
+		return m.UnmarshalJSON([]byte(value))
+	case nil:
+		*m = Money{}
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoney_MulRat(t *testing.T) {
+	tests := []struct {
+		cents    int64
+		num, den int64
+		want     int64
+	}{
+		{1000, 1, 3, 333},
+		{2000, 1, 3, 667},
+		{5, 1, 2, 3},
+		{-5, 1, 2, -3},
+		{1, 1, 2, 1},
+		{-1, 1, 2, -1},
+		{3333, 3, 10, 1000},
+		{7142, 355, 1000, 2535},
+		{0, 7, 9, 0},
+	}
+	for _, test := range tests {
+		assert.Equal(t, Cents(test.want), Cents(test.cents).MulRat(big.NewRat(test.num, test.den)), "%d * %d/%d", test.cents, test.num, test.den)
+		assert.Equal(t, Cents(test.want), Cents(test.cents).MulRatio(test.num, test.den), "%d * %d/%d", test.cents, test.num, test.den)
+	}
+}
+
+func TestMoney_Percent(t *testing.T) {
+	assert.Equal(t, Cents(750), Cents(7142).Percent(10.5))
+	assert.Equal(t, Cents(3571), Cents(7142).Percent(50))
+	assert.Equal(t, Cents(35279), Cents(35279).Percent(100))
+	assert.Equal(t, big.NewRat(21, 2), ExactRat(10.5))
+	assert.Equal(t, big.NewRat(1, 10), ExactRat(0.1))
+}
+
+func TestMoney_Allocate(t *testing.T) {
+	tests := []struct {
+		cents   int64
+		weights []int64
+		want    []int64
+	}{
+		{1000, []int64{1, 1, 1}, []int64{334, 333, 333}},
+		{100, []int64{1, 2}, []int64{33, 67}},
+		{-1000, []int64{1, 1, 1}, []int64{-334, -333, -333}},
+		{5, []int64{3, 3, 3, 1}, []int64{2, 2, 1, 0}},
+		{1000, []int64{0, 0}, []int64{0, 0}},
+		{1000, []int64{7142, 35279}, []int64{168, 832}},
+	}
+	for _, test := range tests {
+		parts := Cents(test.cents).Allocate(test.weights)
+		var sum, want Money
+		got := make([]int64, len(parts))
+		for i, part := range parts {
+			got[i] = part.Cents()
+			sum = sum.Add(part)
+			if test.weights[i] != 0 {
+				want = Cents(test.cents)
+			}
+		}
+		assert.Equal(t, test.want, got, "%d over %v", test.cents, test.weights)
+		assert.Equal(t, want, sum, "%d over %v", test.cents, test.weights)
+	}
+}
+
+func TestMoney_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json  string
+		want  int64
+		valid bool
+	}{
+		{`352.79`, 35279, true},
+		{`"352.79"`, 35279, true},
+		{`71.4`, 7140, true},
+		{`-3`, -300, true},
+		{`1e2`, 10000, true},
+		{`1.5E1`, 1500, true},
+		{`3.5e-1`, 35, true},
+		{`35279e-2`, 35279, true},
+		{`null`, 0, true},
+		{`1e-3`, 0, false},
+		{`1.234`, 0, false},
+		{`"abc"`, 0, false},
+		{`"1,5"`, 0, false},
+	}
+	for _, test := range tests {
+		var m Money
+		err := json.Unmarshal([]byte(test.json), &m)
+		if !test.valid {
+			assert.Error(t, err, test.json)
+			continue
+		}
+		if assert.NoError(t, err, test.json) {
+			assert.Equal(t, Cents(test.want), m, test.json)
+		}
+	}
+}
+
+func TestMoney_Scan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want int64
+	}{
+		{int64(35279), 35279},
+		{int64(-7), -7},
+		{[]byte("71.4"), 7140},
+		{"3", 300},
+		{nil, 0},
+	}
+	for _, test := range tests {
+		var m Money
+		if assert.NoError(t, m.Scan(test.src), "%v", test.src) {
+			assert.Equal(t, Cents(test.want), m, "%v", test.src)
+		}
+	}
+	var m Money
+	assert.Error(t, m.Scan(true))
+	// Amounts are stored in cents; a float is not read as one.
+	assert.Error(t, m.Scan(1.15))
+}
+
+func TestMoney_RoundTrip(t *testing.T) {
+	for _, cents := range []int64{0, 1, 7, 29, 115, 7140, 35279, -7, -35279, 12345678999} {
+		m := Cents(cents)
+
+		data, err := json.Marshal(m)
+		assert.NoError(t, err)
+		var fromJSON Money
+		assert.NoError(t, json.Unmarshal(data, &fromJSON))
+		assert.Equal(t, m, fromJSON, string(data))
+
+		value, err := m.Value()
+		assert.NoError(t, err)
+		var fromSQL Money
+		assert.NoError(t, fromSQL.Scan(value))
+		assert.Equal(t, m, fromSQL, "%v", value)
+
+		parsed, err := ParseMoney(m.String())
+		assert.NoError(t, err)
+		assert.Equal(t, m, parsed, m.String())
+	}
+	assert.Equal(t, "71.40", Cents(7140).String())
+	assert.Equal(t, "-0.05", Cents(-5).String())
+	data, _ := json.Marshal(Cents(7140))
+	assert.Equal(t, "71.4", string(data))
+}
+
+func TestMoney_Currencies(t *testing.T) {
+	euros := NewMoney(1000, "EUR")
+
+	assert.Equal(t, Cents(1000), NewMoney(1000, DefaultCurrency))
+	assert.Equal(t, DefaultCurrency, Money{}.Currency())
+	// Every operation keeps the currency, so amounts worked out from a
+	// localized price can be combined with each other.
+	assert.NotPanics(t, func() {
+		total := euros.Add(euros.Percent(21)).Sub(euros.MulRatio(1, 3)).Add(euros.Mul(2)).Add(euros.Neg())
+		for _, part := range euros.Allocate([]int64{1, 2}) {
+			total = total.Add(part)
+		}
+		assert.Equal(t, Currency("EUR"), total.Currency())
+		assert.Equal(t, 1, total.Compare(NewMoney(0, "EUR")))
+	})
+	assert.Equal(t, Currency("EUR"), Cents(1000).In("EUR").Currency())
+	assert.Equal(t, Currency("EUR"), NewMoney(0, "EUR").Allocate([]int64{0})[0].Currency())
+
+	assert.Panics(t, func() { euros.Add(Cents(1)) })
+	assert.Panics(t, func() { Cents(1).Sub(euros) })
+	assert.Panics(t, func() { euros.Compare(Money{}) })
+}
+
+func TestParseCurrency(t *testing.T) {
+	code, err := ParseCurrency(" eur ")
+	assert.NoError(t, err)
+	assert.Equal(t, Currency("EUR"), code)
+	for _, text := range []string{"", "EURO", "E1R", "€"} {
+		_, err := ParseCurrency(text)
+		assert.Error(t, err, text)
+	}
+}
@@ -0,0 +1,71 @@
+package domain
+
+import "time"
+
+// PriceChangeStatus tells whether a price change is waiting for its time,
+// took effect or was withdrawn before it did.
+type PriceChangeStatus string
+
+const (
+	PriceChangeScheduled PriceChangeStatus = "scheduled"
+	PriceChangeApplied   PriceChangeStatus = "applied"
+	PriceChangeCancelled PriceChangeStatus = "cancelled"
+)
+
+// PriceChange is one entry of a product's price history. Applied changes are
+// never modified; scheduled ones are applied or cancelled.
+// swagger:model
+type PriceChange struct {
+	// The ID of the change.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// The product whose price changes.
+	//
+	// example: 1
+	ProductID int `json:"product_id"`
+
+	// The price before the change, set when it is applied.
+	//
+	// example: 71.42
+	OldPrice Money `json:"old_price" swaggertype:"number"`
+
+	// The price after the change.
+	//
+	// required: true
+	// example: 69.99
+	NewPrice Money `json:"new_price" swaggertype:"number"`
+
+	// Who made the change.
+	//
+	// example: "jane"
+	ChangedBy string `json:"changed_by"`
+
+	// Why the price changed.
+	//
+	// required: false
+	// example: "Supplier cost increase"
+	Reason string `json:"reason,omitempty"`
+
+	// When the new price takes effect. Empty means right away.
+	//
+	// required: false
+	// example: "2024-07-01T06:00:00Z"
+	EffectiveAt time.Time `json:"effective_at"`
+
+	// scheduled, applied or cancelled.
+	//
+	// example: "applied"
+	Status PriceChangeStatus `json:"status"`
+
+	// When the change was recorded.
+	//
+	// example: "2024-06-20T15:04:05Z"
+	CreatedAt time.Time `json:"created_at"`
+
+	// When the product's price was actually changed.
+	//
+	// example: "2024-07-01T06:00:12Z"
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
@@ -0,0 +1,65 @@
+package domain
+
+import "time"
+
+// ExchangeRate is how many units of Currency one unit of DefaultCurrency buys.
+// swagger:model
+type ExchangeRate struct {
+	// The currency the rate converts to.
+	//
+	// required: true
+	// example: "EUR"
+	Currency Currency `json:"currency"`
+
+	// Units of Currency per unit of DefaultCurrency.
+	//
+	// required: true
+	// example: 0.92
+	Rate float64 `json:"rate"`
+
+	// When the rate was last set.
+	//
+	// example: "2024-06-01T09:00:00Z"
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PriceList holds fixed prices for products sold in one currency, and
+// optionally in one store only. Products it does not list are converted from
+// their list price with the exchange rate.
+// swagger:model
+type PriceList struct {
+	// The ID of the price list.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// A short description of the price list.
+	//
+	// required: true
+	// example: "Euro stores"
+	Name string `json:"name"`
+
+	// The currency of the prices.
+	//
+	// required: true
+	// example: "EUR"
+	Currency Currency `json:"currency"`
+
+	// The store the list is for. Empty means every store.
+	//
+	// required: false
+	// example: "madrid-01"
+	Store string `json:"store,omitempty"`
+
+	// The prices of the listed products.
+	//
+	// required: true
+	Prices []PriceListEntry `json:"prices"`
+}
+
+// PriceListEntry is the price of one product in a price list, in the list's
+// currency.
+type PriceListEntry struct {
+	ProductID int   `json:"product_id"`
+	Price     Money `json:"price" swaggertype:"number"`
+}
@@ -0,0 +1,61 @@
+package domain
+
+// PricingRule marks down products close to their expiration date.
+// swagger:model
+type PricingRule struct {
+	// The ID of the rule.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// A short description of the rule.
+	//
+	// required: true
+	// example: "Last day"
+	Name string `json:"name"`
+
+	// The rule applies from this many days before expiration up to the
+	// expiration day itself. 0 means only on the last day.
+	//
+	// required: true
+	// example: 3
+	WithinDays int `json:"within_days"`
+
+	// The discount applied to the list price, in percent.
+	//
+	// required: true
+	// example: 30
+	DiscountPercent float64 `json:"discount_percent"`
+
+	// Whether the rule is applied. A rule saved without it is active.
+	//
+	// required: false
+	// default: true
+	// example: true
+	Active *bool `json:"active" default:"true"`
+}
+
+// IsActive reports whether the rule is applied. A rule that leaves Active
+// out, as one sent only to be previewed may, is.
+func (r PricingRule) IsActive() bool {
+	return r.Active == nil || *r.Active
+}
+
+// PricedProduct is a product together with the price it is sold at today.
+// The list price in Product.Price is never modified by pricing rules.
+// NetPrice, TaxAmount and GrossPrice split the effective price by the
+// product's tax class. All prices are in Currency; when it is not the default
+// one they come from the price list PriceListID or were converted at
+// ExchangeRate.
+type PricedProduct struct {
+	Product
+	EffectivePrice Money        `json:"effective_price" swaggertype:"number"`
+	AppliedRule    *PricingRule `json:"applied_rule,omitempty"`
+	TaxRate        float64      `json:"tax_rate"`
+	NetPrice       Money        `json:"net_price" swaggertype:"number"`
+	TaxAmount      Money        `json:"tax_amount" swaggertype:"number"`
+	GrossPrice     Money        `json:"gross_price" swaggertype:"number"`
+	Currency       Currency     `json:"currency"`
+	PriceListID    int          `json:"price_list_id,omitempty"`
+	ExchangeRate   float64      `json:"exchange_rate,omitempty"`
+}
@@ -21,6 +21,14 @@ type Product struct {
 	// example: 10
 	Quantity int `json:"quantity"`
 
+	// The quantity in the product's unit, such as 2.5 for 2.5 kg. Products
+	// sold by weight or volume take their quantity as a measure. It is only
+	// read on requests.
+	//
+	// required: false
+	// example: 2.5
+	Measure *Measure `json:"measure,omitempty" swaggertype:"number"`
+
 	// The code value of the product.
 	//
 	// required: true
@@ -33,15 +41,69 @@ type Product struct {
 	// example: true
 	IsPublished bool `json:"is_published"`
 
-	// The expiration date of the product.
+	// The expiration date of the product. Accepts YYYY-MM-DD or the legacy
+	// DD/MM/YYYY and is always returned as YYYY-MM-DD.
 	//
 	// required: true
 	// example: "2023-12-31"
-	Expiration string `json:"expiration"`
+	Expiration Date `json:"expiration" swaggertype:"string"`
 
 	// The price of the product.
 	//
 	// required: true
 	// example: 2.99
-	Price float64 `json:"price"`
+	Price Money `json:"price" swaggertype:"number"`
+
+	// The categories the product belongs to.
+	//
+	// required: false
+	// example: [2, 7]
+	CategoryIDs []int `json:"category_ids,omitempty"`
+
+	// The stock that should always be on hand. Falling below it makes a
+	// replenishment suggestion critical.
+	//
+	// required: false
+	// example: 5
+	MinStock int `json:"min_stock,omitempty"`
+
+	// Replenishment is suggested once the stock, counting units already on
+	// order, is at or below this level. Defaults to MinStock.
+	//
+	// required: false
+	// example: 12
+	ReorderPoint int `json:"reorder_point,omitempty"`
+
+	// How many units to order at a time. Defaults to ReorderPoint.
+	//
+	// required: false
+	// example: 48
+	ReorderQuantity int `json:"reorder_quantity,omitempty"`
+
+	// standard, reduced or exempt. Empty means standard.
+	//
+	// required: false
+	// example: "reduced"
+	TaxClass TaxClass `json:"tax_class,omitempty"`
+
+	// The GS1 barcodes of the product, for example one on each unit and one
+	// on the case.
+	//
+	// required: false
+	Barcodes []Barcode `json:"barcodes,omitempty"`
+
+	// each, kg, g or l. Empty means each. Products sold by the kilogram or
+	// litre keep their quantity and stock in grams or millilitres, and their
+	// price is per kilogram or litre.
+	//
+	// required: false
+	// example: "kg"
+	Unit UnitOfMeasure `json:"unit,omitempty"`
+
+	// The 4 or 5 digit price look-up code keyed in or printed on scale
+	// labels for loose items.
+	//
+	// required: false
+	// example: "4011"
+	PLU string `json:"plu,omitempty"`
 }
@@ -0,0 +1,133 @@
+package domain
+
+// PromotionType selects how a promotion reprices the units it covers.
+type PromotionType string
+
+const (
+	// PromotionPercentOff takes Percent off the list price of every unit.
+	PromotionPercentOff PromotionType = "percent_off"
+	// PromotionFixedOff takes Amount off the list price of every unit.
+	PromotionFixedOff PromotionType = "fixed_off"
+	// PromotionBuyXGetY gives FreeQuantity units for every BuyQuantity units
+	// of the same product bought.
+	PromotionBuyXGetY PromotionType = "buy_x_get_y"
+	// PromotionMultiBuy sells BundleQuantity units of the same product for
+	// BundlePrice.
+	PromotionMultiBuy PromotionType = "multi_buy"
+	// PromotionMixAndMatch sells any BundleQuantity eligible units, across
+	// products and categories, for BundlePrice.
+	PromotionMixAndMatch PromotionType = "mix_and_match"
+)
+
+// Promotion is a time-bounded discount evaluated when a cart is priced. A
+// product is eligible when it is listed in ProductIDs or belongs to one of
+// CategoryIDs or their subcategories.
+// swagger:model
+type Promotion struct {
+	// The ID of the promotion.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// A short description printed on receipts.
+	//
+	// required: true
+	// example: "Soda 3 for 5"
+	Name string `json:"name"`
+
+	// percent_off, fixed_off, buy_x_get_y, multi_buy or mix_and_match.
+	//
+	// required: true
+	// example: "multi_buy"
+	Type PromotionType `json:"type"`
+
+	// The eligible products.
+	//
+	// required: false
+	// example: [1, 2]
+	ProductIDs []int `json:"product_ids,omitempty"`
+
+	// The eligible categories.
+	//
+	// required: false
+	// example: [3]
+	CategoryIDs []int `json:"category_ids,omitempty"`
+
+	// The first day of the promotion. Empty means it already started.
+	//
+	// required: false
+	// example: "2024-06-01"
+	StartsOn Date `json:"starts_on"`
+
+	// The last day of the promotion. Empty means it never ends.
+	//
+	// required: false
+	// example: "2024-06-07"
+	EndsOn Date `json:"ends_on"`
+
+	// Whether the promotion is applied. A promotion saved without it is
+	// active.
+	//
+	// required: false
+	// default: true
+	// example: true
+	Active *bool `json:"active" default:"true"`
+
+	// The discount of percent_off promotions, in percent.
+	//
+	// required: false
+	// example: 20
+	Percent float64 `json:"percent,omitempty"`
+
+	// The discount per unit of fixed_off promotions.
+	//
+	// required: false
+	// example: 0.5
+	Amount Money `json:"amount" swaggertype:"number"`
+
+	// The units to buy in buy_x_get_y promotions.
+	//
+	// required: false
+	// example: 1
+	BuyQuantity int `json:"buy_quantity,omitempty"`
+
+	// The units given for free in buy_x_get_y promotions.
+	//
+	// required: false
+	// example: 1
+	FreeQuantity int `json:"free_quantity,omitempty"`
+
+	// The units in a bundle of multi_buy and mix_and_match promotions.
+	//
+	// required: false
+	// example: 3
+	BundleQuantity int `json:"bundle_quantity,omitempty"`
+
+	// The price of a bundle of multi_buy and mix_and_match promotions.
+	//
+	// required: false
+	// example: 5
+	BundlePrice Money `json:"bundle_price" swaggertype:"number"`
+}
+
+// ActiveOn reports whether the promotion applies on day.
+func (p Promotion) ActiveOn(day Date) bool {
+	if p.Active != nil && !*p.Active {
+		return false
+	}
+	if !p.StartsOn.IsZero() && day.Before(p.StartsOn) {
+		return false
+	}
+	return p.EndsOn.IsZero() || !day.After(p.EndsOn)
+}
+
+// AppliedPromotion explains what a promotion took off a sale line.
+type AppliedPromotion struct {
+	PromotionID int           `json:"promotion_id"`
+	Name        string        `json:"name"`
+	Type        PromotionType `json:"type"`
+	// Units counts the units of the line the promotion repriced.
+	Units       int    `json:"units"`
+	Savings     Money  `json:"savings" swaggertype:"number"`
+	Explanation string `json:"explanation"`
+}
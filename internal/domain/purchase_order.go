@@ -0,0 +1,129 @@
+package domain
+
+import "time"
+
+// PurchaseOrderStatus is the stage of a purchase order. Orders move from
+// draft to submitted, then through partially received to received as goods
+// arrive, and are finally closed.
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderDraft             PurchaseOrderStatus = "draft"
+	PurchaseOrderSubmitted         PurchaseOrderStatus = "submitted"
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "partially_received"
+	PurchaseOrderReceived          PurchaseOrderStatus = "received"
+	PurchaseOrderClosed            PurchaseOrderStatus = "closed"
+)
+
+// PurchaseOrder is an order of products placed with a single supplier.
+// swagger:model
+type PurchaseOrder struct {
+	// The ID of the order.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// The supplier the order is placed with.
+	//
+	// required: true
+	// example: 1
+	SupplierID int `json:"supplier_id"`
+
+	// The stage of the order, set by the system.
+	//
+	// example: "draft"
+	Status PurchaseOrderStatus `json:"status"`
+
+	// The products ordered.
+	//
+	// required: true
+	Lines []PurchaseOrderLine `json:"lines"`
+
+	// Every delivery received against the order, oldest first.
+	Receipts []PurchaseOrderReceipt `json:"receipts"`
+
+	// When the order was created, in UTC.
+	CreatedAt time.Time `json:"created_at"`
+
+	// When the order was last changed, in UTC.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PurchaseOrderLine is one product of a purchase order.
+type PurchaseOrderLine struct {
+	// The product ordered.
+	//
+	// required: true
+	// example: 12
+	ProductID int `json:"product_id"`
+
+	// How many units were ordered.
+	//
+	// required: true
+	// example: 48
+	Quantity int `json:"quantity"`
+
+	// How much was ordered in the product's unit, for products sold by
+	// weight or volume. It is only read on requests.
+	//
+	// required: false
+	// example: 25
+	Measure *Measure `json:"measure,omitempty" swaggertype:"number"`
+
+	// How many units arrived so far, set by the system.
+	//
+	// example: 24
+	QuantityReceived int `json:"quantity_received"`
+
+	// The agreed cost per unit. Defaults to the supplier's cost price.
+	//
+	// required: false
+	// example: 41.5
+	UnitCost Money `json:"unit_cost" swaggertype:"number"`
+}
+
+// PurchaseOrderReceipt is one delivery received against a purchase order.
+type PurchaseOrderReceipt struct {
+	// When the delivery was received, in UTC.
+	ReceivedAt time.Time `json:"received_at"`
+
+	// The products delivered.
+	//
+	// required: true
+	Lines []ReceiptLine `json:"lines"`
+}
+
+// ReceiptLine is a quantity of one product delivered with a purchase order.
+type ReceiptLine struct {
+	// The product delivered.
+	//
+	// required: true
+	// example: 12
+	ProductID int `json:"product_id"`
+
+	// How many units arrived.
+	//
+	// required: true
+	// example: 24
+	Quantity int `json:"quantity"`
+
+	// How much arrived in the product's unit, for products sold by weight
+	// or volume. It is only read on requests.
+	//
+	// required: false
+	// example: 24.5
+	Measure *Measure `json:"measure,omitempty" swaggertype:"number"`
+
+	// The lot the units belong to. Defaults to the order number followed
+	// by the expiration date.
+	//
+	// required: false
+	// example: "L2304-118"
+	LotNumber string `json:"lot_number"`
+
+	// The expiration date printed on the delivered units.
+	//
+	// required: true
+	// example: "2024-03-15"
+	Expiration Date `json:"expiration" swaggertype:"string"`
+}
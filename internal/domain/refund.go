@@ -0,0 +1,88 @@
+package domain
+
+import "time"
+
+// ReturnDisposition says what happens to returned goods.
+type ReturnDisposition string
+
+const (
+	// ReturnRestock puts the returned units back on the shelf.
+	ReturnRestock ReturnDisposition = "restock"
+	// ReturnDamaged takes the returned units back and writes them off as
+	// shrinkage.
+	ReturnDamaged ReturnDisposition = "damaged"
+)
+
+// Refund records goods a customer brought back from a sale and the money
+// paid back. Refunds are never changed once recorded.
+// swagger:model
+type Refund struct {
+	// The ID of the refund.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// The sale the goods were bought in.
+	//
+	// example: 7
+	SaleID int `json:"sale_id"`
+
+	// Why the customer returned the goods.
+	//
+	// required: false
+	// example: "Wrong size"
+	Reason string `json:"reason"`
+
+	// The returned receipt lines.
+	//
+	// required: true
+	Lines []RefundLine `json:"lines"`
+
+	// The money paid back.
+	//
+	// example: 71.42
+	Total Money `json:"total" swaggertype:"number"`
+
+	// When the refund was made, in UTC.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RefundLine returns units of one receipt line.
+type RefundLine struct {
+	// The position of the line on the receipt, starting at 1.
+	//
+	// required: true
+	// example: 1
+	Line int `json:"line"`
+
+	// The returned product, set by the system.
+	//
+	// example: 12
+	ProductID int `json:"product_id"`
+
+	// How many units are returned. For weighed lines it is in grams or
+	// millilitres and set by the system from the measure.
+	//
+	// required: false
+	// example: 1
+	Quantity int `json:"quantity"`
+
+	// How much of a weighed line is returned, in the unit it was sold in,
+	// such as 0.5 for half a kilogram. Weighed lines take it instead of a
+	// quantity.
+	//
+	// required: false
+	// example: 0.5
+	Measure *Measure `json:"measure,omitempty" swaggertype:"number"`
+
+	// restock (the default) or damaged.
+	//
+	// required: false
+	// example: "restock"
+	Disposition ReturnDisposition `json:"disposition"`
+
+	// The money paid back for the line, set by the system.
+	//
+	// example: 71.42
+	Amount Money `json:"amount" swaggertype:"number"`
+}
@@ -0,0 +1,86 @@
+package domain
+
+import "time"
+
+// Sale is the receipt of a checkout. Sales are never changed once recorded.
+// swagger:model
+type Sale struct {
+	// The ID of the sale, printed on the receipt.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// The cart that was checked out.
+	//
+	// example: 3
+	CartID int `json:"cart_id"`
+
+	// The products sold.
+	Lines []SaleLine `json:"lines"`
+
+	// The sum of the lines at list price.
+	//
+	// example: 150.5
+	Subtotal Money `json:"subtotal" swaggertype:"number"`
+
+	// Whether the subtotal, discount and line totals include tax.
+	//
+	// example: true
+	PricesIncludeTax bool `json:"prices_include_tax"`
+
+	// How much markdowns and promotions took off the subtotal.
+	//
+	// example: 12.3
+	Discount Money `json:"discount" swaggertype:"number"`
+
+	// The tax in the total.
+	//
+	// example: 23.98
+	Tax Money `json:"tax" swaggertype:"number"`
+
+	// The tax per tax class.
+	Taxes []TaxBreakdown `json:"taxes"`
+
+	// What the customer paid, tax included.
+	//
+	// example: 138.2
+	Total Money `json:"total" swaggertype:"number"`
+
+	// When the sale happened, in UTC.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SaleLine is one product of a sale with the prices it was sold at.
+type SaleLine struct {
+	ProductID int    `json:"product_id"`
+	CodeValue string `json:"code_value"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	// Unit and Measure are set for weighed items, whose Quantity is in
+	// grams or millilitres and whose prices are per Unit. LabelPrice is the
+	// price of a scale label, which is charged as it is.
+	Unit       UnitOfMeasure `json:"unit,omitempty"`
+	Measure    *Measure      `json:"measure,omitempty" swaggertype:"number"`
+	LabelPrice *Money        `json:"label_price,omitempty" swaggertype:"number"`
+	// ListPrice is the product's price at the time of sale, UnitPrice what
+	// a unit cost after markdowns. Total is what was charged for the line,
+	// after the promotion if one applied, in the sale's price mode; Gross
+	// is the same with tax included.
+	ListPrice Money             `json:"list_price" swaggertype:"number"`
+	UnitPrice Money             `json:"unit_price" swaggertype:"number"`
+	Total     Money             `json:"total" swaggertype:"number"`
+	Promotion *AppliedPromotion `json:"promotion,omitempty"`
+	TaxClass  TaxClass          `json:"tax_class"`
+	TaxRate   float64           `json:"tax_rate"`
+	Tax       Money             `json:"tax" swaggertype:"number"`
+	Gross     Money             `json:"gross" swaggertype:"number"`
+}
+
+// Paid returns what the customer paid for the line, tax included. Sales
+// recorded before taxes were tracked have no gross, and paid the total.
+func (l SaleLine) Paid() Money {
+	if l.Gross.IsZero() {
+		return l.Total
+	}
+	return l.Gross
+}
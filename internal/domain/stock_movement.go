@@ -0,0 +1,90 @@
+package domain
+
+import "time"
+
+// MovementType classifies why stock changed.
+type MovementType string
+
+const (
+	MovementReceipt    MovementType = "receipt"
+	MovementSale       MovementType = "sale"
+	MovementAdjustment MovementType = "adjustment"
+	MovementShrinkage  MovementType = "shrinkage"
+	MovementReturn     MovementType = "return"
+	MovementTransfer   MovementType = "transfer"
+)
+
+// StockMovement is one entry of a product's stock ledger. Entries are never
+// changed or removed; a product's quantity is the sum of its movements.
+// swagger:model
+type StockMovement struct {
+	// The ID of the movement.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// The product whose stock changed.
+	//
+	// example: 1
+	ProductID int `json:"product_id"`
+
+	// One of receipt, sale, adjustment, shrinkage, return or transfer.
+	//
+	// required: true
+	// example: "receipt"
+	Type MovementType `json:"type"`
+
+	// The signed change in stock. Receipts and returns are positive, sales
+	// and shrinkage negative; adjustments and transfers may be either.
+	//
+	// required: true
+	// example: 24
+	Quantity int `json:"quantity"`
+
+	// The change in the product's unit, such as 0.5 for half a kilogram.
+	// Products sold by weight or volume take it instead of a quantity. It is
+	// only read on requests.
+	//
+	// required: false
+	// example: 0.5
+	Measure *Measure `json:"measure,omitempty" swaggertype:"number"`
+
+	// A reason code refining the type, e.g. "damaged" for shrinkage.
+	//
+	// required: false
+	// example: "supplier_delivery"
+	Reason string `json:"reason"`
+
+	// Free text linking the movement to a document such as an invoice.
+	//
+	// required: false
+	// example: "INV-2023-0042"
+	Reference string `json:"reference,omitempty"`
+
+	// The lot the movement is for. Incoming stock with a new lot number
+	// starts a lot; outgoing stock without one is taken from the lots that
+	// expire first.
+	//
+	// required: false
+	// example: "L2304-118"
+	LotNumber string `json:"lot_number,omitempty"`
+
+	// The expiration date of a new lot.
+	//
+	// required: false
+	// example: "2024-03-15"
+	Expiration *Date `json:"expiration,omitempty" swaggertype:"string"`
+
+	// How the movement was spread over the product's lots, set by the system.
+	Allocations []LotAllocation `json:"allocations,omitempty"`
+
+	// The stock level right after this movement.
+	//
+	// example: 463
+	Balance int `json:"balance"`
+
+	// When the movement was recorded.
+	//
+	// example: "2023-04-10T00:26:31Z"
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,67 @@
+package domain
+
+// Supplier is a company the supermarket buys products from.
+// swagger:model
+type Supplier struct {
+	// The ID of the supplier.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// The name of the supplier.
+	//
+	// required: true
+	// example: "Fresh Farms Ltd."
+	Name string `json:"name"`
+
+	// Where purchase orders are sent.
+	//
+	// required: false
+	// example: "orders@freshfarms.example"
+	Email string `json:"email"`
+
+	// A phone number for the supplier.
+	//
+	// required: false
+	// example: "+54 11 5555-0100"
+	Phone string `json:"phone"`
+}
+
+// SupplierProduct links a product to a supplier that sells it, with the terms
+// of that supplier. A product can be bought from several suppliers.
+// swagger:model
+type SupplierProduct struct {
+	// The ID of the link.
+	//
+	// example: 1
+	ID int `json:"id"`
+
+	// The supplier selling the product.
+	//
+	// example: 1
+	SupplierID int `json:"supplier_id"`
+
+	// The product being sold.
+	//
+	// required: true
+	// example: 12
+	ProductID int `json:"product_id"`
+
+	// The code the supplier uses for the product.
+	//
+	// required: false
+	// example: "FF-00412"
+	SupplierSKU string `json:"supplier_sku"`
+
+	// What the supplier charges per unit.
+	//
+	// required: true
+	// example: 41.5
+	CostPrice Money `json:"cost_price" swaggertype:"number"`
+
+	// Days between ordering and delivery.
+	//
+	// required: true
+	// example: 3
+	LeadTimeDays int `json:"lead_time_days"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+// TaxClass groups products taxed at the same rate.
+type TaxClass string
+
+const (
+	TaxStandard TaxClass = "standard"
+	TaxReduced  TaxClass = "reduced"
+	TaxExempt   TaxClass = "exempt"
+)
+
+// TaxBreakdown sums the lines of a sale taxed under one class.
+type TaxBreakdown struct {
+	Class TaxClass `json:"class"`
+	// Rate is the tax rate of the class, in percent.
+	Rate  float64 `json:"rate"`
+	Net   Money   `json:"net" swaggertype:"number"`
+	Tax   Money   `json:"tax" swaggertype:"number"`
+	Gross Money   `json:"gross" swaggertype:"number"`
+}
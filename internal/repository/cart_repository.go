@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrCartNotFound = errors.New("Cart not found")
+
+var ErrSaleNotFound = errors.New("Sale not found")
+
+type cartRepository struct {
+	storage *store.Collection[domain.Cart]
+}
+
+func NewCartRepository(storage *store.Collection[domain.Cart]) CartRepository {
+	return &cartRepository{storage: storage}
+}
+
+func (r *cartRepository) GetCartByID(id int) (domain.Cart, error) {
+	carts, err := r.storage.All()
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	for _, cart := range carts {
+		if cart.ID == id {
+			return cart, nil
+		}
+	}
+	return domain.Cart{}, ErrCartNotFound
+}
+
+func (r *cartRepository) CreateCart(cart *domain.Cart) (*domain.Cart, error) {
+	err := r.storage.Mutate(func(carts []domain.Cart, nextID func() int) ([]domain.Cart, error) {
+		cart.ID = nextID()
+		return append(carts, *cart), nil
+	})
+	if err != nil {
+		return &domain.Cart{}, err
+	}
+	return cart, nil
+}
+
+func (r *cartRepository) UpdateCart(id int, cart *domain.Cart) error {
+	return r.storage.Mutate(func(carts []domain.Cart, _ func() int) ([]domain.Cart, error) {
+		for i, current := range carts {
+			if current.ID == id {
+				cart.ID = id
+				carts[i] = *cart
+				return carts, nil
+			}
+		}
+		return nil, ErrCartNotFound
+	})
+}
+
+type saleRepository struct {
+	storage *store.Collection[domain.Sale]
+}
+
+func NewSaleRepository(storage *store.Collection[domain.Sale]) SaleRepository {
+	return &saleRepository{storage: storage}
+}
+
+func (r *saleRepository) GetAllSales() ([]domain.Sale, error) {
+	return r.storage.All()
+}
+
+func (r *saleRepository) GetSaleByID(id int) (domain.Sale, error) {
+	sales, err := r.storage.All()
+	if err != nil {
+		return domain.Sale{}, err
+	}
+	for _, sale := range sales {
+		if sale.ID == id {
+			return sale, nil
+		}
+	}
+	return domain.Sale{}, ErrSaleNotFound
+}
+
+func (r *saleRepository) CreateSale(sale *domain.Sale) (*domain.Sale, error) {
+	err := r.storage.Mutate(func(sales []domain.Sale, nextID func() int) ([]domain.Sale, error) {
+		sale.ID = nextID()
+		return append(sales, *sale), nil
+	})
+	if err != nil {
+		return &domain.Sale{}, err
+	}
+	return sale, nil
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type CartRepository interface {
+	GetCartByID(id int) (domain.Cart, error)
+	CreateCart(cart *domain.Cart) (*domain.Cart, error)
+	UpdateCart(id int, cart *domain.Cart) error
+}
+
+// SaleRepository has no way to change or delete a sale once created.
+type SaleRepository interface {
+	GetAllSales() ([]domain.Sale, error)
+	GetSaleByID(id int) (domain.Sale, error)
+	CreateSale(sale *domain.Sale) (*domain.Sale, error)
+}
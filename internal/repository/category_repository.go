@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrCategoryNotFound = errors.New("Category not found")
+
+type categoryRepository struct {
+	storage *store.Collection[domain.Category]
+}
+
+func NewCategoryRepository(storage *store.Collection[domain.Category]) CategoryRepository {
+	return &categoryRepository{storage: storage}
+}
+
+func (r *categoryRepository) GetAllCategories() ([]domain.Category, error) {
+	return r.storage.All()
+}
+
+func (r *categoryRepository) GetCategoryByID(id int) (domain.Category, error) {
+	categories, err := r.storage.All()
+	if err != nil {
+		return domain.Category{}, err
+	}
+	for _, category := range categories {
+		if category.ID == id {
+			return category, nil
+		}
+	}
+	return domain.Category{}, ErrCategoryNotFound
+}
+
+func (r *categoryRepository) CreateCategory(category *domain.Category) (*domain.Category, error) {
+	err := r.storage.Mutate(func(categories []domain.Category, nextID func() int) ([]domain.Category, error) {
+		category.ID = nextID()
+		return append(categories, *category), nil
+	})
+	if err != nil {
+		return &domain.Category{}, err
+	}
+	return category, nil
+}
+
+func (r *categoryRepository) UpdateCategory(id int, category *domain.Category) error {
+	return r.storage.Mutate(func(categories []domain.Category, _ func() int) ([]domain.Category, error) {
+		for i, current := range categories {
+			if current.ID == id {
+				category.ID = id
+				categories[i] = *category
+				return categories, nil
+			}
+		}
+		return nil, ErrCategoryNotFound
+	})
+}
+
+func (r *categoryRepository) DeleteCategory(id int) error {
+	return r.storage.Mutate(func(categories []domain.Category, _ func() int) ([]domain.Category, error) {
+		for i, current := range categories {
+			if current.ID == id {
+				return append(categories[:i], categories[i+1:]...), nil
+			}
+		}
+		return nil, ErrCategoryNotFound
+	})
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type CategoryRepository interface {
+	GetAllCategories() ([]domain.Category, error)
+	GetCategoryByID(id int) (domain.Category, error)
+	CreateCategory(category *domain.Category) (*domain.Category, error)
+	UpdateCategory(id int, category *domain.Category) error
+	DeleteCategory(id int) error
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrExchangeRateNotFound = errors.New("Exchange rate not found")
+
+// exchangeRateRepository keeps one rate per currency. The file can be edited
+// by hand or maintained through the API.
+type exchangeRateRepository struct {
+	storage *store.Collection[domain.ExchangeRate]
+}
+
+func NewExchangeRateRepository(storage *store.Collection[domain.ExchangeRate]) ExchangeRateRepository {
+	return &exchangeRateRepository{storage: storage}
+}
+
+func (r *exchangeRateRepository) GetAllRates() ([]domain.ExchangeRate, error) {
+	return r.storage.All()
+}
+
+func (r *exchangeRateRepository) GetRate(currency domain.Currency) (domain.ExchangeRate, error) {
+	rates, err := r.storage.All()
+	if err != nil {
+		return domain.ExchangeRate{}, err
+	}
+	for _, rate := range rates {
+		if rate.Currency == currency {
+			return rate, nil
+		}
+	}
+	return domain.ExchangeRate{}, ErrExchangeRateNotFound
+}
+
+// SaveRate replaces the rate of the currency, or adds it.
+func (r *exchangeRateRepository) SaveRate(rate *domain.ExchangeRate) error {
+	return r.storage.Mutate(func(rates []domain.ExchangeRate, _ func() int) ([]domain.ExchangeRate, error) {
+		for i, current := range rates {
+			if current.Currency == rate.Currency {
+				rates[i] = *rate
+				return rates, nil
+			}
+		}
+		return append(rates, *rate), nil
+	})
+}
+
+func (r *exchangeRateRepository) DeleteRate(currency domain.Currency) error {
+	return r.storage.Mutate(func(rates []domain.ExchangeRate, _ func() int) ([]domain.ExchangeRate, error) {
+		for i, current := range rates {
+			if current.Currency == currency {
+				return append(rates[:i], rates[i+1:]...), nil
+			}
+		}
+		return nil, ErrExchangeRateNotFound
+	})
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type ExchangeRateRepository interface {
+	GetAllRates() ([]domain.ExchangeRate, error)
+	GetRate(currency domain.Currency) (domain.ExchangeRate, error)
+	SaveRate(rate *domain.ExchangeRate) error
+	DeleteRate(currency domain.Currency) error
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrLotNotFound = errors.New("Lot not found")
+
+type lotRepository struct {
+	storage *store.Collection[domain.Lot]
+}
+
+func NewLotRepository(storage *store.Collection[domain.Lot]) LotRepository {
+	return &lotRepository{storage: storage}
+}
+
+func (r *lotRepository) GetAllLots() ([]domain.Lot, error) {
+	return r.storage.All()
+}
+
+func (r *lotRepository) GetLotsByProduct(productID int) ([]domain.Lot, error) {
+	lots, err := r.storage.All()
+	if err != nil {
+		return nil, err
+	}
+	productLots := []domain.Lot{}
+	for _, lot := range lots {
+		if lot.ProductID == productID {
+			productLots = append(productLots, lot)
+		}
+	}
+	return productLots, nil
+}
+
+// SaveLots creates the lots without an ID and replaces the others, all in a
+// single write.
+func (r *lotRepository) SaveLots(lots ...*domain.Lot) error {
+	return r.storage.Mutate(func(all []domain.Lot, nextID func() int) ([]domain.Lot, error) {
+		positions := make(map[int]int, len(all))
+		for i, lot := range all {
+			positions[lot.ID] = i
+		}
+		for _, lot := range lots {
+			if lot.ID == 0 {
+				lot.ID = nextID()
+				all = append(all, *lot)
+				continue
+			}
+			i, ok := positions[lot.ID]
+			if !ok {
+				return nil, ErrLotNotFound
+			}
+			all[i] = *lot
+		}
+		return all, nil
+	})
+}
+
+// DeleteLots removes the lots with the given IDs in a single write.
+func (r *lotRepository) DeleteLots(ids ...int) error {
+	return r.storage.Mutate(func(all []domain.Lot, nextID func() int) ([]domain.Lot, error) {
+		deleted := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			deleted[id] = true
+		}
+		kept := all[:0]
+		for _, lot := range all {
+			if !deleted[lot.ID] {
+				kept = append(kept, lot)
+			}
+		}
+		return kept, nil
+	})
+}
@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type LotRepository interface {
+	GetAllLots() ([]domain.Lot, error)
+	GetLotsByProduct(productID int) ([]domain.Lot, error)
+	SaveLots(lots ...*domain.Lot) error
+	DeleteLots(ids ...int) error
+}
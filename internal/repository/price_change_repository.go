@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrPriceChangeNotFound = errors.New("Price change not found")
+
+type priceChangeRepository struct {
+	storage *store.Collection[domain.PriceChange]
+}
+
+func NewPriceChangeRepository(storage *store.Collection[domain.PriceChange]) PriceChangeRepository {
+	return &priceChangeRepository{storage: storage}
+}
+
+func (r *priceChangeRepository) GetAllPriceChanges() ([]domain.PriceChange, error) {
+	return r.storage.All()
+}
+
+func (r *priceChangeRepository) GetPriceChangeByID(id int) (domain.PriceChange, error) {
+	changes, err := r.storage.All()
+	if err != nil {
+		return domain.PriceChange{}, err
+	}
+	for _, change := range changes {
+		if change.ID == id {
+			return change, nil
+		}
+	}
+	return domain.PriceChange{}, ErrPriceChangeNotFound
+}
+
+func (r *priceChangeRepository) GetPriceChangesByProduct(productID int) ([]domain.PriceChange, error) {
+	changes, err := r.storage.All()
+	if err != nil {
+		return nil, err
+	}
+	result := []domain.PriceChange{}
+	for _, change := range changes {
+		if change.ProductID == productID {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+func (r *priceChangeRepository) CreatePriceChange(change *domain.PriceChange) (*domain.PriceChange, error) {
+	err := r.storage.Mutate(func(changes []domain.PriceChange, nextID func() int) ([]domain.PriceChange, error) {
+		change.ID = nextID()
+		return append(changes, *change), nil
+	})
+	if err != nil {
+		return &domain.PriceChange{}, err
+	}
+	return change, nil
+}
+
+func (r *priceChangeRepository) UpdatePriceChange(change *domain.PriceChange) error {
+	return r.storage.Mutate(func(changes []domain.PriceChange, _ func() int) ([]domain.PriceChange, error) {
+		for i, current := range changes {
+			if current.ID == change.ID {
+				changes[i] = *change
+				return changes, nil
+			}
+		}
+		return nil, ErrPriceChangeNotFound
+	})
+}
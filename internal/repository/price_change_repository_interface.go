@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type PriceChangeRepository interface {
+	GetAllPriceChanges() ([]domain.PriceChange, error)
+	GetPriceChangeByID(id int) (domain.PriceChange, error)
+	GetPriceChangesByProduct(productID int) ([]domain.PriceChange, error)
+	CreatePriceChange(change *domain.PriceChange) (*domain.PriceChange, error)
+	UpdatePriceChange(change *domain.PriceChange) error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrPriceListNotFound = errors.New("Price list not found")
+
+type priceListRepository struct {
+	storage *store.Collection[domain.PriceList]
+}
+
+func NewPriceListRepository(storage *store.Collection[domain.PriceList]) PriceListRepository {
+	return &priceListRepository{storage: storage}
+}
+
+func (r *priceListRepository) GetAllPriceLists() ([]domain.PriceList, error) {
+	return r.storage.All()
+}
+
+func (r *priceListRepository) GetPriceListByID(id int) (domain.PriceList, error) {
+	priceLists, err := r.storage.All()
+	if err != nil {
+		return domain.PriceList{}, err
+	}
+	for _, priceList := range priceLists {
+		if priceList.ID == id {
+			return priceList, nil
+		}
+	}
+	return domain.PriceList{}, ErrPriceListNotFound
+}
+
+func (r *priceListRepository) CreatePriceList(priceList *domain.PriceList) (*domain.PriceList, error) {
+	err := r.storage.Mutate(func(priceLists []domain.PriceList, nextID func() int) ([]domain.PriceList, error) {
+		priceList.ID = nextID()
+		return append(priceLists, *priceList), nil
+	})
+	if err != nil {
+		return &domain.PriceList{}, err
+	}
+	return priceList, nil
+}
+
+func (r *priceListRepository) UpdatePriceList(id int, priceList *domain.PriceList) error {
+	return r.storage.Mutate(func(priceLists []domain.PriceList, _ func() int) ([]domain.PriceList, error) {
+		for i, current := range priceLists {
+			if current.ID == id {
+				priceList.ID = id
+				priceLists[i] = *priceList
+				return priceLists, nil
+			}
+		}
+		return nil, ErrPriceListNotFound
+	})
+}
+
+func (r *priceListRepository) DeletePriceList(id int) error {
+	return r.storage.Mutate(func(priceLists []domain.PriceList, _ func() int) ([]domain.PriceList, error) {
+		for i, current := range priceLists {
+			if current.ID == id {
+				return append(priceLists[:i], priceLists[i+1:]...), nil
+			}
+		}
+		return nil, ErrPriceListNotFound
+	})
+}
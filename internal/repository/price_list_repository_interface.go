@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type PriceListRepository interface {
+	GetAllPriceLists() ([]domain.PriceList, error)
+	GetPriceListByID(id int) (domain.PriceList, error)
+	CreatePriceList(priceList *domain.PriceList) (*domain.PriceList, error)
+	UpdatePriceList(id int, priceList *domain.PriceList) error
+	DeletePriceList(id int) error
+}
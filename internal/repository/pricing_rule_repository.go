@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrPricingRuleNotFound = errors.New("Pricing rule not found")
+
+type pricingRuleRepository struct {
+	storage *store.Collection[domain.PricingRule]
+}
+
+func NewPricingRuleRepository(storage *store.Collection[domain.PricingRule]) PricingRuleRepository {
+	return &pricingRuleRepository{storage: storage}
+}
+
+func (r *pricingRuleRepository) GetAllRules() ([]domain.PricingRule, error) {
+	return r.storage.All()
+}
+
+func (r *pricingRuleRepository) GetRuleByID(id int) (domain.PricingRule, error) {
+	rules, err := r.storage.All()
+	if err != nil {
+		return domain.PricingRule{}, err
+	}
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return domain.PricingRule{}, ErrPricingRuleNotFound
+}
+
+func (r *pricingRuleRepository) CreateRule(rule *domain.PricingRule) (*domain.PricingRule, error) {
+	err := r.storage.Mutate(func(rules []domain.PricingRule, nextID func() int) ([]domain.PricingRule, error) {
+		rule.ID = nextID()
+		return append(rules, *rule), nil
+	})
+	if err != nil {
+		return &domain.PricingRule{}, err
+	}
+	return rule, nil
+}
+
+func (r *pricingRuleRepository) UpdateRule(id int, rule *domain.PricingRule) error {
+	return r.storage.Mutate(func(rules []domain.PricingRule, _ func() int) ([]domain.PricingRule, error) {
+		for i, current := range rules {
+			if current.ID == id {
+				rule.ID = id
+				rules[i] = *rule
+				return rules, nil
+			}
+		}
+		return nil, ErrPricingRuleNotFound
+	})
+}
+
+func (r *pricingRuleRepository) DeleteRule(id int) error {
+	return r.storage.Mutate(func(rules []domain.PricingRule, _ func() int) ([]domain.PricingRule, error) {
+		for i, current := range rules {
+			if current.ID == id {
+				return append(rules[:i], rules[i+1:]...), nil
+			}
+		}
+		return nil, ErrPricingRuleNotFound
+	})
+}
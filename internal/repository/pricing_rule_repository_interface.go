@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type PricingRuleRepository interface {
+	GetAllRules() ([]domain.PricingRule, error)
+	GetRuleByID(id int) (domain.PricingRule, error)
+	CreateRule(rule *domain.PricingRule) (*domain.PricingRule, error)
+	UpdateRule(id int, rule *domain.PricingRule) error
+	DeleteRule(id int) error
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+)
+
+// Filter is a predicate over products. Filters are built and validated by the
+// service layer and evaluated here, next to the indexes.
+type Filter interface {
+	Match(product domain.Product) bool
+}
+
+// Comparison compares one product field with a value. Value must already have
+// the field's type: int64 for id and quantity, domain.Money for price, bool
+// for is_published, domain.Date for expiration and string for name and
+// code_value.
+type Comparison struct {
+	Field    string
+	Operator string
+	Value    interface{}
+}
+
+type AndFilter []Filter
+
+type OrFilter []Filter
+
+type NotFilter struct {
+	Filter Filter
+}
+
+func (f AndFilter) Match(product domain.Product) bool {
+	for _, filter := range f {
+		if !filter.Match(product) {
+			return false
+		}
+	}
+	return true
+}
+
+func (f OrFilter) Match(product domain.Product) bool {
+	for _, filter := range f {
+		if filter.Match(product) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f NotFilter) Match(product domain.Product) bool {
+	return !f.Filter.Match(product)
+}
+
+func (c Comparison) Match(product domain.Product) bool {
+	switch value := c.Value.(type) {
+	case int64:
+		var actual int64
+		switch c.Field {
+		case "id":
+			actual = int64(product.ID)
+		case "quantity":
+			actual = int64(product.Quantity)
+		default:
+			return false
+		}
+		return matchOrder(compareInt(actual, value), c.Operator)
+	case domain.Money:
+		if c.Field != "price" {
+			return false
+		}
+		return matchOrder(product.Price.Compare(value), c.Operator)
+	case bool:
+		if c.Field != "is_published" {
+			return false
+		}
+		return matchOrder(compareBool(product.IsPublished, value), c.Operator)
+	case domain.Date:
+		if c.Field != "expiration" || product.Expiration.IsZero() {
+			return false
+		}
+		return matchOrder(product.Expiration.Compare(value), c.Operator)
+	case string:
+		var actual string
+		switch c.Field {
+		case "name":
+			actual = product.Name
+		case "code_value":
+			actual = product.CodeValue
+		default:
+			return false
+		}
+		if c.Operator == "~" {
+			return strings.Contains(strings.ToLower(actual), strings.ToLower(value))
+		}
+		return matchOrder(strings.Compare(actual, value), c.Operator)
+	}
+	return false
+}
+
+func matchOrder(cmp int, operator string) bool {
+	switch operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// priceRange narrows the price index to the slice that can satisfy the price
+// conditions found at the top level of filter. Anything else is left to Match.
+func (r *productRepository) priceRange(filter Filter) (int, int) {
+	conditions := []Filter{filter}
+	if and, ok := filter.(AndFilter); ok {
+		conditions = and
+	}
+	lo, hi := 0, len(r.productsByPrice)
+	search := func(pred func(price domain.Money) bool) int {
+		return sort.Search(len(r.productsByPrice), func(i int) bool {
+			return pred(r.productsByPrice[i].Price)
+		})
+	}
+	for _, condition := range conditions {
+		c, ok := condition.(Comparison)
+		if !ok || c.Field != "price" {
+			continue
+		}
+		value, ok := c.Value.(domain.Money)
+		if !ok {
+			continue
+		}
+		atLeast := search(func(price domain.Money) bool { return price.Compare(value) >= 0 })
+		above := search(func(price domain.Money) bool { return price.Compare(value) > 0 })
+		switch c.Operator {
+		case ">":
+			lo = maxInt(lo, above)
+		case ">=":
+			lo = maxInt(lo, atLeast)
+		case "<":
+			hi = minInt(hi, atLeast)
+		case "<=":
+			hi = minInt(hi, above)
+		case "=":
+			lo, hi = maxInt(lo, atLeast), minInt(hi, above)
+		}
+	}
+	if lo > hi {
+		lo = hi
+	}
+	return lo, hi
+}
+
+// GetProductsByFilter returns the products matching filter ordered by ID.
+func (r *productRepository) GetProductsByFilter(filter Filter) ([]domain.Product, error) {
+	if err := r.refresh(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	lo, hi := r.priceRange(filter)
+	products := []domain.Product{}
+	for _, product := range r.productsByPrice[lo:hi] {
+		if filter.Match(product) {
+			products = append(products, product)
+		}
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID < products[j].ID
+	})
+	return products, nil
+}
@@ -2,129 +2,356 @@ package repository
 
 import (
 	"errors"
+	"sort"
+	"sync"
 
 	"github.com/NPG27/supermarket_dop/internal/domain"
 	"github.com/NPG27/supermarket_dop/pkg/store"
 )
 
+// productRepository serves reads from in-memory indexes built from the store.
+// Every write goes to the store first and then updates the indexes, and reads
+// reload everything when the store reports that its data changed underneath
+// us (for example products.json edited by hand).
 type productRepository struct {
 	storage       store.Store
+	mu            sync.RWMutex
+	version       string
+	productByID   map[int]domain.Product
 	productByCode map[string]domain.Product
+	// productByGTIN maps each 14 digit barcode to its product.
+	productByGTIN map[string]domain.Product
+	// productByPLU maps each price look-up code to its product.
+	productByPLU map[string]domain.Product
+	// productsByPrice is kept sorted by price, then ID, for range queries.
+	productsByPrice []domain.Product
+	searchIndex     *searchIndex
 }
 
 func NewProductRepository(storage store.Store) (ProductRepository, error) {
 	repo := &productRepository{storage: storage}
-	repo.productByCode = make(map[string]domain.Product)
-	products, err := storage.GetAllProducts()
-	if err != nil {
+	if err := repo.Reload(); err != nil {
 		return nil, err
 	}
-	for _, product := range products {
-		repo.productByCode[product.CodeValue] = product
-	}
 	return repo, nil
 }
 
-func (r *productRepository) GetProductByCode() map[string]domain.Product {
-	return r.productByCode
+// Reload discards the indexes and rebuilds them from the store.
+func (r *productRepository) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reload()
 }
 
-func (r *productRepository) GetAllProducts() ([]domain.Product, error) {
+func (r *productRepository) reload() error {
+	// Read the version before the data: a change in between only causes an
+	// extra reload later, never a missed one.
+	version, err := r.storage.Version()
+	if err != nil {
+		return err
+	}
 	products, err := r.storage.GetAllProducts()
 	if err != nil {
+		return err
+	}
+	r.productByID = make(map[int]domain.Product, len(products))
+	r.productByCode = make(map[string]domain.Product, len(products))
+	r.productByGTIN = make(map[string]domain.Product)
+	r.productByPLU = make(map[string]domain.Product)
+	r.productsByPrice = make([]domain.Product, 0, len(products))
+	r.searchIndex = newSearchIndex()
+	for _, product := range products {
+		r.productByID[product.ID] = product
+		r.productByCode[product.CodeValue] = product
+		for _, barcode := range product.Barcodes {
+			r.productByGTIN[barcode.GTIN] = product
+		}
+		if product.PLU != "" {
+			r.productByPLU[product.PLU] = product
+		}
+		r.productsByPrice = append(r.productsByPrice, product)
+		r.searchIndex.add(product)
+	}
+	sort.Slice(r.productsByPrice, func(i, j int) bool {
+		return lessByPrice(r.productsByPrice[i], r.productsByPrice[j])
+	})
+	r.version = version
+	return nil
+}
+
+// refresh reloads the indexes if the store changed since they were built.
+func (r *productRepository) refresh() error {
+	version, err := r.storage.Version()
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	fresh := version == r.version
+	r.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.version == version {
+		return nil
+	}
+	return r.reload()
+}
+
+// refreshLocked is refresh for callers already holding the write lock.
+func (r *productRepository) refreshLocked() error {
+	version, err := r.storage.Version()
+	if err != nil {
+		return err
+	}
+	if version == r.version {
+		return nil
+	}
+	return r.reload()
+}
+
+// syncVersion records the store version after one of our own writes so it is
+// not mistaken for an external change.
+func (r *productRepository) syncVersion() {
+	if version, err := r.storage.Version(); err == nil {
+		r.version = version
+	}
+}
+
+func lessByPrice(a, b domain.Product) bool {
+	if c := a.Price.Compare(b.Price); c != 0 {
+		return c < 0
+	}
+	return a.ID < b.ID
+}
+
+func (r *productRepository) indexProduct(product domain.Product) {
+	if old, ok := r.productByID[product.ID]; ok {
+		r.unindexProduct(old)
+	}
+	r.productByID[product.ID] = product
+	r.productByCode[product.CodeValue] = product
+	for _, barcode := range product.Barcodes {
+		r.productByGTIN[barcode.GTIN] = product
+	}
+	if product.PLU != "" {
+		r.productByPLU[product.PLU] = product
+	}
+	i := sort.Search(len(r.productsByPrice), func(i int) bool {
+		return !lessByPrice(r.productsByPrice[i], product)
+	})
+	r.productsByPrice = append(r.productsByPrice, domain.Product{})
+	copy(r.productsByPrice[i+1:], r.productsByPrice[i:])
+	r.productsByPrice[i] = product
+	r.searchIndex.add(product)
+}
+
+func (r *productRepository) unindexProduct(product domain.Product) {
+	delete(r.productByID, product.ID)
+	r.searchIndex.remove(product.ID)
+	if current, ok := r.productByCode[product.CodeValue]; ok && current.ID == product.ID {
+		delete(r.productByCode, product.CodeValue)
+	}
+	for _, barcode := range product.Barcodes {
+		if current, ok := r.productByGTIN[barcode.GTIN]; ok && current.ID == product.ID {
+			delete(r.productByGTIN, barcode.GTIN)
+		}
+	}
+	if current, ok := r.productByPLU[product.PLU]; ok && current.ID == product.ID {
+		delete(r.productByPLU, product.PLU)
+	}
+	i := sort.Search(len(r.productsByPrice), func(i int) bool {
+		return !lessByPrice(r.productsByPrice[i], product)
+	})
+	if i < len(r.productsByPrice) && r.productsByPrice[i].ID == product.ID {
+		r.productsByPrice = append(r.productsByPrice[:i], r.productsByPrice[i+1:]...)
+	}
+}
+
+func (r *productRepository) GetProductByCode(code string) (domain.Product, bool) {
+	if err := r.refresh(); err != nil {
+		return domain.Product{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	product, ok := r.productByCode[code]
+	return product, ok
+}
+
+// GetProductByBarcode finds the product with a barcode, given as 14 digits.
+func (r *productRepository) GetProductByBarcode(gtin string) (domain.Product, bool) {
+	if err := r.refresh(); err != nil {
+		return domain.Product{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	product, ok := r.productByGTIN[gtin]
+	return product, ok
+}
+
+// GetProductByPLU finds the product with a price look-up code, given without
+// extra leading zeros.
+func (r *productRepository) GetProductByPLU(plu string) (domain.Product, bool) {
+	if err := r.refresh(); err != nil {
+		return domain.Product{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	product, ok := r.productByPLU[plu]
+	return product, ok
+}
+
+func (r *productRepository) GetAllProducts() ([]domain.Product, error) {
+	if err := r.refresh(); err != nil {
 		return nil, err
 	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	products := make([]domain.Product, 0, len(r.productByID))
+	for _, product := range r.productByID {
+		products = append(products, product)
+	}
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].ID < products[j].ID
+	})
 	return products, nil
 }
 
 func (r *productRepository) GetProductByID(id int) (domain.Product, error) {
-	products, err := r.storage.GetAllProducts()
-	if err != nil {
+	if err := r.refresh(); err != nil {
 		return domain.Product{}, err
 	}
-	for _, p := range products {
-		if p.ID == id {
-			return p, nil
-		}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	product, ok := r.productByID[id]
+	if !ok {
+		return domain.Product{}, errors.New("Product not found")
 	}
-	return domain.Product{}, errors.New("Product not found")
+	return product, nil
 }
 
-func (r *productRepository) GetProductByPriceGreaterThan(price float64) []domain.Product {
-	products, _ := r.storage.GetAllProducts()
-	var filteredProducts []domain.Product
-	for _, product := range products {
-		if product.Price > price {
-			filteredProducts = append(filteredProducts, product)
-		}
+// GetProductByPriceGreaterThan returns the matching products ordered by price.
+func (r *productRepository) GetProductByPriceGreaterThan(price domain.Money) []domain.Product {
+	if err := r.refresh(); err != nil {
+		return nil
 	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	i := sort.Search(len(r.productsByPrice), func(i int) bool {
+		return r.productsByPrice[i].Price.Compare(price) > 0
+	})
+	var filteredProducts []domain.Product
+	filteredProducts = append(filteredProducts, r.productsByPrice[i:]...)
 	return filteredProducts
 }
 
 func (r *productRepository) CreateProduct(product *domain.Product) (*domain.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.refreshLocked(); err != nil {
+		return &domain.Product{}, err
+	}
 	product, err := r.storage.CreateProduct(product)
 	if err != nil {
 		return &domain.Product{}, err
 	}
-	r.productByCode[product.CodeValue] = *product
+	r.indexProduct(*product)
+	r.syncVersion()
 	return product, nil
 }
 
 func (r *productRepository) UpdateProduct(id int, product *domain.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.refreshLocked(); err != nil {
+		return err
+	}
 	product.ID = id
 	err := r.storage.UpdateProduct(*product)
 	if err != nil {
 		return err
 	}
-	r.productByCode[product.CodeValue] = *product
+	r.indexProduct(*product)
+	r.syncVersion()
 	return nil
 }
 
 func (r *productRepository) PatchProduct(id int, product *domain.Product) error {
-	products, err := r.storage.GetAllProducts()
-	if err != nil {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.refreshLocked(); err != nil {
 		return err
 	}
-	for i, p := range products {
-		if p.ID == id {
-			product.ID = id
-			if product.Name == "" {
-				product.Name = products[i].Name
-			}
-			if product.Quantity == 0 {
-				product.Quantity = products[i].Quantity
-			}
-			if product.CodeValue == "" {
-				product.CodeValue = products[i].CodeValue
-			}
-			if product.IsPublished == false {
-				product.IsPublished = products[i].IsPublished
-			}
-			if product.Expiration == "" {
-				product.Expiration = products[i].Expiration
-			}
-			if product.Price == 0 {
-				product.Price = products[i].Price
-			}
-			r.storage.UpdateProduct(*product)
-			r.productByCode[product.CodeValue] = *product
-			return nil
-		}
+	current, ok := r.productByID[id]
+	if !ok {
+		return errors.New("Product not found")
 	}
-	return errors.New("Product not found")
+	product.ID = id
+	if product.Name == "" {
+		product.Name = current.Name
+	}
+	if product.Quantity == 0 {
+		product.Quantity = current.Quantity
+	}
+	if product.CodeValue == "" {
+		product.CodeValue = current.CodeValue
+	}
+	if product.IsPublished == false {
+		product.IsPublished = current.IsPublished
+	}
+	if product.Expiration.IsZero() {
+		product.Expiration = current.Expiration
+	}
+	if product.Price.IsZero() {
+		product.Price = current.Price
+	}
+	if product.CategoryIDs == nil {
+		product.CategoryIDs = current.CategoryIDs
+	}
+	if product.MinStock == 0 {
+		product.MinStock = current.MinStock
+	}
+	if product.ReorderPoint == 0 {
+		product.ReorderPoint = current.ReorderPoint
+	}
+	if product.ReorderQuantity == 0 {
+		product.ReorderQuantity = current.ReorderQuantity
+	}
+	if product.TaxClass == "" {
+		product.TaxClass = current.TaxClass
+	}
+	if product.Barcodes == nil {
+		product.Barcodes = current.Barcodes
+	}
+	if product.Unit == "" {
+		product.Unit = current.Unit
+	}
+	if product.PLU == "" {
+		product.PLU = current.PLU
+	}
+	if err := r.storage.UpdateProduct(*product); err != nil {
+		return err
+	}
+	r.indexProduct(*product)
+	r.syncVersion()
+	return nil
 }
 
 func (r *productRepository) DeleteProduct(id int) error {
-	products, err := r.storage.GetAllProducts()
-	if err != nil {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.refreshLocked(); err != nil {
 		return err
 	}
-	for _, p := range products {
-		if p.ID == id {
-			r.storage.DeleteProduct(p.ID)
-			delete(r.productByCode, p.CodeValue)
-			return nil
-		}
+	product, ok := r.productByID[id]
+	if !ok {
+		return errors.New("Product not found")
 	}
-	return errors.New("Product not found")
+	if err := r.storage.DeleteProduct(id); err != nil {
+		return err
+	}
+	r.unindexProduct(product)
+	r.syncVersion()
+	return nil
 }
@@ -3,12 +3,17 @@ package repository
 import "github.com/NPG27/supermarket_dop/internal/domain"
 
 type ProductRepository interface {
-	GetProductByCode() map[string]domain.Product
+	GetProductByCode(code string) (domain.Product, bool)
+	GetProductByBarcode(gtin string) (domain.Product, bool)
+	GetProductByPLU(plu string) (domain.Product, bool)
 	GetAllProducts() ([]domain.Product, error)
 	GetProductByID(id int) (domain.Product, error)
-	GetProductByPriceGreaterThan(price float64) []domain.Product
+	GetProductByPriceGreaterThan(price domain.Money) []domain.Product
+	GetProductsByFilter(filter Filter) ([]domain.Product, error)
+	SearchProducts(query string, limit int) ([]SearchResult, error)
 	CreateProduct(product *domain.Product) (*domain.Product, error)
 	UpdateProduct(id int, product *domain.Product) error
 	PatchProduct(id int, product *domain.Product) error
 	DeleteProduct(id int) error
+	Reload() error
 }
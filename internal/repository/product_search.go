@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+)
+
+// SearchResult is a product matched by a name search, with its relevance.
+type SearchResult struct {
+	domain.Product
+	Score float64 `json:"score"`
+}
+
+const (
+	exactMatchWeight  = 1.0
+	prefixMatchWeight = 0.75
+	fuzzyMatchWeight  = 0.6
+	minPrefixLength   = 2
+)
+
+// searchIndex is an inverted index from name tokens to the products whose
+// name contains them.
+type searchIndex struct {
+	postings      map[string]map[int]bool
+	termsByID     map[int][]string
+	productsCount int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings:  make(map[string]map[int]bool),
+		termsByID: make(map[int][]string),
+	}
+}
+
+// tokenize lowercases text and splits it on anything that is not a letter or
+// a digit, dropping duplicates.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(fields))
+	tokens := fields[:0]
+	for _, field := range fields {
+		if !seen[field] {
+			seen[field] = true
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+func (idx *searchIndex) add(product domain.Product) {
+	idx.remove(product.ID)
+	terms := tokenize(product.Name)
+	for _, term := range terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[int]bool)
+		}
+		idx.postings[term][product.ID] = true
+	}
+	idx.termsByID[product.ID] = terms
+	idx.productsCount++
+}
+
+func (idx *searchIndex) remove(id int) {
+	terms, ok := idx.termsByID[id]
+	if !ok {
+		return
+	}
+	for _, term := range terms {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.termsByID, id)
+	idx.productsCount--
+}
+
+// maxEdits is how many typos a query token of the given length tolerates.
+func maxEdits(length int) int {
+	switch {
+	case length <= 3:
+		return 0
+	case length <= 7:
+		return 1
+	}
+	return 2
+}
+
+// editDistance is the Levenshtein distance between a and b, giving up with
+// limit+1 as soon as the distance is known to exceed limit.
+func editDistance(a, b []rune, limit int) int {
+	if d := len(a) - len(b); d > limit || -d > limit {
+		return limit + 1
+	}
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+			rowMin = minInt(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// termWeight scores how well an index term matches a query token: exact
+// matches beat prefix matches, which beat matches within the typo budget.
+func termWeight(token, term string) float64 {
+	if token == term {
+		return exactMatchWeight
+	}
+	if len(token) >= minPrefixLength && strings.HasPrefix(term, token) {
+		return prefixMatchWeight
+	}
+	tokenRunes := []rune(token)
+	limit := maxEdits(len(tokenRunes))
+	if limit == 0 {
+		return 0
+	}
+	if d := editDistance(tokenRunes, []rune(term), limit); d <= limit {
+		return fuzzyMatchWeight / float64(d)
+	}
+	return 0
+}
+
+// search ranks products by the sum, over query tokens, of the best matching
+// term weighted by its inverse document frequency, so rare words count more
+// than common ones like "canned".
+func (idx *searchIndex) search(query string) map[int]float64 {
+	scores := make(map[int]float64)
+	for _, token := range tokenize(query) {
+		best := make(map[int]float64)
+		for term, ids := range idx.postings {
+			weight := termWeight(token, term)
+			if weight == 0 {
+				continue
+			}
+			weight *= math.Log(1 + float64(idx.productsCount)/float64(len(ids)))
+			for id := range ids {
+				if weight > best[id] {
+					best[id] = weight
+				}
+			}
+		}
+		for id, weight := range best {
+			scores[id] += weight
+		}
+	}
+	return scores
+}
+
+// SearchProducts returns the products whose name matches query, best first.
+// A limit of 0 returns every match.
+func (r *productRepository) SearchProducts(query string, limit int) ([]SearchResult, error) {
+	if err := r.refresh(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	scores := r.searchIndex.search(query)
+	results := make([]SearchResult, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, SearchResult{
+			Product: r.productByID[id],
+			Score:   math.Round(score*1000) / 1000,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrPromotionNotFound = errors.New("Promotion not found")
+
+type promotionRepository struct {
+	storage *store.Collection[domain.Promotion]
+}
+
+func NewPromotionRepository(storage *store.Collection[domain.Promotion]) PromotionRepository {
+	return &promotionRepository{storage: storage}
+}
+
+func (r *promotionRepository) GetAllPromotions() ([]domain.Promotion, error) {
+	return r.storage.All()
+}
+
+func (r *promotionRepository) GetPromotionByID(id int) (domain.Promotion, error) {
+	promotions, err := r.storage.All()
+	if err != nil {
+		return domain.Promotion{}, err
+	}
+	for _, promotion := range promotions {
+		if promotion.ID == id {
+			return promotion, nil
+		}
+	}
+	return domain.Promotion{}, ErrPromotionNotFound
+}
+
+func (r *promotionRepository) CreatePromotion(promotion *domain.Promotion) (*domain.Promotion, error) {
+	err := r.storage.Mutate(func(promotions []domain.Promotion, nextID func() int) ([]domain.Promotion, error) {
+		promotion.ID = nextID()
+		return append(promotions, *promotion), nil
+	})
+	if err != nil {
+		return &domain.Promotion{}, err
+	}
+	return promotion, nil
+}
+
+func (r *promotionRepository) UpdatePromotion(id int, promotion *domain.Promotion) error {
+	return r.storage.Mutate(func(promotions []domain.Promotion, _ func() int) ([]domain.Promotion, error) {
+		for i, current := range promotions {
+			if current.ID == id {
+				promotion.ID = id
+				promotions[i] = *promotion
+				return promotions, nil
+			}
+		}
+		return nil, ErrPromotionNotFound
+	})
+}
+
+func (r *promotionRepository) DeletePromotion(id int) error {
+	return r.storage.Mutate(func(promotions []domain.Promotion, _ func() int) ([]domain.Promotion, error) {
+		for i, current := range promotions {
+			if current.ID == id {
+				return append(promotions[:i], promotions[i+1:]...), nil
+			}
+		}
+		return nil, ErrPromotionNotFound
+	})
+}
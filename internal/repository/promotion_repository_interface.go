@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type PromotionRepository interface {
+	GetAllPromotions() ([]domain.Promotion, error)
+	GetPromotionByID(id int) (domain.Promotion, error)
+	CreatePromotion(promotion *domain.Promotion) (*domain.Promotion, error)
+	UpdatePromotion(id int, promotion *domain.Promotion) error
+	DeletePromotion(id int) error
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrPurchaseOrderNotFound = errors.New("Purchase order not found")
+
+type purchaseOrderRepository struct {
+	storage *store.Collection[domain.PurchaseOrder]
+}
+
+func NewPurchaseOrderRepository(storage *store.Collection[domain.PurchaseOrder]) PurchaseOrderRepository {
+	return &purchaseOrderRepository{storage: storage}
+}
+
+func (r *purchaseOrderRepository) GetAllPurchaseOrders() ([]domain.PurchaseOrder, error) {
+	return r.storage.All()
+}
+
+func (r *purchaseOrderRepository) GetPurchaseOrderByID(id int) (domain.PurchaseOrder, error) {
+	orders, err := r.storage.All()
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	for _, order := range orders {
+		if order.ID == id {
+			return order, nil
+		}
+	}
+	return domain.PurchaseOrder{}, ErrPurchaseOrderNotFound
+}
+
+func (r *purchaseOrderRepository) CreatePurchaseOrder(order *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	err := r.storage.Mutate(func(orders []domain.PurchaseOrder, nextID func() int) ([]domain.PurchaseOrder, error) {
+		order.ID = nextID()
+		return append(orders, *order), nil
+	})
+	if err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	return order, nil
+}
+
+func (r *purchaseOrderRepository) UpdatePurchaseOrder(id int, order *domain.PurchaseOrder) error {
+	return r.storage.Mutate(func(orders []domain.PurchaseOrder, _ func() int) ([]domain.PurchaseOrder, error) {
+		for i, current := range orders {
+			if current.ID == id {
+				order.ID = id
+				orders[i] = *order
+				return orders, nil
+			}
+		}
+		return nil, ErrPurchaseOrderNotFound
+	})
+}
+
+func (r *purchaseOrderRepository) DeletePurchaseOrder(id int) error {
+	return r.storage.Mutate(func(orders []domain.PurchaseOrder, _ func() int) ([]domain.PurchaseOrder, error) {
+		for i, current := range orders {
+			if current.ID == id {
+				return append(orders[:i], orders[i+1:]...), nil
+			}
+		}
+		return nil, ErrPurchaseOrderNotFound
+	})
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type PurchaseOrderRepository interface {
+	GetAllPurchaseOrders() ([]domain.PurchaseOrder, error)
+	GetPurchaseOrderByID(id int) (domain.PurchaseOrder, error)
+	CreatePurchaseOrder(order *domain.PurchaseOrder) (*domain.PurchaseOrder, error)
+	UpdatePurchaseOrder(id int, order *domain.PurchaseOrder) error
+	DeletePurchaseOrder(id int) error
+}
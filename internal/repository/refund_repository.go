@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrRefundNotFound = errors.New("Refund not found")
+
+type refundRepository struct {
+	storage *store.Collection[domain.Refund]
+}
+
+func NewRefundRepository(storage *store.Collection[domain.Refund]) RefundRepository {
+	return &refundRepository{storage: storage}
+}
+
+func (r *refundRepository) GetRefundByID(id int) (domain.Refund, error) {
+	refunds, err := r.storage.All()
+	if err != nil {
+		return domain.Refund{}, err
+	}
+	for _, refund := range refunds {
+		if refund.ID == id {
+			return refund, nil
+		}
+	}
+	return domain.Refund{}, ErrRefundNotFound
+}
+
+func (r *refundRepository) GetRefundsBySale(saleID int) ([]domain.Refund, error) {
+	refunds, err := r.storage.All()
+	if err != nil {
+		return nil, err
+	}
+	saleRefunds := []domain.Refund{}
+	for _, refund := range refunds {
+		if refund.SaleID == saleID {
+			saleRefunds = append(saleRefunds, refund)
+		}
+	}
+	return saleRefunds, nil
+}
+
+func (r *refundRepository) CreateRefund(refund *domain.Refund) (*domain.Refund, error) {
+	err := r.storage.Mutate(func(refunds []domain.Refund, nextID func() int) ([]domain.Refund, error) {
+		refund.ID = nextID()
+		return append(refunds, *refund), nil
+	})
+	if err != nil {
+		return &domain.Refund{}, err
+	}
+	return refund, nil
+}
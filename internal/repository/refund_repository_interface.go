@@ -0,0 +1,10 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+// RefundRepository has no way to change or delete a refund once created.
+type RefundRepository interface {
+	GetRefundByID(id int) (domain.Refund, error)
+	GetRefundsBySale(saleID int) ([]domain.Refund, error)
+	CreateRefund(refund *domain.Refund) (*domain.Refund, error)
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+// stockMovementRepository is append-only: it has no way to change or delete
+// a movement once recorded.
+type stockMovementRepository struct {
+	storage *store.Collection[domain.StockMovement]
+}
+
+func NewStockMovementRepository(storage *store.Collection[domain.StockMovement]) StockMovementRepository {
+	return &stockMovementRepository{storage: storage}
+}
+
+func (r *stockMovementRepository) GetAllMovements() ([]domain.StockMovement, error) {
+	return r.storage.All()
+}
+
+func (r *stockMovementRepository) GetMovementsByProduct(productID int) ([]domain.StockMovement, error) {
+	movements, err := r.storage.All()
+	if err != nil {
+		return nil, err
+	}
+	productMovements := []domain.StockMovement{}
+	for _, movement := range movements {
+		if movement.ProductID == productID {
+			productMovements = append(productMovements, movement)
+		}
+	}
+	return productMovements, nil
+}
+
+// AppendMovements assigns IDs and saves all movements in a single write.
+func (r *stockMovementRepository) AppendMovements(movements ...*domain.StockMovement) error {
+	return r.storage.Mutate(func(all []domain.StockMovement, nextID func() int) ([]domain.StockMovement, error) {
+		for _, movement := range movements {
+			movement.ID = nextID()
+			all = append(all, *movement)
+		}
+		return all, nil
+	})
+}
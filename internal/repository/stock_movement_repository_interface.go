@@ -0,0 +1,9 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type StockMovementRepository interface {
+	GetAllMovements() ([]domain.StockMovement, error)
+	GetMovementsByProduct(productID int) ([]domain.StockMovement, error)
+	AppendMovements(movements ...*domain.StockMovement) error
+}
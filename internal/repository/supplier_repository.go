@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/pkg/store"
+)
+
+var ErrSupplierNotFound = errors.New("Supplier not found")
+
+var ErrSupplierProductNotFound = errors.New("Supplier does not sell this product")
+
+type supplierRepository struct {
+	storage *store.Collection[domain.Supplier]
+}
+
+func NewSupplierRepository(storage *store.Collection[domain.Supplier]) SupplierRepository {
+	return &supplierRepository{storage: storage}
+}
+
+func (r *supplierRepository) GetAllSuppliers() ([]domain.Supplier, error) {
+	return r.storage.All()
+}
+
+func (r *supplierRepository) GetSupplierByID(id int) (domain.Supplier, error) {
+	suppliers, err := r.storage.All()
+	if err != nil {
+		return domain.Supplier{}, err
+	}
+	for _, supplier := range suppliers {
+		if supplier.ID == id {
+			return supplier, nil
+		}
+	}
+	return domain.Supplier{}, ErrSupplierNotFound
+}
+
+func (r *supplierRepository) CreateSupplier(supplier *domain.Supplier) (*domain.Supplier, error) {
+	err := r.storage.Mutate(func(suppliers []domain.Supplier, nextID func() int) ([]domain.Supplier, error) {
+		supplier.ID = nextID()
+		return append(suppliers, *supplier), nil
+	})
+	if err != nil {
+		return &domain.Supplier{}, err
+	}
+	return supplier, nil
+}
+
+func (r *supplierRepository) UpdateSupplier(id int, supplier *domain.Supplier) error {
+	return r.storage.Mutate(func(suppliers []domain.Supplier, _ func() int) ([]domain.Supplier, error) {
+		for i, current := range suppliers {
+			if current.ID == id {
+				supplier.ID = id
+				suppliers[i] = *supplier
+				return suppliers, nil
+			}
+		}
+		return nil, ErrSupplierNotFound
+	})
+}
+
+func (r *supplierRepository) DeleteSupplier(id int) error {
+	return r.storage.Mutate(func(suppliers []domain.Supplier, _ func() int) ([]domain.Supplier, error) {
+		for i, current := range suppliers {
+			if current.ID == id {
+				return append(suppliers[:i], suppliers[i+1:]...), nil
+			}
+		}
+		return nil, ErrSupplierNotFound
+	})
+}
+
+type supplierProductRepository struct {
+	storage *store.Collection[domain.SupplierProduct]
+}
+
+func NewSupplierProductRepository(storage *store.Collection[domain.SupplierProduct]) SupplierProductRepository {
+	return &supplierProductRepository{storage: storage}
+}
+
+func (r *supplierProductRepository) GetAllSupplierProducts() ([]domain.SupplierProduct, error) {
+	return r.storage.All()
+}
+
+func (r *supplierProductRepository) filter(match func(domain.SupplierProduct) bool) ([]domain.SupplierProduct, error) {
+	links, err := r.storage.All()
+	if err != nil {
+		return nil, err
+	}
+	filtered := []domain.SupplierProduct{}
+	for _, link := range links {
+		if match(link) {
+			filtered = append(filtered, link)
+		}
+	}
+	return filtered, nil
+}
+
+func (r *supplierProductRepository) GetSupplierProductsBySupplier(supplierID int) ([]domain.SupplierProduct, error) {
+	return r.filter(func(link domain.SupplierProduct) bool {
+		return link.SupplierID == supplierID
+	})
+}
+
+func (r *supplierProductRepository) GetSupplierProductsByProduct(productID int) ([]domain.SupplierProduct, error) {
+	return r.filter(func(link domain.SupplierProduct) bool {
+		return link.ProductID == productID
+	})
+}
+
+func (r *supplierProductRepository) CreateSupplierProduct(link *domain.SupplierProduct) (*domain.SupplierProduct, error) {
+	err := r.storage.Mutate(func(links []domain.SupplierProduct, nextID func() int) ([]domain.SupplierProduct, error) {
+		for _, current := range links {
+			if current.SupplierID == link.SupplierID && current.ProductID == link.ProductID {
+				return nil, errors.New("Supplier already sells this product")
+			}
+		}
+		link.ID = nextID()
+		return append(links, *link), nil
+	})
+	if err != nil {
+		return &domain.SupplierProduct{}, err
+	}
+	return link, nil
+}
+
+func (r *supplierProductRepository) UpdateSupplierProduct(id int, link *domain.SupplierProduct) error {
+	return r.storage.Mutate(func(links []domain.SupplierProduct, _ func() int) ([]domain.SupplierProduct, error) {
+		for i, current := range links {
+			if current.ID == id {
+				link.ID = id
+				links[i] = *link
+				return links, nil
+			}
+		}
+		return nil, ErrSupplierProductNotFound
+	})
+}
+
+func (r *supplierProductRepository) DeleteSupplierProduct(id int) error {
+	return r.storage.Mutate(func(links []domain.SupplierProduct, _ func() int) ([]domain.SupplierProduct, error) {
+		for i, current := range links {
+			if current.ID == id {
+				return append(links[:i], links[i+1:]...), nil
+			}
+		}
+		return nil, ErrSupplierProductNotFound
+	})
+}
+
+func (r *supplierProductRepository) DeleteSupplierProductsBySupplier(supplierID int) error {
+	return r.storage.Mutate(func(links []domain.SupplierProduct, _ func() int) ([]domain.SupplierProduct, error) {
+		kept := links[:0]
+		for _, link := range links {
+			if link.SupplierID != supplierID {
+				kept = append(kept, link)
+			}
+		}
+		return kept, nil
+	})
+}
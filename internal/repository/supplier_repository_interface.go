@@ -0,0 +1,21 @@
+package repository
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type SupplierRepository interface {
+	GetAllSuppliers() ([]domain.Supplier, error)
+	GetSupplierByID(id int) (domain.Supplier, error)
+	CreateSupplier(supplier *domain.Supplier) (*domain.Supplier, error)
+	UpdateSupplier(id int, supplier *domain.Supplier) error
+	DeleteSupplier(id int) error
+}
+
+type SupplierProductRepository interface {
+	GetAllSupplierProducts() ([]domain.SupplierProduct, error)
+	GetSupplierProductsBySupplier(supplierID int) ([]domain.SupplierProduct, error)
+	GetSupplierProductsByProduct(productID int) ([]domain.SupplierProduct, error)
+	CreateSupplierProduct(link *domain.SupplierProduct) (*domain.SupplierProduct, error)
+	UpdateSupplierProduct(id int, link *domain.SupplierProduct) error
+	DeleteSupplierProduct(id int) error
+	DeleteSupplierProductsBySupplier(supplierID int) error
+}
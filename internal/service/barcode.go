@@ -0,0 +1,51 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+)
+
+// ErrUnknownBarcode is returned when no product carries a valid barcode.
+var ErrUnknownBarcode = errors.New("No product has this barcode")
+
+// validateBarcodes normalizes the barcodes of product id and checks that none
+// is repeated or belongs to another product.
+func (s *productService) validateBarcodes(id int, barcodes []domain.Barcode) error {
+	seen := make(map[string]bool, len(barcodes))
+	for i := range barcodes {
+		if err := barcodes[i].Normalize(); err != nil {
+			return err
+		}
+		gtin := barcodes[i].GTIN
+		if seen[gtin] {
+			return fmt.Errorf("Barcode %s is listed twice", gtin)
+		}
+		seen[gtin] = true
+		if owner, ok := s.productRepo.GetProductByBarcode(gtin); ok && owner.ID != id {
+			return fmt.Errorf("Barcode %s already belongs to product %d", gtin, owner.ID)
+		}
+	}
+	return nil
+}
+
+// GetProductByBarcode finds a product by any form of one of its barcodes and
+// returns the barcode that matched, which tells whether a unit or a case was
+// scanned.
+func (s *productService) GetProductByBarcode(code string) (domain.Product, domain.Barcode, error) {
+	gtin, err := domain.NormalizeGTIN(code)
+	if err != nil {
+		return domain.Product{}, domain.Barcode{}, err
+	}
+	product, ok := s.productRepo.GetProductByBarcode(gtin)
+	if !ok {
+		return domain.Product{}, domain.Barcode{}, fmt.Errorf("%w: %s", ErrUnknownBarcode, gtin)
+	}
+	for _, barcode := range product.Barcodes {
+		if barcode.GTIN == gtin {
+			return product, barcode, nil
+		}
+	}
+	return domain.Product{}, domain.Barcode{}, fmt.Errorf("%w: %s", ErrUnknownBarcode, gtin)
+}
@@ -0,0 +1,434 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+var (
+	// ErrUnknownCode is returned when a scanned code matches no product.
+	ErrUnknownCode = errors.New("No product has this code value")
+	// ErrCartClosed is returned when changing or paying a checked out cart.
+	ErrCartClosed = errors.New("Cart is already checked out")
+	// ErrNotForSale is returned when a cart holds an unpublished product.
+	ErrNotForSale = errors.New("Product is not for sale")
+)
+
+type cartService struct {
+	cartRepo         repository.CartRepository
+	saleRepo         repository.SaleRepository
+	productRepo      repository.ProductRepository
+	stockService     StockService
+	pricingService   PricingService
+	promotionService PromotionService
+	tax              *TaxCalculator
+	// mu serializes cart changes so two tills cannot check out the same
+	// cart twice.
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func NewCartService(cartRepo repository.CartRepository, saleRepo repository.SaleRepository, productRepo repository.ProductRepository, stockService StockService, pricingService PricingService, promotionService PromotionService, tax *TaxCalculator) *cartService {
+	return &cartService{
+		cartRepo:         cartRepo,
+		saleRepo:         saleRepo,
+		productRepo:      productRepo,
+		stockService:     stockService,
+		pricingService:   pricingService,
+		promotionService: promotionService,
+		tax:              tax,
+		now:              time.Now,
+	}
+}
+
+func (s *cartService) CreateCart() (*domain.Cart, error) {
+	now := s.now().UTC()
+	return s.cartRepo.CreateCart(&domain.Cart{
+		Status:    domain.CartOpen,
+		Lines:     []domain.CartLine{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+func (s *cartService) GetCartByID(id int) (domain.Cart, error) {
+	return s.cartRepo.GetCartByID(id)
+}
+
+// openCart loads a cart that can still be changed.
+func (s *cartService) openCart(id int) (domain.Cart, error) {
+	cart, err := s.cartRepo.GetCartByID(id)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	if cart.Status != domain.CartOpen {
+		return domain.Cart{}, ErrCartClosed
+	}
+	return cart, nil
+}
+
+func (s *cartService) saveCart(cart *domain.Cart) (*domain.Cart, error) {
+	cart.UpdatedAt = s.now().UTC()
+	if err := s.cartRepo.UpdateCart(cart.ID, cart); err != nil {
+		return &domain.Cart{}, err
+	}
+	return cart, nil
+}
+
+// productByBarcode finds the product carrying the scanned barcode. Scanning a
+// case barcode multiplies quantity, at least 1, by the units in the case.
+func (s *cartService) productByBarcode(code string, quantity *int) (domain.Product, bool) {
+	gtin, err := domain.NormalizeGTIN(code)
+	if err != nil {
+		return domain.Product{}, false
+	}
+	product, ok := s.productRepo.GetProductByBarcode(gtin)
+	if !ok {
+		return domain.Product{}, false
+	}
+	for _, barcode := range product.Barcodes {
+		if barcode.GTIN == gtin && barcode.Units > 1 {
+			*quantity = maxInt(*quantity, 1) * barcode.Units
+		}
+	}
+	return product, true
+}
+
+// productByLabel finds the product of a price- or weight-embedded scale label
+// by the PLU printed in it. ok is false for codes that are not a scale label.
+func (s *cartService) productByLabel(code string) (domain.Product, domain.EmbeddedBarcode, bool, error) {
+	embedded, ok, err := domain.ParseEmbeddedBarcode(code)
+	if !ok || err != nil {
+		return domain.Product{}, domain.EmbeddedBarcode{}, ok, err
+	}
+	product, ok := s.productRepo.GetProductByPLU(embedded.PLU)
+	if !ok {
+		return domain.Product{}, domain.EmbeddedBarcode{}, true, fmt.Errorf("%w: PLU %s", ErrUnknownCode, embedded.PLU)
+	}
+	return product, embedded, true, nil
+}
+
+// productByPLU finds the product with a keyed in PLU.
+func (s *cartService) productByPLU(code string) (domain.Product, bool) {
+	plu, err := domain.NormalizePLU(code)
+	if err != nil {
+		return domain.Product{}, false
+	}
+	return s.productRepo.GetProductByPLU(plu)
+}
+
+// scanLine works out the cart line for a scan of product. Products sold by
+// the kilogram, gram or litre take a measure, or the weight or price printed
+// on a scale label, instead of a quantity; everything else is counted.
+func (s *cartService) scanLine(product domain.Product, quantity int, measure domain.Measure, label domain.EmbeddedBarcode, labelled bool) (domain.CartLine, error) {
+	if labelled && (quantity != 0 || !measure.IsZero()) {
+		return domain.CartLine{}, errors.New("A scale label already carries the weight or price, scan it without quantity or measure")
+	}
+	line := domain.CartLine{
+		ProductID: product.ID,
+		CodeValue: product.CodeValue,
+		Name:      product.Name,
+		Quantity:  quantity,
+	}
+	unit := unitOf(product)
+	if !unit.Weighed() {
+		if !measure.IsZero() || label.Weight != 0 {
+			return domain.CartLine{}, fmt.Errorf("%s is sold by unit, scan it with a quantity instead of a measure", product.Name)
+		}
+		if !label.Price.IsZero() {
+			price := label.Price
+			line.LabelPrice = &price
+		}
+		if line.Quantity == 0 {
+			line.Quantity = 1
+		}
+		return line, nil
+	}
+
+	switch {
+	case !label.Price.IsZero():
+		priced, err := s.pricingService.PriceProduct(product)
+		if err != nil {
+			return domain.CartLine{}, err
+		}
+		// The scale charged for this weight at the effective price; it is
+		// what leaves the stock, to the nearest gram or millilitre.
+		price, per := label.Price, priced.EffectivePrice.Cents()
+		line.LabelPrice = &price
+		line.Quantity = int((price.Cents()*unit.BaseUnits() + per/2) / per)
+		if line.Quantity == 0 {
+			return domain.CartLine{}, fmt.Errorf("%s: the label price %s is below the price of the smallest weight", product.Name, price)
+		}
+	case label.Weight != 0:
+		// Stock of every weighed unit is counted in grams or millilitres,
+		// what the label carries; the line's measure shows it in the unit
+		// the product is sold in.
+		line.Quantity = label.Weight
+	default:
+		if quantity != 0 || measure.Sign() <= 0 {
+			return domain.CartLine{}, fmt.Errorf("%s is sold by %s, scan it with a measure instead of a quantity", product.Name, unit)
+		}
+		converted, err := measure.Quantity(unit)
+		if err != nil {
+			return domain.CartLine{}, err
+		}
+		line.Quantity = converted
+	}
+	weighed := domain.MeasureOf(line.Quantity, unit)
+	line.Unit = unit
+	line.Measure = &weighed
+	return line, nil
+}
+
+// productByScan finds the product a scanned code stands for: its code value,
+// one of its barcodes, a scale label or its PLU. label is set when the code
+// is a scale label. Scanning a case barcode multiplies quantity.
+func (s *cartService) productByScan(code string, quantity *int) (product domain.Product, label domain.EmbeddedBarcode, labelled bool, err error) {
+	product, ok := s.productRepo.GetProductByCode(code)
+	if !ok {
+		product, ok = s.productByBarcode(code, quantity)
+	}
+	if !ok {
+		product, label, labelled, err = s.productByLabel(code)
+		if err != nil {
+			return domain.Product{}, domain.EmbeddedBarcode{}, false, err
+		}
+		ok = labelled
+	}
+	if !ok {
+		product, ok = s.productByPLU(code)
+	}
+	if !ok {
+		return domain.Product{}, domain.EmbeddedBarcode{}, false, fmt.Errorf("%w: %s", ErrUnknownCode, code)
+	}
+	return product, label, labelled, nil
+}
+
+// addedBy tells whether line is the one scanning label added.
+func addedBy(line domain.CartLine, label domain.EmbeddedBarcode) bool {
+	if !label.Price.IsZero() {
+		return line.LabelPrice != nil && line.LabelPrice.Compare(label.Price) == 0
+	}
+	return line.LabelPrice == nil && line.Measure != nil && line.Quantity == label.Weight
+}
+
+// AddItem scans the product with codeValue into the cart. The code can also
+// be a barcode of the product, a scale label or a PLU. Counted products take
+// quantity units, 0 meaning a single one; weighed products take a measure in
+// the unit they are sold in, such as 0.355 for 355 g of a product sold by the
+// kilogram. Weighed and labelled items get a line each, other scans of a
+// product already in the cart add to its line.
+func (s *cartService) AddItem(cartID int, codeValue string, quantity int, measure domain.Measure) (*domain.Cart, error) {
+	if quantity < 0 {
+		return &domain.Cart{}, errors.New("Quantity must be positive")
+	}
+	if measure.Sign() < 0 {
+		return &domain.Cart{}, errors.New("Measure must be positive")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cart, err := s.openCart(cartID)
+	if err != nil {
+		return &domain.Cart{}, err
+	}
+	product, label, labelled, err := s.productByScan(codeValue, &quantity)
+	if err != nil {
+		return &domain.Cart{}, err
+	}
+	if !product.IsPublished {
+		return &domain.Cart{}, fmt.Errorf("%w: %s", ErrNotForSale, product.Name)
+	}
+	line, err := s.scanLine(product, quantity, measure, label, labelled)
+	if err != nil {
+		return &domain.Cart{}, err
+	}
+	if line.Measure == nil && line.LabelPrice == nil {
+		for i := range cart.Lines {
+			existing := &cart.Lines[i]
+			if existing.ProductID == product.ID && existing.Measure == nil && existing.LabelPrice == nil {
+				existing.Quantity += line.Quantity
+				return s.saveCart(&cart)
+			}
+		}
+	}
+	cart.Lines = append(cart.Lines, line)
+	return s.saveCart(&cart)
+}
+
+// RemoveItem takes quantity units of the product with codeValue out of the
+// cart. A quantity of 0, or more than the cart holds, removes the line, and
+// weighed or labelled items are always removed whole, one line at a time. A
+// line whose product has since been deleted or given another code is found by
+// the code value it was added with.
+func (s *cartService) RemoveItem(cartID int, codeValue string, quantity int) (*domain.Cart, error) {
+	if quantity < 0 {
+		return &domain.Cart{}, errors.New("Quantity must be positive")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cart, err := s.openCart(cartID)
+	if err != nil {
+		return &domain.Cart{}, err
+	}
+	product, label, labelled, errScan := s.productByScan(codeValue, &quantity)
+	if errScan != nil && !errors.Is(errScan, ErrUnknownCode) {
+		return &domain.Cart{}, errScan
+	}
+	for i := range cart.Lines {
+		matches := cart.Lines[i].ProductID == product.ID && (!labelled || addedBy(cart.Lines[i], label))
+		if errScan != nil {
+			// The catalog no longer knows the code.
+			matches = cart.Lines[i].CodeValue == codeValue
+		}
+		if !matches {
+			continue
+		}
+		line := cart.Lines[i]
+		if quantity == 0 || quantity >= line.Quantity || line.Measure != nil || line.LabelPrice != nil {
+			cart.Lines = append(cart.Lines[:i], cart.Lines[i+1:]...)
+		} else {
+			cart.Lines[i].Quantity -= quantity
+		}
+		return s.saveCart(&cart)
+	}
+	return &domain.Cart{}, fmt.Errorf("%w: %s is not in the cart", ErrUnknownCode, codeValue)
+}
+
+// priceCart prices every line at the product's current effective price,
+// applies the running promotions and taxes the result, and checks that the
+// product can still be sold.
+func (s *cartService) priceCart(cart domain.Cart) (domain.Sale, error) {
+	sale := domain.Sale{CartID: cart.ID, Lines: make([]domain.SaleLine, 0, len(cart.Lines))}
+	for _, line := range cart.Lines {
+		product, err := s.productRepo.GetProductByID(line.ProductID)
+		if err != nil {
+			return domain.Sale{}, fmt.Errorf("%s: %w", line.CodeValue, err)
+		}
+		if !product.IsPublished {
+			return domain.Sale{}, fmt.Errorf("%w: %s", ErrNotForSale, product.Name)
+		}
+		priced, err := s.pricingService.PriceProduct(product)
+		if err != nil {
+			return domain.Sale{}, err
+		}
+		// Weighed items cost their price per kilogram or litre pro rata;
+		// a scale label costs what is printed on it.
+		unit := unitOf(product)
+		listTotal := unit.Cost(product.Price, line.Quantity)
+		total := unit.Cost(priced.EffectivePrice, line.Quantity)
+		if line.LabelPrice != nil {
+			listTotal, total = *line.LabelPrice, *line.LabelPrice
+		}
+		saleLine := domain.SaleLine{
+			ProductID:  product.ID,
+			CodeValue:  product.CodeValue,
+			Name:       product.Name,
+			Quantity:   line.Quantity,
+			Unit:       line.Unit,
+			Measure:    line.Measure,
+			LabelPrice: line.LabelPrice,
+			ListPrice:  product.Price,
+			UnitPrice:  priced.EffectivePrice,
+			Total:      total,
+			TaxClass:   product.TaxClass,
+		}
+		sale.Lines = append(sale.Lines, saleLine)
+		sale.Subtotal = sale.Subtotal.Add(listTotal)
+	}
+	if err := s.promotionService.ApplyPromotions(sale.Lines); err != nil {
+		return domain.Sale{}, err
+	}
+	for _, line := range sale.Lines {
+		sale.Total = sale.Total.Add(line.Total)
+	}
+	sale.Discount = sale.Subtotal.Sub(sale.Total)
+	s.tax.TaxSale(&sale)
+	return sale, nil
+}
+
+// Quote prices an open cart the way checkout would, without selling it.
+func (s *cartService) Quote(cartID int) (domain.Sale, error) {
+	cart, err := s.openCart(cartID)
+	if err != nil {
+		return domain.Sale{}, err
+	}
+	return s.priceCart(cart)
+}
+
+// Checkout sells the cart: every product must still be published and in
+// stock, otherwise nothing is sold. Stock is taken out as one batch of sale
+// movements before the sale is recorded.
+func (s *cartService) Checkout(cartID int) (*domain.Sale, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cart, err := s.openCart(cartID)
+	if err != nil {
+		return &domain.Sale{}, err
+	}
+	if len(cart.Lines) == 0 {
+		return &domain.Sale{}, errors.New("Cart is empty")
+	}
+	sale, err := s.priceCart(cart)
+	if err != nil {
+		return &domain.Sale{}, err
+	}
+	movements := make([]*domain.StockMovement, 0, len(sale.Lines))
+	for _, line := range sale.Lines {
+		movements = append(movements, &domain.StockMovement{
+			ProductID: line.ProductID,
+			Type:      domain.MovementSale,
+			Quantity:  line.Quantity,
+			Reason:    "pos_sale",
+			Reference: fmt.Sprintf("CART-%d", cart.ID),
+		})
+	}
+	// Stock is taken first, then the cart is closed so it cannot be sold
+	// twice, then the sale is recorded. When a later write fails the earlier
+	// ones are undone.
+	if err := s.stockService.RecordMovements(movements...); err != nil {
+		return &domain.Sale{}, err
+	}
+	cart.Status = domain.CartCheckedOut
+	if _, err := s.saveCart(&cart); err != nil {
+		return &domain.Sale{}, s.undoCheckout(err, movements, nil)
+	}
+	sale.CreatedAt = s.now().UTC()
+	created, err := s.saleRepo.CreateSale(&sale)
+	if err != nil {
+		return &domain.Sale{}, s.undoCheckout(err, movements, &cart)
+	}
+	// The sale is recorded and names the cart, so failing to link it back
+	// from the cart loses nothing.
+	cart.SaleID = created.ID
+	_, _ = s.saveCart(&cart)
+	return created, nil
+}
+
+// undoCheckout returns the stock of a failed checkout and reopens its cart,
+// if it was closed. It returns the error that made the checkout fail, joined
+// with any error undoing it.
+func (s *cartService) undoCheckout(cause error, movements []*domain.StockMovement, cart *domain.Cart) error {
+	if err := s.stockService.ReverseMovements(movements...); err != nil {
+		return fmt.Errorf("%w; returning stock failed: %v", cause, err)
+	}
+	if cart != nil {
+		cart.Status = domain.CartOpen
+		if _, err := s.saveCart(cart); err != nil {
+			return fmt.Errorf("%w; reopening cart failed: %v", cause, err)
+		}
+	}
+	return cause
+}
+
+func (s *cartService) GetAllSales() ([]domain.Sale, error) {
+	return s.saleRepo.GetAllSales()
+}
+
+func (s *cartService) GetSaleByID(id int) (domain.Sale, error) {
+	return s.saleRepo.GetSaleByID(id)
+}
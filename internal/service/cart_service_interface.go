@@ -0,0 +1,14 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type CartService interface {
+	CreateCart() (*domain.Cart, error)
+	GetCartByID(id int) (domain.Cart, error)
+	AddItem(cartID int, codeValue string, quantity int, measure domain.Measure) (*domain.Cart, error)
+	RemoveItem(cartID int, codeValue string, quantity int) (*domain.Cart, error)
+	Quote(cartID int) (domain.Sale, error)
+	Checkout(cartID int) (*domain.Sale, error)
+	GetAllSales() ([]domain.Sale, error)
+	GetSaleByID(id int) (domain.Sale, error)
+}
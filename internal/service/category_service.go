@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// ErrCategoryInUse is returned when deleting a category that still has
+// subcategories or products.
+var ErrCategoryInUse = errors.New("Category is in use")
+
+// CategoryNode is a category with its subcategories, for tree views.
+type CategoryNode struct {
+	domain.Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// CategorySummary aggregates the products of a category and all of its
+// descendants. A product in several of those categories is counted once.
+type CategorySummary struct {
+	domain.Category
+	ProductCount   int          `json:"product_count"`
+	TotalQuantity  int          `json:"total_quantity"`
+	InventoryValue domain.Money `json:"inventory_value" swaggertype:"number"`
+}
+
+type categoryService struct {
+	categoryRepo repository.CategoryRepository
+	productRepo  repository.ProductRepository
+}
+
+func NewCategoryService(categoryRepo repository.CategoryRepository, productRepo repository.ProductRepository) *categoryService {
+	return &categoryService{categoryRepo: categoryRepo, productRepo: productRepo}
+}
+
+// slugify lowercases name and joins its words with dashes.
+func slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// descendantIDs returns rootID and the IDs of every category below it.
+func descendantIDs(categories []domain.Category, rootID int) map[int]bool {
+	children := make(map[int][]int)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+	ids := map[int]bool{rootID: true}
+	queue := []int{rootID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, child := range children[id] {
+			if !ids[child] {
+				ids[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return ids
+}
+
+// findCategory looks a category up by numeric ID or by slug.
+func findCategory(categories []domain.Category, ref string) (domain.Category, bool) {
+	id, errConverted := strconv.Atoi(ref)
+	for _, category := range categories {
+		if (errConverted == nil && category.ID == id) || category.Slug == ref {
+			return category, true
+		}
+	}
+	return domain.Category{}, false
+}
+
+func inCategories(product domain.Product, ids map[int]bool) bool {
+	for _, id := range product.CategoryIDs {
+		if ids[id] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *categoryService) validateCategory(id int, category *domain.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return errors.New("Category name is required")
+	}
+	if category.Slug == "" {
+		category.Slug = slugify(category.Name)
+	}
+	if category.Slug != slugify(category.Slug) {
+		return fmt.Errorf("Invalid slug %q, use lowercase words separated by dashes", category.Slug)
+	}
+	categories, err := s.categoryRepo.GetAllCategories()
+	if err != nil {
+		return err
+	}
+	for _, other := range categories {
+		if other.ID != id && other.Slug == category.Slug {
+			return fmt.Errorf("Slug %q already exists", category.Slug)
+		}
+	}
+	if category.ParentID == nil {
+		return nil
+	}
+	if _, ok := findCategory(categories, strconv.Itoa(*category.ParentID)); !ok {
+		return fmt.Errorf("Parent category %d does not exist", *category.ParentID)
+	}
+	if id != 0 && descendantIDs(categories, id)[*category.ParentID] {
+		return errors.New("A category cannot be moved below itself or its descendants")
+	}
+	return nil
+}
+
+func (s *categoryService) GetAllCategories() ([]domain.Category, error) {
+	return s.categoryRepo.GetAllCategories()
+}
+
+func (s *categoryService) GetCategoryTree() ([]*CategoryNode, error) {
+	categories, err := s.categoryRepo.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[int]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{Category: category, Children: []*CategoryNode{}}
+	}
+	roots := []*CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil && nodes[*category.ParentID] != nil {
+			parent := nodes[*category.ParentID]
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+func (s *categoryService) GetCategoryByID(id int) (domain.Category, error) {
+	return s.categoryRepo.GetCategoryByID(id)
+}
+
+func (s *categoryService) CreateCategory(category *domain.Category) (*domain.Category, error) {
+	if err := s.validateCategory(0, category); err != nil {
+		return &domain.Category{}, err
+	}
+	return s.categoryRepo.CreateCategory(category)
+}
+
+func (s *categoryService) UpdateCategory(id int, category *domain.Category) error {
+	if _, err := s.categoryRepo.GetCategoryByID(id); err != nil {
+		return err
+	}
+	if err := s.validateCategory(id, category); err != nil {
+		return err
+	}
+	return s.categoryRepo.UpdateCategory(id, category)
+}
+
+func (s *categoryService) DeleteCategory(id int) error {
+	categories, err := s.categoryRepo.GetAllCategories()
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		if category.ParentID != nil && *category.ParentID == id {
+			return fmt.Errorf("%w: it has subcategories", ErrCategoryInUse)
+		}
+	}
+	products, err := s.productRepo.GetAllProducts()
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		if inCategories(product, map[int]bool{id: true}) {
+			return fmt.Errorf("%w: product %d belongs to it", ErrCategoryInUse, product.ID)
+		}
+	}
+	return s.categoryRepo.DeleteCategory(id)
+}
+
+func (s *categoryService) GetCategorySummary() ([]CategorySummary, error) {
+	categories, err := s.categoryRepo.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	products, err := s.productRepo.GetAllProducts()
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]CategorySummary, 0, len(categories))
+	for _, category := range categories {
+		summary := CategorySummary{Category: category}
+		ids := descendantIDs(categories, category.ID)
+		for _, product := range products {
+			if inCategories(product, ids) {
+				summary.ProductCount++
+				summary.TotalQuantity += product.Quantity
+				summary.InventoryValue = summary.InventoryValue.Add(unitOf(product).Cost(product.Price, product.Quantity))
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].ID < summaries[j].ID
+	})
+	return summaries, nil
+}
@@ -0,0 +1,13 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type CategoryService interface {
+	GetAllCategories() ([]domain.Category, error)
+	GetCategoryTree() ([]*CategoryNode, error)
+	GetCategoryByID(id int) (domain.Category, error)
+	CreateCategory(category *domain.Category) (*domain.Category, error)
+	UpdateCategory(id int, category *domain.Category) error
+	DeleteCategory(id int) error
+	GetCategorySummary() ([]CategorySummary, error)
+}
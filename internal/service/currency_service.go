@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// ErrUnknownCurrency is returned when prices are asked for in a currency with
+// neither an exchange rate nor a price list.
+var ErrUnknownCurrency = errors.New("Unknown currency")
+
+type currencyService struct {
+	rateRepo      repository.ExchangeRateRepository
+	priceListRepo repository.PriceListRepository
+	productRepo   repository.ProductRepository
+	tax           *TaxCalculator
+	now           func() time.Time
+}
+
+func NewCurrencyService(rateRepo repository.ExchangeRateRepository, priceListRepo repository.PriceListRepository, productRepo repository.ProductRepository, tax *TaxCalculator) *currencyService {
+	return &currencyService{
+		rateRepo:      rateRepo,
+		priceListRepo: priceListRepo,
+		productRepo:   productRepo,
+		tax:           tax,
+		now:           time.Now,
+	}
+}
+
+func (s *currencyService) GetAllRates() ([]domain.ExchangeRate, error) {
+	return s.rateRepo.GetAllRates()
+}
+
+// SetRate adds or replaces the rate of a currency against DefaultCurrency.
+func (s *currencyService) SetRate(rate *domain.ExchangeRate) error {
+	currency, err := domain.ParseCurrency(string(rate.Currency))
+	if err != nil {
+		return err
+	}
+	if currency == domain.DefaultCurrency {
+		return fmt.Errorf("%s is the default currency, its rate is always 1", currency)
+	}
+	if rate.Rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+	rate.Currency = currency
+	rate.UpdatedAt = s.now().UTC()
+	return s.rateRepo.SaveRate(rate)
+}
+
+func (s *currencyService) DeleteRate(currency string) error {
+	code, err := domain.ParseCurrency(currency)
+	if err != nil {
+		return fmt.Errorf("%w: %v", repository.ErrExchangeRateNotFound, err)
+	}
+	return s.rateRepo.DeleteRate(code)
+}
+
+// rate returns the units of currency per unit of DefaultCurrency as an exact
+// fraction.
+func (s *currencyService) rate(currency domain.Currency) (*big.Rat, error) {
+	if currency == domain.DefaultCurrency {
+		return big.NewRat(1, 1), nil
+	}
+	rate, err := s.rateRepo.GetRate(currency)
+	if errors.Is(err, repository.ErrExchangeRateNotFound) {
+		return nil, fmt.Errorf("%w: no exchange rate for %s", ErrUnknownCurrency, currency)
+	} else if err != nil {
+		return nil, err
+	}
+	return domain.ExactRat(rate.Rate), nil
+}
+
+// Convert converts amount to currency through DefaultCurrency, rounding once
+// to the nearest hundredth.
+func (s *currencyService) Convert(amount domain.Money, to domain.Currency) (domain.Money, error) {
+	if amount.Currency() == to {
+		return amount, nil
+	}
+	from, err := s.rate(amount.Currency())
+	if err != nil {
+		return domain.Money{}, err
+	}
+	target, err := s.rate(to)
+	if err != nil {
+		return domain.Money{}, err
+	}
+	return amount.MulRat(new(big.Rat).Quo(target, from)).In(to), nil
+}
+
+// findPriceList picks the list for currency and store, falling back to the
+// currency's list for every store.
+func findPriceList(lists []domain.PriceList, currency domain.Currency, store string) *domain.PriceList {
+	var fallback *domain.PriceList
+	for i, list := range lists {
+		if list.Currency != currency {
+			continue
+		}
+		if list.Store == store {
+			return &lists[i]
+		}
+		if list.Store == "" {
+			fallback = &lists[i]
+		}
+	}
+	return fallback
+}
+
+// LocalizeProducts reprices products in currency for store. A product listed
+// in the matching price list gets the list price, marked down by the same
+// pricing rule; any other product has its prices converted at the exchange
+// rate. Tax is worked out again on the new effective price. Empty currency
+// means DefaultCurrency and empty store means no particular store.
+func (s *currencyService) LocalizeProducts(priced []domain.PricedProduct, currency string, store string) error {
+	code := domain.DefaultCurrency
+	if currency != "" {
+		parsed, err := domain.ParseCurrency(currency)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnknownCurrency, err)
+		}
+		code = parsed
+	}
+	lists, err := s.priceListRepo.GetAllPriceLists()
+	if err != nil {
+		return err
+	}
+	list := findPriceList(lists, code, strings.TrimSpace(store))
+	prices := make(map[int]domain.Money)
+	if list != nil {
+		for _, entry := range list.Prices {
+			prices[entry.ProductID] = entry.Price.In(code)
+		}
+	}
+	// Without a rate only the products in the price list can be priced.
+	rate, errRate := s.rate(code)
+	if errRate != nil && (list == nil || !errors.Is(errRate, ErrUnknownCurrency)) {
+		return errRate
+	}
+
+	for i := range priced {
+		product := &priced[i]
+		if price, ok := prices[product.ID]; ok {
+			product.Price = price
+			product.EffectivePrice = price
+			if product.AppliedRule != nil {
+				product.EffectivePrice = price.Sub(price.Percent(product.AppliedRule.DiscountPercent))
+			}
+			product.PriceListID = list.ID
+		} else if code != product.Price.Currency() {
+			if errRate != nil {
+				return fmt.Errorf("%w, product %d is not in price list %d", errRate, product.ID, list.ID)
+			}
+			product.Price = product.Price.MulRat(rate).In(code)
+			product.EffectivePrice = product.EffectivePrice.MulRat(rate).In(code)
+			product.ExchangeRate, _ = rate.Float64()
+		} else {
+			continue
+		}
+		product.Currency = code
+		s.tax.PriceProduct(product)
+	}
+	return nil
+}
+
+func (s *currencyService) validatePriceList(id int, priceList *domain.PriceList) error {
+	priceList.Name = strings.TrimSpace(priceList.Name)
+	if priceList.Name == "" {
+		return errors.New("Price list name is required")
+	}
+	currency, err := domain.ParseCurrency(string(priceList.Currency))
+	if err != nil {
+		return err
+	}
+	priceList.Currency = currency
+	priceList.Store = strings.TrimSpace(priceList.Store)
+	lists, err := s.priceListRepo.GetAllPriceLists()
+	if err != nil {
+		return err
+	}
+	for _, other := range lists {
+		if other.ID != id && other.Currency == priceList.Currency && other.Store == priceList.Store {
+			return fmt.Errorf("Price list %d already covers %s for this store", other.ID, priceList.Currency)
+		}
+	}
+	if priceList.Prices == nil {
+		priceList.Prices = []domain.PriceListEntry{}
+	}
+	seen := make(map[int]bool)
+	for _, entry := range priceList.Prices {
+		if seen[entry.ProductID] {
+			return fmt.Errorf("Product %d is listed twice", entry.ProductID)
+		}
+		seen[entry.ProductID] = true
+		if entry.Price.Sign() <= 0 {
+			return fmt.Errorf("Product %d: price must be positive", entry.ProductID)
+		}
+		if _, err := s.productRepo.GetProductByID(entry.ProductID); err != nil {
+			return fmt.Errorf("Product %d: %w", entry.ProductID, err)
+		}
+	}
+	return nil
+}
+
+func (s *currencyService) GetAllPriceLists() ([]domain.PriceList, error) {
+	return s.priceListRepo.GetAllPriceLists()
+}
+
+func (s *currencyService) GetPriceListByID(id int) (domain.PriceList, error) {
+	return s.priceListRepo.GetPriceListByID(id)
+}
+
+func (s *currencyService) CreatePriceList(priceList *domain.PriceList) (*domain.PriceList, error) {
+	if err := s.validatePriceList(0, priceList); err != nil {
+		return &domain.PriceList{}, err
+	}
+	return s.priceListRepo.CreatePriceList(priceList)
+}
+
+func (s *currencyService) UpdatePriceList(id int, priceList *domain.PriceList) error {
+	if _, err := s.priceListRepo.GetPriceListByID(id); err != nil {
+		return err
+	}
+	if err := s.validatePriceList(id, priceList); err != nil {
+		return err
+	}
+	return s.priceListRepo.UpdatePriceList(id, priceList)
+}
+
+func (s *currencyService) DeletePriceList(id int) error {
+	return s.priceListRepo.DeletePriceList(id)
+}
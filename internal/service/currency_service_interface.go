@@ -0,0 +1,16 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type CurrencyService interface {
+	GetAllRates() ([]domain.ExchangeRate, error)
+	SetRate(rate *domain.ExchangeRate) error
+	DeleteRate(currency string) error
+	Convert(amount domain.Money, to domain.Currency) (domain.Money, error)
+	GetAllPriceLists() ([]domain.PriceList, error)
+	GetPriceListByID(id int) (domain.PriceList, error)
+	CreatePriceList(priceList *domain.PriceList) (*domain.PriceList, error)
+	UpdatePriceList(id int, priceList *domain.PriceList) error
+	DeletePriceList(id int) error
+	LocalizeProducts(priced []domain.PricedProduct, currency string, store string) error
+}
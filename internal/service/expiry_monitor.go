@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// ExpiryAlert reports a product that is already expired or expires soon.
+type ExpiryAlert struct {
+	Product  domain.Product `json:"product"`
+	DaysLeft int            `json:"days_left"`
+	Expired  bool           `json:"expired"`
+}
+
+// ParseDays reads a window such as "7d", "2w" or "7" as a number of days.
+func ParseDays(value string) (int, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(value, "d"):
+		value = strings.TrimSuffix(value, "d")
+	case strings.HasSuffix(value, "w"):
+		value = strings.TrimSuffix(value, "w")
+		multiplier = 7
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("%w: window must look like 7d, 2w or 7", ErrInvalidQuery)
+	}
+	return days * multiplier, nil
+}
+
+// findExpiring returns alerts for products that expired before today or
+// expire within the next windowDays days, soonest first.
+func findExpiring(products []domain.Product, today domain.Date, windowDays int) []ExpiryAlert {
+	alerts := []ExpiryAlert{}
+	limit := today.AddDays(windowDays)
+	for _, product := range products {
+		if product.Expiration.IsZero() || product.Expiration.After(limit) {
+			continue
+		}
+		alerts = append(alerts, ExpiryAlert{
+			Product:  product,
+			DaysLeft: today.DaysUntil(product.Expiration),
+			Expired:  product.Expiration.Before(today),
+		})
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if c := alerts[i].Product.Expiration.Compare(alerts[j].Product.Expiration); c != 0 {
+			return c < 0
+		}
+		return alerts[i].Product.ID < alerts[j].Product.ID
+	})
+	return alerts
+}
+
+func (s *productService) GetExpiringProducts(windowDays int) ([]ExpiryAlert, error) {
+	products, err := s.productRepo.GetAllProducts()
+	if err != nil {
+		return nil, err
+	}
+	return findExpiring(products, domain.Today(), windowDays), nil
+}
+
+// ExpiryMonitor periodically looks for expired and soon to expire products,
+// sends new alerts to a Notifier and can unpublish expired products.
+type ExpiryMonitor struct {
+	productRepo   repository.ProductRepository
+	notifier      Notifier
+	windowDays    int
+	autoUnpublish bool
+
+	mu sync.Mutex
+	// notified remembers what was already reported per product, so each
+	// product is announced once as expiring and once more when it expires.
+	notified map[int]bool
+	stop     chan struct{}
+}
+
+func NewExpiryMonitor(repo repository.ProductRepository, notifier Notifier, windowDays int, autoUnpublish bool) *ExpiryMonitor {
+	return &ExpiryMonitor{
+		productRepo:   repo,
+		notifier:      notifier,
+		windowDays:    windowDays,
+		autoUnpublish: autoUnpublish,
+		notified:      make(map[int]bool),
+	}
+}
+
+// Check runs a single scan and returns the alerts that were sent.
+func (m *ExpiryMonitor) Check() ([]ExpiryAlert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	products, err := m.productRepo.GetAllProducts()
+	if err != nil {
+		return nil, err
+	}
+	var fresh []ExpiryAlert
+	var errs []error
+	for _, alert := range findExpiring(products, domain.Today(), m.windowDays) {
+		if expired, seen := m.notified[alert.Product.ID]; !seen || expired != alert.Expired {
+			fresh = append(fresh, alert)
+		}
+		if alert.Expired && m.autoUnpublish && alert.Product.IsPublished {
+			product := alert.Product
+			product.IsPublished = false
+			if err := m.productRepo.UpdateProduct(product.ID, &product); err != nil {
+				errs = append(errs, fmt.Errorf("unpublishing product %d: %w", product.ID, err))
+			}
+		}
+	}
+	if len(fresh) > 0 {
+		if err := m.notifier.Notify(fresh); err != nil {
+			return nil, errors.Join(append(errs, err)...)
+		}
+		for _, alert := range fresh {
+			m.notified[alert.Product.ID] = alert.Expired
+		}
+	}
+	return fresh, errors.Join(errs...)
+}
+
+// Start runs Check immediately and then every interval until Stop is called.
+func (m *ExpiryMonitor) Start(interval time.Duration) {
+	m.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := m.Check(); err != nil {
+				log.Printf("Expiry monitor: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (m *ExpiryMonitor) Stop() {
+	if m.stop != nil {
+		close(m.stop)
+	}
+}
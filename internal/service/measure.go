@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+)
+
+// unitOf resolves the unit a product is sold in.
+func unitOf(product domain.Product) domain.UnitOfMeasure {
+	if product.Unit == "" {
+		return domain.UnitEach
+	}
+	return product.Unit
+}
+
+// validateMeasure normalizes the unit and PLU of product id, which is 0 for a
+// new product, and checks that the PLU belongs to no other product. current
+// is the stored product, if any: its unit can only change while it has no
+// stock, since the stock is counted in that unit.
+func (s *productService) validateMeasure(id int, product *domain.Product, current *domain.Product) error {
+	if product.Unit != "" {
+		unit, err := domain.ParseUnit(string(product.Unit))
+		if err != nil {
+			return err
+		}
+		product.Unit = unit
+	}
+	if product.PLU != "" {
+		plu, err := domain.NormalizePLU(product.PLU)
+		if err != nil {
+			return err
+		}
+		product.PLU = plu
+		if owner, ok := s.productRepo.GetProductByPLU(plu); ok && owner.ID != id {
+			return fmt.Errorf("PLU %s already belongs to product %d", plu, owner.ID)
+		}
+	}
+	if current != nil && current.Quantity != 0 && unitOf(*product) != unitOf(*current) {
+		return fmt.Errorf("Unit of product %d can only change while it has no stock", id)
+	}
+	return nil
+}
+
+// measuredQuantity returns the stock units given as quantity or as measure
+// for a product sold in unit. Products sold by weight or volume count stock
+// in grams or millilitres but take a measure in their own unit, such as 2.5
+// for 2.5 kg; a bare quantity is rejected rather than read as grams.
+func measuredQuantity(unit domain.UnitOfMeasure, quantity int, measure *domain.Measure) (int, error) {
+	if measure == nil {
+		if unit.Weighed() && quantity != 0 {
+			return 0, fmt.Errorf("Products sold by %s take a measure in %s instead of a quantity", unit, unit)
+		}
+		return quantity, nil
+	}
+	if !unit.Weighed() {
+		return 0, errors.New("Products sold by unit take a quantity instead of a measure")
+	}
+	if quantity != 0 {
+		return 0, errors.New("Give either a quantity or a measure, not both")
+	}
+	return measure.Quantity(unit)
+}
+
+// applyMeasure sets the quantity of product from its measure, if it has one,
+// for a product sold in unit. kept is a quantity accepted without a measure
+// because it leaves the stock as it is.
+func applyMeasure(product *domain.Product, unit domain.UnitOfMeasure, kept int) error {
+	parsed, err := domain.ParseUnit(string(unit))
+	if err != nil {
+		return err
+	}
+	measure := product.Measure
+	product.Measure = nil
+	if measure == nil && product.Quantity == kept {
+		return nil
+	}
+	quantity, err := measuredQuantity(parsed, product.Quantity, measure)
+	if err != nil {
+		return err
+	}
+	product.Quantity = quantity
+	return nil
+}
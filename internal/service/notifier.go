@@ -0,0 +1,68 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Notifier delivers expiry alerts somewhere a person will see them.
+type Notifier interface {
+	Notify(alerts []ExpiryAlert) error
+}
+
+// LogNotifier writes one line per alert to the standard logger.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(alerts []ExpiryAlert) error {
+	for _, alert := range alerts {
+		state := fmt.Sprintf("expires in %d day(s)", alert.DaysLeft)
+		if alert.Expired {
+			state = fmt.Sprintf("expired %d day(s) ago", -alert.DaysLeft)
+		}
+		log.Printf("Expiry alert: product %d %q (%s) %s on %s",
+			alert.Product.ID, alert.Product.Name, alert.Product.CodeValue, state, alert.Product.Expiration)
+	}
+	return nil
+}
+
+// WebhookNotifier posts the alerts as JSON to a URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *WebhookNotifier) Notify(alerts []ExpiryAlert) error {
+	body, err := json.Marshal(map[string]interface{}{"alerts": alerts})
+	if err != nil {
+		return err
+	}
+	resp, err := n.Client.Post(n.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s answered %s", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// MultiNotifier sends alerts to every notifier in turn.
+type MultiNotifier []Notifier
+
+func (m MultiNotifier) Notify(alerts []ExpiryAlert) error {
+	for _, notifier := range m {
+		if err := notifier.Notify(alerts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// ErrPriceChangeStatus is returned when cancelling a change that already took
+// effect or was cancelled.
+var ErrPriceChangeStatus = errors.New("Price change is not scheduled")
+
+// ChangeNote says who changes a product and why, for its price history.
+type ChangeNote struct {
+	By     string
+	Reason string
+}
+
+// PriceHistory is the price timeline of a product, in order of effect. When
+// asked for a moment, PriceAsOf is the price in effect then, including
+// scheduled changes for moments still to come.
+type PriceHistory struct {
+	ProductID    int                  `json:"product_id"`
+	CurrentPrice domain.Money         `json:"current_price" swaggertype:"number"`
+	AsOf         *time.Time           `json:"as_of,omitempty"`
+	PriceAsOf    *domain.Money        `json:"price_as_of,omitempty" swaggertype:"number"`
+	Changes      []domain.PriceChange `json:"changes"`
+}
+
+type priceHistoryService struct {
+	changeRepo  repository.PriceChangeRepository
+	productRepo repository.ProductRepository
+	now         func() time.Time
+	// mu keeps a scheduled change from being applied twice, or applied and
+	// cancelled at once.
+	mu sync.Mutex
+}
+
+func NewPriceHistoryService(changeRepo repository.PriceChangeRepository, productRepo repository.ProductRepository) *priceHistoryService {
+	return &priceHistoryService{changeRepo: changeRepo, productRepo: productRepo, now: time.Now}
+}
+
+// RecordPriceChange logs a change of price that was just saved.
+func (s *priceHistoryService) RecordPriceChange(productID int, oldPrice domain.Money, newPrice domain.Money, note ChangeNote) error {
+	if oldPrice.Compare(newPrice) == 0 {
+		return nil
+	}
+	now := s.now().UTC()
+	_, err := s.changeRepo.CreatePriceChange(&domain.PriceChange{
+		ProductID:   productID,
+		OldPrice:    oldPrice,
+		NewPrice:    newPrice,
+		ChangedBy:   note.By,
+		Reason:      note.Reason,
+		EffectiveAt: now,
+		Status:      domain.PriceChangeApplied,
+		CreatedAt:   now,
+		AppliedAt:   &now,
+	})
+	return err
+}
+
+// SchedulePriceChange records a new price for the product taking effect at
+// change.EffectiveAt. Changes without a time or with one already past are
+// applied right away.
+func (s *priceHistoryService) SchedulePriceChange(productID int, change *domain.PriceChange) (*domain.PriceChange, error) {
+	if change.NewPrice.Sign() <= 0 {
+		return &domain.PriceChange{}, errors.New("new_price must be positive")
+	}
+	if _, err := s.productRepo.GetProductByID(productID); err != nil {
+		return &domain.PriceChange{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now().UTC()
+	change.ID = 0
+	change.ProductID = productID
+	change.OldPrice = domain.Money{}
+	change.Status = domain.PriceChangeScheduled
+	change.CreatedAt = now
+	change.AppliedAt = nil
+	if change.EffectiveAt.IsZero() {
+		change.EffectiveAt = now
+	}
+	change.EffectiveAt = change.EffectiveAt.UTC()
+	created, err := s.changeRepo.CreatePriceChange(change)
+	if err != nil {
+		return &domain.PriceChange{}, err
+	}
+	if !created.EffectiveAt.After(now) {
+		if err := s.apply(created, now); err != nil {
+			return &domain.PriceChange{}, err
+		}
+	}
+	return created, nil
+}
+
+// apply sets the product's price to the change's. s.mu must be held.
+func (s *priceHistoryService) apply(change *domain.PriceChange, now time.Time) error {
+	product, err := s.productRepo.GetProductByID(change.ProductID)
+	if err != nil {
+		// The product is gone; there is nothing left to reprice.
+		change.Status = domain.PriceChangeCancelled
+		return s.changeRepo.UpdatePriceChange(change)
+	}
+	if err := s.productRepo.PatchProduct(change.ProductID, &domain.Product{Price: change.NewPrice}); err != nil {
+		return err
+	}
+	change.OldPrice = product.Price
+	change.Status = domain.PriceChangeApplied
+	change.AppliedAt = &now
+	return s.changeRepo.UpdatePriceChange(change)
+}
+
+// ApplyDueChanges applies the scheduled changes whose time has come, earliest
+// first, and returns them.
+func (s *priceHistoryService) ApplyDueChanges() ([]domain.PriceChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes, err := s.changeRepo.GetAllPriceChanges()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	var due []domain.PriceChange
+	for _, change := range changes {
+		if change.Status == domain.PriceChangeScheduled && !change.EffectiveAt.After(now) {
+			due = append(due, change)
+		}
+	}
+	sortChanges(due)
+	applied := []domain.PriceChange{}
+	var errs []error
+	for i := range due {
+		if err := s.apply(&due[i], now); err != nil {
+			errs = append(errs, fmt.Errorf("price change %d: %w", due[i].ID, err))
+		} else if due[i].Status == domain.PriceChangeApplied {
+			applied = append(applied, due[i])
+		}
+	}
+	return applied, errors.Join(errs...)
+}
+
+func (s *priceHistoryService) CancelPriceChange(productID int, changeID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change, err := s.changeRepo.GetPriceChangeByID(changeID)
+	if err != nil {
+		return err
+	}
+	if change.ProductID != productID {
+		return repository.ErrPriceChangeNotFound
+	}
+	if change.Status != domain.PriceChangeScheduled {
+		return fmt.Errorf("%w: it is %s", ErrPriceChangeStatus, change.Status)
+	}
+	change.Status = domain.PriceChangeCancelled
+	return s.changeRepo.UpdatePriceChange(&change)
+}
+
+func sortChanges(changes []domain.PriceChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].EffectiveAt.Equal(changes[j].EffectiveAt) {
+			return changes[i].EffectiveAt.Before(changes[j].EffectiveAt)
+		}
+		return changes[i].ID < changes[j].ID
+	})
+}
+
+// priceAt works out the price in effect at moment from the product's current
+// price and its changes in order of effect. The latest change in effect by
+// then sets the price; before the first applied change it is the price that
+// change replaced.
+func priceAt(current domain.Money, changes []domain.PriceChange, moment time.Time) domain.Money {
+	var latest *domain.PriceChange
+	for i, change := range changes {
+		if change.Status == domain.PriceChangeCancelled || change.EffectiveAt.After(moment) {
+			continue
+		}
+		latest = &changes[i]
+	}
+	if latest != nil {
+		return latest.NewPrice
+	}
+	for _, change := range changes {
+		if change.Status == domain.PriceChangeApplied {
+			return change.OldPrice
+		}
+	}
+	return current
+}
+
+// GetPriceHistory applies any change that is due and returns the product's
+// price timeline, with the price as of asOf when it is given.
+func (s *priceHistoryService) GetPriceHistory(productID int, asOf *time.Time) (PriceHistory, error) {
+	if _, err := s.ApplyDueChanges(); err != nil {
+		log.Printf("Price scheduler: %v", err)
+	}
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return PriceHistory{}, err
+	}
+	changes, err := s.changeRepo.GetPriceChangesByProduct(productID)
+	if err != nil {
+		return PriceHistory{}, err
+	}
+	sortChanges(changes)
+	history := PriceHistory{ProductID: productID, CurrentPrice: product.Price, Changes: changes}
+	if asOf != nil {
+		price := priceAt(product.Price, changes, *asOf)
+		history.AsOf = asOf
+		history.PriceAsOf = &price
+	}
+	return history, nil
+}
+
+// PriceScheduler applies scheduled price changes when they come due.
+type PriceScheduler struct {
+	history PriceHistoryService
+	stop    chan struct{}
+}
+
+func NewPriceScheduler(history PriceHistoryService) *PriceScheduler {
+	return &PriceScheduler{history: history}
+}
+
+// Start applies due changes immediately and then every interval until Stop
+// is called.
+func (p *PriceScheduler) Start(interval time.Duration) {
+	p.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			applied, err := p.history.ApplyDueChanges()
+			if err != nil {
+				log.Printf("Price scheduler: %v", err)
+			}
+			for _, change := range applied {
+				log.Printf("Price scheduler: product %d now costs %s (change %d)", change.ProductID, change.NewPrice, change.ID)
+			}
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (p *PriceScheduler) Stop() {
+	if p.stop != nil {
+		close(p.stop)
+	}
+}
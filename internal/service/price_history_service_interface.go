@@ -0,0 +1,15 @@
+package service
+
+import (
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+)
+
+type PriceHistoryService interface {
+	RecordPriceChange(productID int, oldPrice domain.Money, newPrice domain.Money, note ChangeNote) error
+	SchedulePriceChange(productID int, change *domain.PriceChange) (*domain.PriceChange, error)
+	CancelPriceChange(productID int, changeID int) error
+	GetPriceHistory(productID int, asOf *time.Time) (PriceHistory, error)
+	ApplyDueChanges() ([]domain.PriceChange, error)
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+type pricingService struct {
+	ruleRepo    repository.PricingRuleRepository
+	productRepo repository.ProductRepository
+	tax         *TaxCalculator
+}
+
+func NewPricingService(ruleRepo repository.PricingRuleRepository, productRepo repository.ProductRepository, tax *TaxCalculator) *pricingService {
+	return &pricingService{ruleRepo: ruleRepo, productRepo: productRepo, tax: tax}
+}
+
+// PricingPreview summarizes what a set of rules would do to the catalog today.
+type PricingPreview struct {
+	AffectedProducts int                    `json:"affected_products"`
+	ListValue        domain.Money           `json:"list_value" swaggertype:"number"`
+	EffectiveValue   domain.Money           `json:"effective_value" swaggertype:"number"`
+	MarkdownValue    domain.Money           `json:"markdown_value" swaggertype:"number"`
+	Products         []domain.PricedProduct `json:"products"`
+}
+
+// applyRules picks the largest discount among the active rules whose window
+// covers the product's remaining shelf life. Expired products and products
+// without an expiration date keep their list price.
+func applyRules(product domain.Product, rules []domain.PricingRule, today domain.Date) domain.PricedProduct {
+	priced := domain.PricedProduct{Product: product, EffectivePrice: product.Price, Currency: product.Price.Currency()}
+	if product.Expiration.IsZero() {
+		return priced
+	}
+	daysLeft := today.DaysUntil(product.Expiration)
+	if daysLeft < 0 {
+		return priced
+	}
+	for i, rule := range rules {
+		if !rule.IsActive() || daysLeft > rule.WithinDays {
+			continue
+		}
+		if priced.AppliedRule == nil || rule.DiscountPercent > priced.AppliedRule.DiscountPercent {
+			priced.AppliedRule = &rules[i]
+		}
+	}
+	if priced.AppliedRule != nil {
+		priced.EffectivePrice = product.Price.Sub(product.Price.Percent(priced.AppliedRule.DiscountPercent))
+	}
+	return priced
+}
+
+func validateRule(rule domain.PricingRule) error {
+	if rule.Name == "" {
+		return errors.New("Pricing rule name is required")
+	}
+	if rule.WithinDays < 0 {
+		return errors.New("within_days must not be negative")
+	}
+	if rule.DiscountPercent <= 0 || rule.DiscountPercent > 100 {
+		return errors.New("discount_percent must be greater than 0 and at most 100")
+	}
+	return nil
+}
+
+func (s *pricingService) GetAllRules() ([]domain.PricingRule, error) {
+	return s.ruleRepo.GetAllRules()
+}
+
+func (s *pricingService) GetRuleByID(id int) (domain.PricingRule, error) {
+	return s.ruleRepo.GetRuleByID(id)
+}
+
+// defaultActive makes a rule sent without active one that is applied.
+func defaultActive(rule *domain.PricingRule) {
+	if rule.Active == nil {
+		active := true
+		rule.Active = &active
+	}
+}
+
+func (s *pricingService) CreateRule(rule *domain.PricingRule) (*domain.PricingRule, error) {
+	if err := validateRule(*rule); err != nil {
+		return &domain.PricingRule{}, err
+	}
+	defaultActive(rule)
+	return s.ruleRepo.CreateRule(rule)
+}
+
+func (s *pricingService) UpdateRule(id int, rule *domain.PricingRule) error {
+	if err := validateRule(*rule); err != nil {
+		return err
+	}
+	defaultActive(rule)
+	return s.ruleRepo.UpdateRule(id, rule)
+}
+
+func (s *pricingService) DeleteRule(id int) error {
+	return s.ruleRepo.DeleteRule(id)
+}
+
+func (s *pricingService) PriceProduct(product domain.Product) (domain.PricedProduct, error) {
+	rules, err := s.ruleRepo.GetAllRules()
+	if err != nil {
+		return domain.PricedProduct{}, err
+	}
+	priced := applyRules(product, rules, domain.Today())
+	s.tax.PriceProduct(&priced)
+	return priced, nil
+}
+
+func (s *pricingService) PriceProducts(products []domain.Product) ([]domain.PricedProduct, error) {
+	rules, err := s.ruleRepo.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+	today := domain.Today()
+	priced := make([]domain.PricedProduct, len(products))
+	for i, product := range products {
+		priced[i] = applyRules(product, rules, today)
+		s.tax.PriceProduct(&priced[i])
+	}
+	return priced, nil
+}
+
+// Preview applies rules, or the stored rules when rules is nil, to the whole
+// catalog without saving anything. Values are price times quantity on hand.
+func (s *pricingService) Preview(rules []domain.PricingRule) (PricingPreview, error) {
+	if rules == nil {
+		stored, err := s.ruleRepo.GetAllRules()
+		if err != nil {
+			return PricingPreview{}, err
+		}
+		rules = stored
+	}
+	for _, rule := range rules {
+		if err := validateRule(rule); err != nil {
+			return PricingPreview{}, err
+		}
+	}
+	products, err := s.productRepo.GetAllProducts()
+	if err != nil {
+		return PricingPreview{}, err
+	}
+	today := domain.Today()
+	preview := PricingPreview{Products: []domain.PricedProduct{}}
+	for _, product := range products {
+		priced := applyRules(product, rules, today)
+		s.tax.PriceProduct(&priced)
+		unit := unitOf(product)
+		preview.ListValue = preview.ListValue.Add(unit.Cost(product.Price, product.Quantity))
+		preview.EffectiveValue = preview.EffectiveValue.Add(unit.Cost(priced.EffectivePrice, product.Quantity))
+		if priced.AppliedRule != nil {
+			preview.AffectedProducts++
+			preview.Products = append(preview.Products, priced)
+		}
+	}
+	preview.MarkdownValue = preview.ListValue.Sub(preview.EffectiveValue)
+	return preview, nil
+}
@@ -0,0 +1,14 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type PricingService interface {
+	GetAllRules() ([]domain.PricingRule, error)
+	GetRuleByID(id int) (domain.PricingRule, error)
+	CreateRule(rule *domain.PricingRule) (*domain.PricingRule, error)
+	UpdateRule(id int, rule *domain.PricingRule) error
+	DeleteRule(id int) error
+	PriceProduct(product domain.Product) (domain.PricedProduct, error)
+	PriceProducts(products []domain.Product) ([]domain.PricedProduct, error)
+	Preview(rules []domain.PricingRule) (PricingPreview, error)
+}
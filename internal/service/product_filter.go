@@ -0,0 +1,313 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// FilterError reports a problem in a filter expression. Position is the
+// 1-based character offset where the problem was found.
+type FilterError struct {
+	Position int
+	Message  string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %s", e.Position, e.Message)
+}
+
+func (e *FilterError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+type filterFieldType int
+
+const (
+	filterMoney filterFieldType = iota
+	filterInteger
+	filterBool
+	filterDate
+	filterText
+)
+
+var filterFields = map[string]filterFieldType{
+	"id":           filterInteger,
+	"quantity":     filterInteger,
+	"price":        filterMoney,
+	"is_published": filterBool,
+	"expiration":   filterDate,
+	"name":         filterText,
+	"code_value":   filterText,
+}
+
+type filterTokenKind int
+
+const (
+	tokenEOF filterTokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type filterToken struct {
+	kind     filterTokenKind
+	text     string
+	position int
+}
+
+func (t filterToken) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-/:+", r)
+}
+
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		position := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, filterToken{tokenLParen, "(", position})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{tokenRParen, ")", position})
+			i++
+		case r == '"':
+			var text strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, &FilterError{position, "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, filterToken{tokenString, text.String(), position})
+		case strings.ContainsRune("=!<>~", r):
+			operator := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' && r != '=' && r != '~' {
+				operator += "="
+			}
+			if operator == "!" {
+				return nil, &FilterError{position, "expected \"!=\""}
+			}
+			tokens = append(tokens, filterToken{tokenOperator, operator, position})
+			i += len(operator)
+		case isWordRune(r):
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, filterToken{tokenWord, string(runes[start:i]), position})
+		default:
+			return nil, &FilterError{position, fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+	return append(tokens, filterToken{tokenEOF, "", len(runes) + 1}), nil
+}
+
+// filterParser is a recursive descent parser for
+//
+//	or         = and { "OR" and }
+//	and        = unary { "AND" unary }
+//	unary      = "NOT" unary | "(" or ")" | comparison
+//	comparison = field operator value
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *filterParser) keyword(word string) bool {
+	token := p.peek()
+	if token.kind == tokenWord && strings.EqualFold(token.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (repository.Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := repository.OrFilter{left}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, right)
+	}
+	if len(filters) == 1 {
+		return left, nil
+	}
+	return filters, nil
+}
+
+func (p *filterParser) parseAnd() (repository.Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	filters := repository.AndFilter{left}
+	for p.keyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, right)
+	}
+	if len(filters) == 1 {
+		return left, nil
+	}
+	return filters, nil
+}
+
+func (p *filterParser) parseUnary() (repository.Filter, error) {
+	if p.keyword("NOT") {
+		filter, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return repository.NotFilter{Filter: filter}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		open := p.next()
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if token := p.next(); token.kind != tokenRParen {
+			return nil, &FilterError{token.position, fmt.Sprintf("expected \")\" to close \"(\" at position %d, found %s", open.position, token.describe())}
+		}
+		return filter, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (repository.Filter, error) {
+	fieldToken := p.next()
+	if fieldToken.kind != tokenWord {
+		return nil, &FilterError{fieldToken.position, fmt.Sprintf("expected a field name, found %s", fieldToken.describe())}
+	}
+	field := strings.ToLower(fieldToken.text)
+	fieldType, ok := filterFields[field]
+	if !ok {
+		return nil, &FilterError{fieldToken.position, fmt.Sprintf("unknown field %q", fieldToken.text)}
+	}
+
+	operatorToken := p.next()
+	if operatorToken.kind != tokenOperator {
+		return nil, &FilterError{operatorToken.position, fmt.Sprintf("expected an operator after %q, found %s", fieldToken.text, operatorToken.describe())}
+	}
+	operator := operatorToken.text
+	switch {
+	case operator == "~" && fieldType != filterText:
+		return nil, &FilterError{operatorToken.position, fmt.Sprintf("operator \"~\" only applies to text fields, not %q", field)}
+	case fieldType == filterBool && operator != "=" && operator != "!=":
+		return nil, &FilterError{operatorToken.position, fmt.Sprintf("field %q only supports \"=\" and \"!=\"", field)}
+	}
+
+	valueToken := p.next()
+	if valueToken.kind != tokenWord && valueToken.kind != tokenString {
+		return nil, &FilterError{valueToken.position, fmt.Sprintf("expected a value after %q, found %s", operator, valueToken.describe())}
+	}
+	value, err := parseFilterValue(fieldType, valueToken.text)
+	if err != nil {
+		return nil, &FilterError{valueToken.position, fmt.Sprintf("invalid value %s for field %q: %v", valueToken.describe(), field, err)}
+	}
+	return repository.Comparison{Field: field, Operator: operator, Value: value}, nil
+}
+
+func parseFilterValue(fieldType filterFieldType, text string) (interface{}, error) {
+	switch fieldType {
+	case filterMoney:
+		value, err := domain.ParseMoney(text)
+		if err != nil {
+			return nil, errors.New("expected an amount with at most 2 decimals")
+		}
+		return value, nil
+	case filterInteger:
+		value, err := strconv.ParseInt(text, 10, 64)
+		switch {
+		case err == nil:
+			return value, nil
+		case errors.Is(err, strconv.ErrRange):
+			return nil, errors.New("integer out of range")
+		}
+		if _, err := strconv.ParseFloat(text, 64); err == nil {
+			return nil, errors.New("expected a whole number")
+		}
+		return nil, errors.New("expected an integer")
+	case filterBool:
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, errors.New("expected true or false")
+		}
+		return value, nil
+	case filterDate:
+		date, err := domain.ParseDate(text)
+		if err != nil {
+			return nil, errors.New("expected a date as YYYY-MM-DD or DD/MM/YYYY")
+		}
+		return date, nil
+	}
+	return text, nil
+}
+
+// parseFilter turns an expression such as
+// `price>=10 AND is_published=true AND name~"milk"` into a repository.Filter.
+func parseFilter(expression string) (repository.Filter, error) {
+	tokens, err := tokenizeFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &FilterError{1, "filter is empty"}
+	}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := p.peek(); token.kind != tokenEOF {
+		return nil, &FilterError{token.position, fmt.Sprintf("expected AND, OR or end of filter, found %s", token.describe())}
+	}
+	return filter, nil
+}
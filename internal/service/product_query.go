@@ -0,0 +1,249 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+)
+
+// ErrInvalidQuery is wrapped by every error caused by bad client input, so
+// handlers can tell it apart from storage failures.
+var ErrInvalidQuery = errors.New("invalid query")
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// ProductQuery describes how GET /products should be filtered, sorted and
+// paginated. When Paginate is false every product is returned. Category, an
+// ID or a slug, also matches products of its subcategories.
+type ProductQuery struct {
+	Category string
+	Sort     string
+	Paginate bool
+	Limit    int
+	Offset   int
+	Cursor   string
+}
+
+// ProductPage is one page of the sorted catalog.
+type ProductPage struct {
+	Products   []domain.Product
+	Total      int
+	Limit      int
+	Offset     int
+	NextCursor string
+	PrevCursor string
+}
+
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+type productComparator func(a, b domain.Product) int
+
+var productComparators = map[string]productComparator{
+	"id": func(a, b domain.Product) int {
+		return compareInts(a.ID, b.ID)
+	},
+	"name": func(a, b domain.Product) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	},
+	"quantity": func(a, b domain.Product) int {
+		return compareInts(a.Quantity, b.Quantity)
+	},
+	"code_value": func(a, b domain.Product) int {
+		return strings.Compare(a.CodeValue, b.CodeValue)
+	},
+	"is_published": func(a, b domain.Product) int {
+		return compareBools(a.IsPublished, b.IsPublished)
+	},
+	"expiration": func(a, b domain.Product) int {
+		return compareExpirations(a.Expiration, b.Expiration)
+	},
+	"price": func(a, b domain.Product) int {
+		return a.Price.Compare(b.Price)
+	},
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+// compareExpirations orders dates chronologically; missing dates sort after
+// every known one.
+func compareExpirations(a, b domain.Date) int {
+	switch {
+	case a.IsZero() && b.IsZero():
+		return 0
+	case a.IsZero():
+		return 1
+	case b.IsZero():
+		return -1
+	}
+	return a.Compare(b)
+}
+
+// parseSort turns "price,-expiration" into sort keys. The product ID is always
+// used as the final tie breaker so the order is total and cursors are stable.
+func parseSort(raw string) ([]sortKey, error) {
+	var keys []sortKey
+	if strings.TrimSpace(raw) == "" {
+		return keys, nil
+	}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		key := sortKey{field: strings.TrimLeft(part, "+-"), desc: strings.HasPrefix(part, "-")}
+		if _, ok := productComparators[key.field]; !ok {
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, part)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func compareProducts(a, b domain.Product, keys []sortKey) int {
+	for _, key := range keys {
+		c := productComparators[key.field](a, b)
+		if key.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInts(a.ID, b.ID)
+}
+
+// cursor points just after (or before) an anchor product in a given ordering.
+// It only carries the anchor's sort values, so it stays valid if the anchor
+// itself is deleted.
+type cursor struct {
+	Sort     string                     `json:"s"`
+	Backward bool                       `json:"b,omitempty"`
+	Anchor   map[string]json.RawMessage `json:"a"`
+}
+
+func encodeCursor(product domain.Product, rawSort string, keys []sortKey, backward bool) string {
+	productJSON, _ := json.Marshal(product)
+	var all map[string]json.RawMessage
+	_ = json.Unmarshal(productJSON, &all)
+	anchor := map[string]json.RawMessage{"id": all["id"]}
+	for _, key := range keys {
+		anchor[key.field] = all[key.field]
+	}
+	bytes, _ := json.Marshal(cursor{Sort: rawSort, Backward: backward, Anchor: anchor})
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func decodeCursor(raw string, rawSort string) (cursor, domain.Product, error) {
+	var c cursor
+	var anchor domain.Product
+	bytes, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return c, anchor, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if err := json.Unmarshal(bytes, &c); err != nil {
+		return c, anchor, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if c.Sort != rawSort {
+		return c, anchor, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalidQuery, c.Sort)
+	}
+	anchorJSON, _ := json.Marshal(c.Anchor)
+	if err := json.Unmarshal(anchorJSON, &anchor); err != nil {
+		return c, anchor, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return c, anchor, nil
+}
+
+func paginateProducts(products []domain.Product, query ProductQuery) (ProductPage, error) {
+	keys, err := parseSort(query.Sort)
+	if err != nil {
+		return ProductPage{}, err
+	}
+	sorted := make([]domain.Product, len(products))
+	copy(sorted, products)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareProducts(sorted[i], sorted[j], keys) < 0
+	})
+	total := len(sorted)
+	if !query.Paginate {
+		return ProductPage{Products: sorted, Total: total, Limit: total}, nil
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	if limit < 0 || limit > maxPageLimit {
+		return ProductPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxPageLimit)
+	}
+	if query.Offset < 0 {
+		return ProductPage{}, fmt.Errorf("%w: offset must not be negative", ErrInvalidQuery)
+	}
+
+	start := query.Offset
+	if query.Cursor != "" {
+		c, anchor, err := decodeCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return ProductPage{}, err
+		}
+		if c.Backward {
+			end := sort.Search(total, func(i int) bool {
+				return compareProducts(sorted[i], anchor, keys) >= 0
+			})
+			start = end - limit
+			if start < 0 {
+				start = 0
+			}
+		} else {
+			start = sort.Search(total, func(i int) bool {
+				return compareProducts(sorted[i], anchor, keys) > 0
+			})
+		}
+	}
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+
+	page := ProductPage{
+		Products: sorted[start:end],
+		Total:    total,
+		Limit:    limit,
+		Offset:   start,
+	}
+	if end < total && end > start {
+		page.NextCursor = encodeCursor(sorted[end-1], query.Sort, keys, false)
+	}
+	if start > 0 && end > start {
+		page.PrevCursor = encodeCursor(sorted[start], query.Sort, keys, true)
+	}
+	return page, nil
+}
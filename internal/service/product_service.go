@@ -2,18 +2,22 @@ package service
 
 import (
 	"errors"
-	"time"
+	"fmt"
+	"strings"
 
 	"github.com/NPG27/supermarket_dop/internal/domain"
 	"github.com/NPG27/supermarket_dop/internal/repository"
 )
 
 type productService struct {
-	productRepo repository.ProductRepository
+	productRepo  repository.ProductRepository
+	stockService StockService
+	categoryRepo repository.CategoryRepository
+	priceHistory PriceHistoryService
 }
 
-func NewProductService(repo repository.ProductRepository) *productService {
-	return &productService{productRepo: repo}
+func NewProductService(repo repository.ProductRepository, stockService StockService, categoryRepo repository.CategoryRepository, priceHistory PriceHistoryService) *productService {
+	return &productService{productRepo: repo, stockService: stockService, categoryRepo: categoryRepo, priceHistory: priceHistory}
 }
 
 func (s *productService) GetAllProducts() ([]domain.Product, error) {
@@ -24,6 +28,41 @@ func (s *productService) GetAllProducts() ([]domain.Product, error) {
 	return products, nil
 }
 
+func (s *productService) ListProducts(query ProductQuery) (ProductPage, error) {
+	products, err := s.productRepo.GetAllProducts()
+	if err != nil {
+		return ProductPage{}, err
+	}
+	if query.Category != "" {
+		products, err = s.filterByCategory(products, query.Category)
+		if err != nil {
+			return ProductPage{}, err
+		}
+	}
+	return paginateProducts(products, query)
+}
+
+// filterByCategory keeps the products assigned to the category identified by
+// ref (an ID or a slug) or to any of its subcategories.
+func (s *productService) filterByCategory(products []domain.Product, ref string) ([]domain.Product, error) {
+	categories, err := s.categoryRepo.GetAllCategories()
+	if err != nil {
+		return nil, err
+	}
+	category, ok := findCategory(categories, ref)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown category %q", ErrInvalidQuery, ref)
+	}
+	ids := descendantIDs(categories, category.ID)
+	filtered := []domain.Product{}
+	for _, product := range products {
+		if inCategories(product, ids) {
+			filtered = append(filtered, product)
+		}
+	}
+	return filtered, nil
+}
+
 func (s *productService) GetProductByID(id int) (domain.Product, error) {
 	product, err := s.productRepo.GetProductByID(id)
 	if err != nil {
@@ -32,70 +71,257 @@ func (s *productService) GetProductByID(id int) (domain.Product, error) {
 	return product, nil
 }
 
-func (s *productService) GetProductByPriceGreaterThan(price float64) []domain.Product {
+func (s *productService) GetProductByPriceGreaterThan(price domain.Money) []domain.Product {
 	return s.productRepo.GetProductByPriceGreaterThan(price)
 }
 
+func (s *productService) FilterProducts(expression string) ([]domain.Product, error) {
+	filter, err := parseFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	return s.productRepo.GetProductsByFilter(filter)
+}
+
+func (s *productService) SearchProducts(query string, limit int) ([]repository.SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("%w: search query is empty", ErrInvalidQuery)
+	}
+	if limit < 0 || limit > maxPageLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, maxPageLimit)
+	}
+	if limit == 0 {
+		limit = defaultPageLimit
+	}
+	return s.productRepo.SearchProducts(query, limit)
+}
+
+// validExpiration is the expiration check shared by product creation and
+// purchase order receiving.
+func validExpiration(expiration domain.Date) bool {
+	return !expiration.IsZero()
+}
+
 func validateProduct(product domain.Product) bool {
 	isCorrect := true
-	if product.Name == "" || product.Quantity == 0 || product.CodeValue == "" || product.Expiration == "" || product.Price == float64(0) {
+	if product.Name == "" || product.Quantity == 0 || product.CodeValue == "" || !validExpiration(product.Expiration) || product.Price.IsZero() {
 		isCorrect = false
 	}
 	return isCorrect
 }
 
+// validateReorderLevels checks the replenishment settings of a product.
+func validateReorderLevels(product domain.Product) error {
+	if product.MinStock < 0 || product.ReorderPoint < 0 || product.ReorderQuantity < 0 {
+		return errors.New("Minimum stock, reorder point and reorder quantity must not be negative")
+	}
+	if product.ReorderPoint > 0 && product.ReorderPoint < product.MinStock {
+		return errors.New("Reorder point must not be below the minimum stock")
+	}
+	return nil
+}
+
+// validateCategories checks that every category assigned to a product exists.
+func (s *productService) validateCategories(ids []int) error {
+	for _, id := range ids {
+		if _, err := s.categoryRepo.GetCategoryByID(id); err != nil {
+			if errors.Is(err, repository.ErrCategoryNotFound) {
+				return fmt.Errorf("Category %d does not exist", id)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *productService) CreateProduct(product *domain.Product) (*domain.Product, error) {
+	if err := applyMeasure(product, product.Unit, 0); err != nil {
+		return &domain.Product{}, err
+	}
 	if !validateProduct(*product) {
 		return nil, errors.New("Product is missing required values")
 	}
-	_, errProductExpiration := time.Parse("02/01/2006", product.Expiration)
-	if errProductExpiration != nil {
-		return &domain.Product{}, errors.New("Invalid expiration date format")
+	if err := validateReorderLevels(*product); err != nil {
+		return &domain.Product{}, err
+	}
+	if err := validateTaxClass(product.TaxClass); err != nil {
+		return &domain.Product{}, err
+	}
+	if err := s.validateCategories(product.CategoryIDs); err != nil {
+		return &domain.Product{}, err
 	}
-	productByCode := s.productRepo.GetProductByCode()
-	if _, codeValueExists := productByCode[product.CodeValue]; codeValueExists {
+	if err := s.validateBarcodes(0, product.Barcodes); err != nil {
+		return &domain.Product{}, err
+	}
+	if err := s.validateMeasure(0, product, nil); err != nil {
+		return &domain.Product{}, err
+	}
+	if _, codeValueExists := s.productRepo.GetProductByCode(product.CodeValue); codeValueExists {
 		return &domain.Product{}, errors.New("Code value already exists")
 	}
 	pr, err := s.productRepo.CreateProduct(product)
 	if err != nil {
 		return &domain.Product{}, err
 	}
+	if err := s.stockService.OpenLedger(*pr); err != nil {
+		return &domain.Product{}, err
+	}
 	return pr, nil
 }
 
-func (s *productService) UpdateProduct(id int, product *domain.Product) error {
+// adjustQuantity records a manual quantity change as a ledger adjustment, so
+// the product quantity keeps matching its stock movements. It returns the
+// recorded movement, or nil when the quantity did not change.
+func (s *productService) adjustQuantity(id int, from int, to int) (*domain.StockMovement, error) {
+	if from == to {
+		return nil, nil
+	}
+	movement := &domain.StockMovement{
+		ProductID: id,
+		Type:      domain.MovementAdjustment,
+		Quantity:  to - from,
+		Reason:    ReasonManualEdit,
+	}
+	if err := s.stockService.RecordMovements(movement); err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+// recordEdit records the ledger adjustment and the price change of an edit
+// already written to the product. When either fails the edit is undone, so
+// the product never disagrees with its ledger or its price history.
+func (s *productService) recordEdit(current domain.Product, from int, to int, price domain.Money, note ChangeNote) error {
+	movement, err := s.adjustQuantity(current.ID, from, to)
+	if err != nil {
+		return s.undoEdit(err, current, nil)
+	}
+	if err := s.priceHistory.RecordPriceChange(current.ID, current.Price, price, note); err != nil {
+		return s.undoEdit(err, current, movement)
+	}
+	return nil
+}
+
+// undoEdit reverses the ledger adjustment of a failed edit, if any, and
+// writes the product back as it was. It returns cause, joined with any error
+// met undoing the edit.
+func (s *productService) undoEdit(cause error, current domain.Product, movement *domain.StockMovement) error {
+	if movement != nil {
+		if err := s.stockService.ReverseMovements(movement); err != nil {
+			return fmt.Errorf("%w; reversing the stock adjustment failed: %v", cause, err)
+		}
+	}
+	if err := s.productRepo.UpdateProduct(current.ID, &current); err != nil {
+		return fmt.Errorf("%w; restoring product %d failed: %v", cause, current.ID, err)
+	}
+	return cause
+}
+
+// checkLotExpiration rejects a new expiration date for a product tracked by
+// lots, whose expiration comes from the lot that expires first.
+func (s *productService) checkLotExpiration(current domain.Product, expiration domain.Date) error {
+	if expiration.IsZero() || expiration.Compare(current.Expiration) == 0 {
+		return nil
+	}
+	lots, err := s.stockService.GetLots(current.ID)
+	if err != nil {
+		return err
+	}
+	if len(lots) > 0 {
+		return fmt.Errorf("Expiration of product %d comes from its lots, update the lot instead", current.ID)
+	}
+	return nil
+}
+
+func (s *productService) UpdateProduct(id int, product *domain.Product, note ChangeNote) error {
+	current, err := s.productRepo.GetProductByID(id)
+	if err != nil {
+		return err
+	}
+	// Sending back the stored quantity of a weighed product keeps it.
+	if err := applyMeasure(product, product.Unit, current.Quantity); err != nil {
+		return err
+	}
 	if !validateProduct(*product) {
 		return errors.New("Product is missing required values")
 	}
-	productByCode := s.productRepo.GetProductByCode()
-	if productMap, codeValueExists := productByCode[product.CodeValue]; productMap.ID != id && codeValueExists {
+	if productMap, codeValueExists := s.productRepo.GetProductByCode(product.CodeValue); productMap.ID != id && codeValueExists {
 		return errors.New("Code value already exists")
 	}
-	_, errProductExpiration := time.Parse("02/01/2006", product.Expiration)
-	if errProductExpiration != nil {
-		return errors.New("Invalid expiration date format")
+	if err := validateReorderLevels(*product); err != nil {
+		return err
 	}
-
-	err := s.productRepo.UpdateProduct(id, product)
+	if err := validateTaxClass(product.TaxClass); err != nil {
+		return err
+	}
+	if err := s.validateCategories(product.CategoryIDs); err != nil {
+		return err
+	}
+	if err := s.validateBarcodes(id, product.Barcodes); err != nil {
+		return err
+	}
+	if err := s.validateMeasure(id, product, &current); err != nil {
+		return err
+	}
+	if err := s.checkLotExpiration(current, product.Expiration); err != nil {
+		return err
+	}
+	quantity := product.Quantity
+	product.Quantity = current.Quantity
+	err = s.productRepo.UpdateProduct(id, product)
 	if err != nil {
 		return err
 	}
-	return nil
+	return s.recordEdit(current, current.Quantity, quantity, product.Price, note)
 }
 
-func (s *productService) PatchProduct(id int, product *domain.Product) error {
-	productByCode := s.productRepo.GetProductByCode()
-	if productMap, codeValueExists := productByCode[product.CodeValue]; product.CodeValue != "" && productMap.ID != id && codeValueExists {
+func (s *productService) PatchProduct(id int, product *domain.Product, note ChangeNote) error {
+	if productMap, codeValueExists := s.productRepo.GetProductByCode(product.CodeValue); product.CodeValue != "" && productMap.ID != id && codeValueExists {
 		return errors.New("Code value already exists")
 	}
-	if _, errProductExpiration := time.Parse("02/01/2006", product.Expiration); product.Expiration != "" && errProductExpiration != nil {
-		return errors.New("Invalid expiration date format")
+	if err := validateReorderLevels(*product); err != nil {
+		return err
+	}
+	if err := validateTaxClass(product.TaxClass); err != nil {
+		return err
 	}
-	err := s.productRepo.PatchProduct(id, product)
+	if err := s.validateCategories(product.CategoryIDs); err != nil {
+		return err
+	}
+	if err := s.validateBarcodes(id, product.Barcodes); err != nil {
+		return err
+	}
+	current, err := s.productRepo.GetProductByID(id)
 	if err != nil {
 		return err
 	}
-	return nil
+	// An empty unit leaves the unit as it is, so only a new one is checked
+	// against the stock.
+	unitFrom := &current
+	unit := product.Unit
+	if unit == "" {
+		unitFrom = nil
+		unit = current.Unit
+	}
+	if err := applyMeasure(product, unit, 0); err != nil {
+		return err
+	}
+	if err := s.validateMeasure(id, product, unitFrom); err != nil {
+		return err
+	}
+	if err := s.checkLotExpiration(current, product.Expiration); err != nil {
+		return err
+	}
+	quantity := product.Quantity
+	product.Quantity = 0
+	err = s.productRepo.PatchProduct(id, product)
+	if err != nil {
+		return err
+	}
+	if quantity == 0 {
+		quantity = product.Quantity
+	}
+	return s.recordEdit(current, product.Quantity, quantity, product.Price, note)
 }
 
 func (s *productService) DeleteProduct(id int) error {
@@ -1,13 +1,21 @@
 package service
 
-import "github.com/NPG27/supermarket_dop/internal/domain"
+import (
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
 
 type ProductService interface {
 	GetAllProducts() ([]domain.Product, error)
+	ListProducts(query ProductQuery) (ProductPage, error)
 	GetProductByID(id int) (domain.Product, error)
-	GetProductByPriceGreaterThan(price float64) []domain.Product
+	GetProductByBarcode(code string) (domain.Product, domain.Barcode, error)
+	GetProductByPriceGreaterThan(price domain.Money) []domain.Product
+	FilterProducts(expression string) ([]domain.Product, error)
+	SearchProducts(query string, limit int) ([]repository.SearchResult, error)
+	GetExpiringProducts(windowDays int) ([]ExpiryAlert, error)
 	CreateProduct(product *domain.Product) (*domain.Product, error)
-	UpdateProduct(id int, product *domain.Product) error
-	PatchProduct(id int, product *domain.Product) error
+	UpdateProduct(id int, product *domain.Product, note ChangeNote) error
+	PatchProduct(id int, product *domain.Product, note ChangeNote) error
 	DeleteProduct(id int) error
 }
@@ -0,0 +1,475 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+type promotionService struct {
+	promotionRepo repository.PromotionRepository
+	productRepo   repository.ProductRepository
+	categoryRepo  repository.CategoryRepository
+	today         func() domain.Date
+}
+
+func NewPromotionService(promotionRepo repository.PromotionRepository, productRepo repository.ProductRepository, categoryRepo repository.CategoryRepository) *promotionService {
+	return &promotionService{
+		promotionRepo: promotionRepo,
+		productRepo:   productRepo,
+		categoryRepo:  categoryRepo,
+		today:         domain.Today,
+	}
+}
+
+// promotionLine is a sale line as the promotion engine sees it. unitPrice is
+// what a unit costs without promotions, after markdowns. Prices of weighed
+// items are per kilogram or litre and their quantity is in grams or
+// millilitres.
+type promotionLine struct {
+	index     int
+	product   domain.Product
+	quantity  int
+	unit      domain.UnitOfMeasure
+	listPrice domain.Money
+	unitPrice domain.Money
+}
+
+func (l promotionLine) baseTotal() domain.Money {
+	return l.cost(l.unitPrice)
+}
+
+// cost returns what the line costs at price per unit.
+func (l promotionLine) cost(price domain.Money) domain.Money {
+	return l.unit.Cost(price, l.quantity)
+}
+
+// amount describes the quantity of the line, as in "2" or "0.355 kg".
+func (l promotionLine) amount() string {
+	if l.unit.Weighed() {
+		return fmt.Sprintf("%s %s", domain.MeasureOf(l.quantity, l.unit), l.unit)
+	}
+	return fmt.Sprintf("%d", l.quantity)
+}
+
+// counted leaves out weighed lines, which promotions counting units do not
+// apply to.
+func counted(lines []promotionLine) []promotionLine {
+	var kept []promotionLine
+	for _, line := range lines {
+		if !line.unit.Weighed() {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// promotionOffer is what one promotion would charge for some lines.
+type promotionOffer struct {
+	promotion domain.Promotion
+	lines     map[int]domain.AppliedPromotion
+	totals    map[int]domain.Money
+	savings   domain.Money
+}
+
+func newOffer(promotion domain.Promotion) *promotionOffer {
+	return &promotionOffer{
+		promotion: promotion,
+		lines:     make(map[int]domain.AppliedPromotion),
+		totals:    make(map[int]domain.Money),
+	}
+}
+
+// charge records that line costs total under the offer. Lines the promotion
+// would make more expensive are left out.
+func (o *promotionOffer) charge(line promotionLine, units int, total domain.Money, explanation string) {
+	savings := line.baseTotal().Sub(total)
+	if savings.Sign() <= 0 {
+		return
+	}
+	o.totals[line.index] = total
+	o.lines[line.index] = domain.AppliedPromotion{
+		PromotionID: o.promotion.ID,
+		Name:        o.promotion.Name,
+		Type:        o.promotion.Type,
+		Units:       units,
+		Savings:     savings,
+		Explanation: explanation,
+	}
+	o.savings = o.savings.Add(savings)
+}
+
+// evaluatePromotion works out what promotion would do to lines, all of which
+// are eligible for it. Units a promotion does not cover keep their price.
+func evaluatePromotion(promotion domain.Promotion, lines []promotionLine) *promotionOffer {
+	offer := newOffer(promotion)
+	switch promotion.Type {
+	case domain.PromotionPercentOff:
+		for _, line := range lines {
+			unit := line.listPrice.Sub(line.listPrice.Percent(promotion.Percent))
+			offer.charge(line, line.quantity, line.cost(unit),
+				fmt.Sprintf("%g%% off: %s x %s", promotion.Percent, line.amount(), unit))
+		}
+	case domain.PromotionFixedOff:
+		for _, line := range lines {
+			unit := line.listPrice.Sub(promotion.Amount)
+			if unit.Sign() < 0 {
+				unit = domain.Money{}
+			}
+			per := "each"
+			if line.unit.Weighed() {
+				per = "per " + string(line.unit)
+			}
+			offer.charge(line, line.quantity, line.cost(unit),
+				fmt.Sprintf("%s off %s: %s x %s", promotion.Amount, per, line.amount(), unit))
+		}
+	case domain.PromotionBuyXGetY:
+		size := promotion.BuyQuantity + promotion.FreeQuantity
+		for _, line := range counted(lines) {
+			groups := line.quantity / size
+			if groups == 0 {
+				continue
+			}
+			covered := groups * size
+			total := line.listPrice.Mul(groups * promotion.BuyQuantity).Add(line.unitPrice.Mul(line.quantity - covered))
+			offer.charge(line, covered, total,
+				fmt.Sprintf("Buy %d get %d free: %d free unit(s)", promotion.BuyQuantity, promotion.FreeQuantity, groups*promotion.FreeQuantity))
+		}
+	case domain.PromotionMultiBuy:
+		for _, line := range counted(lines) {
+			groups := line.quantity / promotion.BundleQuantity
+			if groups == 0 {
+				continue
+			}
+			covered := groups * promotion.BundleQuantity
+			total := promotion.BundlePrice.Mul(groups).Add(line.unitPrice.Mul(line.quantity - covered))
+			offer.charge(line, covered, total,
+				fmt.Sprintf("%d for %s: %d bundle(s)", promotion.BundleQuantity, promotion.BundlePrice, groups))
+		}
+	case domain.PromotionMixAndMatch:
+		evaluateMixAndMatch(promotion, counted(lines), offer)
+	}
+	return offer
+}
+
+// evaluateMixAndMatch bundles the most expensive eligible units, which saves
+// the customer the most, and spreads the bundle price over their lines in
+// proportion to what the bundled units would have cost.
+func evaluateMixAndMatch(promotion domain.Promotion, lines []promotionLine, offer *promotionOffer) {
+	sorted := append([]promotionLine(nil), lines...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].unitPrice.Compare(sorted[j].unitPrice) > 0
+	})
+	units := 0
+	for _, line := range sorted {
+		units += line.quantity
+	}
+	groups := units / promotion.BundleQuantity
+	if groups == 0 {
+		return
+	}
+	remaining := groups * promotion.BundleQuantity
+	bundled := make([]int, len(sorted))
+	weights := make([]int64, len(sorted))
+	var bundledValue domain.Money
+	for i, line := range sorted {
+		bundled[i] = minInt(line.quantity, remaining)
+		remaining -= bundled[i]
+		weights[i] = line.unitPrice.Mul(bundled[i]).Cents()
+		bundledValue = bundledValue.Add(line.unitPrice.Mul(bundled[i]))
+	}
+	price := promotion.BundlePrice.Mul(groups)
+	if bundledValue.Compare(price) <= 0 {
+		return
+	}
+	shares := price.Allocate(weights)
+	for i, line := range sorted {
+		if bundled[i] == 0 {
+			continue
+		}
+		total := shares[i].Add(line.unitPrice.Mul(line.quantity - bundled[i]))
+		offer.charge(line, bundled[i], total,
+			fmt.Sprintf("Any %d for %s: %d unit(s) bundled", promotion.BundleQuantity, promotion.BundlePrice, bundled[i]))
+	}
+}
+
+// eligible tells whether product takes part in promotion. categories maps
+// each of the promotion's categories to itself and its subcategories.
+func eligible(promotion domain.Promotion, product domain.Product, categories map[int]bool) bool {
+	for _, id := range promotion.ProductIDs {
+		if id == product.ID {
+			return true
+		}
+	}
+	return inCategories(product, categories)
+}
+
+// ApplyPromotions reprices sale lines with the promotions running today. A
+// line takes part in at most one promotion, and the lines are shared out
+// between promotions so that together they save the customer the most; see
+// bestOffers for the limit on how many ways are tried. Total and Promotion
+// are set on the lines that got a promotion.
+func (s *promotionService) ApplyPromotions(saleLines []domain.SaleLine) error {
+	promotions, err := s.promotionRepo.GetAllPromotions()
+	if err != nil {
+		return err
+	}
+	categories, err := s.categoryRepo.GetAllCategories()
+	if err != nil {
+		return err
+	}
+	today := s.today()
+	var running []domain.Promotion
+	for _, promotion := range promotions {
+		if promotion.ActiveOn(today) {
+			running = append(running, promotion)
+		}
+	}
+	if len(running) == 0 {
+		return nil
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].ID < running[j].ID })
+	promotionCategories := make([]map[int]bool, len(running))
+	for i, promotion := range running {
+		promotionCategories[i] = make(map[int]bool)
+		for _, id := range promotion.CategoryIDs {
+			for descendant := range descendantIDs(categories, id) {
+				promotionCategories[i][descendant] = true
+			}
+		}
+	}
+
+	lines := make([]promotionLine, 0, len(saleLines))
+	for i, saleLine := range saleLines {
+		// A scale label is charged at the printed price.
+		if saleLine.LabelPrice != nil {
+			continue
+		}
+		product, err := s.productRepo.GetProductByID(saleLine.ProductID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", saleLine.CodeValue, err)
+		}
+		lines = append(lines, promotionLine{
+			index:     i,
+			product:   product,
+			quantity:  saleLine.Quantity,
+			unit:      unitOf(product),
+			listPrice: saleLine.ListPrice,
+			unitPrice: saleLine.UnitPrice,
+		})
+	}
+
+	for _, offer := range bestOffers(running, promotionCategories, lines) {
+		for index, applied := range offer.lines {
+			applied := applied
+			saleLines[index].Promotion = &applied
+			saleLines[index].Total = offer.totals[index]
+		}
+	}
+	return nil
+}
+
+// maxCombinations bounds the ways of sharing lines between mix and match
+// promotions that bestOffers tries. Beyond it the lines are shared out
+// greedily.
+const maxCombinations = 4096
+
+// bestOffers shares lines out between promotions, each line going to at most
+// one, so that the offers together save the most. Promotions other than mix
+// and match price every line on its own, so a line simply takes the best of
+// them; only the lines a mix and match promotion could bundle are tried in
+// every combination, since bundling one takes it away from its own offer.
+func bestOffers(promotions []domain.Promotion, categories []map[int]bool, lines []promotionLine) []*promotionOffer {
+	var offers []*promotionOffer
+	solo := make(map[int]*promotionOffer)
+	var bundling []promotionLine
+	var choices [][]int
+	combinations := 1
+	for _, line := range lines {
+		var mixes []int
+		for i, promotion := range promotions {
+			if !eligible(promotion, line.product, categories[i]) {
+				continue
+			}
+			if promotion.Type == domain.PromotionMixAndMatch {
+				mixes = append(mixes, i)
+				continue
+			}
+			offer := evaluatePromotion(promotion, []promotionLine{line})
+			if offer.savings.Sign() > 0 && (solo[line.index] == nil || offer.savings.Compare(solo[line.index].savings) > 0) {
+				solo[line.index] = offer
+			}
+		}
+		if len(mixes) == 0 {
+			if offer, ok := solo[line.index]; ok {
+				offers = append(offers, offer)
+			}
+			continue
+		}
+		bundling = append(bundling, line)
+		choices = append(choices, mixes)
+		if combinations <= maxCombinations {
+			combinations *= len(mixes) + 1
+		}
+	}
+	if len(bundling) == 0 {
+		return offers
+	}
+	if combinations > maxCombinations {
+		return append(offers, greedyOffers(promotions, categories, bundling)...)
+	}
+
+	// chosen holds, for each bundling line, the mix and match promotion it
+	// goes to, or -1 for its own best offer.
+	chosen := make([]int, len(bundling))
+	var best []*promotionOffer
+	var bestSavings domain.Money
+	var try func(k int)
+	try = func(k int) {
+		if k < len(bundling) {
+			chosen[k] = -1
+			try(k + 1)
+			for _, i := range choices[k] {
+				chosen[k] = i
+				try(k + 1)
+			}
+			return
+		}
+		var combination []*promotionOffer
+		var savings domain.Money
+		bundles := make(map[int][]promotionLine)
+		for j, line := range bundling {
+			if chosen[j] >= 0 {
+				bundles[chosen[j]] = append(bundles[chosen[j]], line)
+			} else if offer, ok := solo[line.index]; ok {
+				combination = append(combination, offer)
+				savings = savings.Add(offer.savings)
+			}
+		}
+		for i := range promotions {
+			if bundled, ok := bundles[i]; ok {
+				offer := evaluatePromotion(promotions[i], bundled)
+				combination = append(combination, offer)
+				savings = savings.Add(offer.savings)
+			}
+		}
+		if best == nil || savings.Compare(bestSavings) > 0 {
+			best, bestSavings = combination, savings
+		}
+	}
+	try(0)
+	return append(offers, best...)
+}
+
+// greedyOffers applies the promotion saving the most first, then the best
+// one among the lines left, and so on.
+func greedyOffers(promotions []domain.Promotion, categories []map[int]bool, lines []promotionLine) []*promotionOffer {
+	var offers []*promotionOffer
+	assigned := make(map[int]bool)
+	for {
+		var best *promotionOffer
+		for i, promotion := range promotions {
+			var candidates []promotionLine
+			for _, line := range lines {
+				if !assigned[line.index] && eligible(promotion, line.product, categories[i]) {
+					candidates = append(candidates, line)
+				}
+			}
+			if len(candidates) == 0 {
+				continue
+			}
+			offer := evaluatePromotion(promotion, candidates)
+			if offer.savings.Sign() > 0 && (best == nil || offer.savings.Compare(best.savings) > 0) {
+				best = offer
+			}
+		}
+		if best == nil {
+			return offers
+		}
+		for index := range best.lines {
+			assigned[index] = true
+		}
+		offers = append(offers, best)
+	}
+}
+
+func (s *promotionService) validatePromotion(promotion *domain.Promotion) error {
+	promotion.Name = strings.TrimSpace(promotion.Name)
+	if promotion.Name == "" {
+		return errors.New("Promotion name is required")
+	}
+	if promotion.Active == nil {
+		active := true
+		promotion.Active = &active
+	}
+	if len(promotion.ProductIDs) == 0 && len(promotion.CategoryIDs) == 0 {
+		return errors.New("Promotion needs product_ids or category_ids")
+	}
+	if !promotion.StartsOn.IsZero() && !promotion.EndsOn.IsZero() && promotion.EndsOn.Before(promotion.StartsOn) {
+		return errors.New("ends_on must not be before starts_on")
+	}
+	switch promotion.Type {
+	case domain.PromotionPercentOff:
+		if promotion.Percent <= 0 || promotion.Percent > 100 {
+			return errors.New("percent must be greater than 0 and at most 100")
+		}
+	case domain.PromotionFixedOff:
+		if promotion.Amount.Sign() <= 0 {
+			return errors.New("amount must be positive")
+		}
+	case domain.PromotionBuyXGetY:
+		if promotion.BuyQuantity < 1 || promotion.FreeQuantity < 1 {
+			return errors.New("buy_quantity and free_quantity must be at least 1")
+		}
+	case domain.PromotionMultiBuy, domain.PromotionMixAndMatch:
+		if promotion.BundleQuantity < 2 {
+			return errors.New("bundle_quantity must be at least 2")
+		}
+		if promotion.BundlePrice.Sign() <= 0 {
+			return errors.New("bundle_price must be positive")
+		}
+	default:
+		return fmt.Errorf("Invalid promotion type %q", promotion.Type)
+	}
+	for _, id := range promotion.ProductIDs {
+		if _, err := s.productRepo.GetProductByID(id); err != nil {
+			return fmt.Errorf("Product %d: %w", id, err)
+		}
+	}
+	for _, id := range promotion.CategoryIDs {
+		if _, err := s.categoryRepo.GetCategoryByID(id); err != nil {
+			return fmt.Errorf("Category %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (s *promotionService) GetAllPromotions() ([]domain.Promotion, error) {
+	return s.promotionRepo.GetAllPromotions()
+}
+
+func (s *promotionService) GetPromotionByID(id int) (domain.Promotion, error) {
+	return s.promotionRepo.GetPromotionByID(id)
+}
+
+func (s *promotionService) CreatePromotion(promotion *domain.Promotion) (*domain.Promotion, error) {
+	if err := s.validatePromotion(promotion); err != nil {
+		return &domain.Promotion{}, err
+	}
+	return s.promotionRepo.CreatePromotion(promotion)
+}
+
+func (s *promotionService) UpdatePromotion(id int, promotion *domain.Promotion) error {
+	if err := s.validatePromotion(promotion); err != nil {
+		return err
+	}
+	return s.promotionRepo.UpdatePromotion(id, promotion)
+}
+
+func (s *promotionService) DeletePromotion(id int) error {
+	return s.promotionRepo.DeletePromotion(id)
+}
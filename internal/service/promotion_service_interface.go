@@ -0,0 +1,12 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type PromotionService interface {
+	GetAllPromotions() ([]domain.Promotion, error)
+	GetPromotionByID(id int) (domain.Promotion, error)
+	CreatePromotion(promotion *domain.Promotion) (*domain.Promotion, error)
+	UpdatePromotion(id int, promotion *domain.Promotion) error
+	DeletePromotion(id int) error
+	ApplyPromotions(lines []domain.SaleLine) error
+}
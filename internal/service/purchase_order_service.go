@@ -0,0 +1,331 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// ErrPurchaseOrderStatus is returned for actions the order's current status
+// does not allow, such as editing a submitted order.
+var ErrPurchaseOrderStatus = errors.New("Action not allowed for the purchase order status")
+
+// ErrOverReceipt is returned when a delivery exceeds what is still expected.
+var ErrOverReceipt = errors.New("Received quantity exceeds the ordered quantity")
+
+var purchaseOrderStatuses = map[domain.PurchaseOrderStatus]bool{
+	domain.PurchaseOrderDraft:             true,
+	domain.PurchaseOrderSubmitted:         true,
+	domain.PurchaseOrderPartiallyReceived: true,
+	domain.PurchaseOrderReceived:          true,
+	domain.PurchaseOrderClosed:            true,
+}
+
+// purchaseOrderActions lists the statuses each action is allowed from.
+var purchaseOrderActions = map[string][]domain.PurchaseOrderStatus{
+	"edit":    {domain.PurchaseOrderDraft},
+	"delete":  {domain.PurchaseOrderDraft},
+	"submit":  {domain.PurchaseOrderDraft},
+	"receive": {domain.PurchaseOrderSubmitted, domain.PurchaseOrderPartiallyReceived},
+	"close":   {domain.PurchaseOrderSubmitted, domain.PurchaseOrderPartiallyReceived, domain.PurchaseOrderReceived},
+}
+
+func checkPurchaseOrderAction(order domain.PurchaseOrder, action string) error {
+	for _, status := range purchaseOrderActions[action] {
+		if order.Status == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: cannot %s a %s order", ErrPurchaseOrderStatus, action, order.Status)
+}
+
+type purchaseOrderService struct {
+	orderRepo    repository.PurchaseOrderRepository
+	supplierRepo repository.SupplierRepository
+	linkRepo     repository.SupplierProductRepository
+	productRepo  repository.ProductRepository
+	stockService StockService
+	// mu serializes status changes so concurrent deliveries cannot both pass
+	// the over-receiving check.
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func NewPurchaseOrderService(orderRepo repository.PurchaseOrderRepository, supplierRepo repository.SupplierRepository, linkRepo repository.SupplierProductRepository, productRepo repository.ProductRepository, stockService StockService) *purchaseOrderService {
+	return &purchaseOrderService{
+		orderRepo:    orderRepo,
+		supplierRepo: supplierRepo,
+		linkRepo:     linkRepo,
+		productRepo:  productRepo,
+		stockService: stockService,
+		now:          time.Now,
+	}
+}
+
+// prepareLines validates the ordered products and fills in unit costs from
+// the supplier's prices where none was given.
+func (s *purchaseOrderService) prepareLines(order *domain.PurchaseOrder) error {
+	if _, err := s.supplierRepo.GetSupplierByID(order.SupplierID); err != nil {
+		return err
+	}
+	if len(order.Lines) == 0 {
+		return errors.New("Purchase order needs at least one line")
+	}
+	links, err := s.linkRepo.GetSupplierProductsBySupplier(order.SupplierID)
+	if err != nil {
+		return err
+	}
+	costs := make(map[int]domain.Money, len(links))
+	for _, link := range links {
+		costs[link.ProductID] = link.CostPrice
+	}
+	seen := make(map[int]bool, len(order.Lines))
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		if seen[line.ProductID] {
+			return fmt.Errorf("Product %d appears in more than one line", line.ProductID)
+		}
+		seen[line.ProductID] = true
+		product, err := s.productRepo.GetProductByID(line.ProductID)
+		if err != nil {
+			return fmt.Errorf("Line %d: %w", i+1, err)
+		}
+		if line.Quantity, err = measuredQuantity(unitOf(product), line.Quantity, line.Measure); err != nil {
+			return fmt.Errorf("Line %d: %w", i+1, err)
+		}
+		line.Measure = nil
+		if line.Quantity <= 0 {
+			return fmt.Errorf("Line %d: quantity must be positive", i+1)
+		}
+		if line.UnitCost.Sign() < 0 {
+			return fmt.Errorf("Line %d: unit cost must not be negative", i+1)
+		}
+		if line.UnitCost.IsZero() {
+			cost, ok := costs[line.ProductID]
+			if !ok {
+				return fmt.Errorf("Line %d: supplier %d has no price for product %d, give a unit_cost", i+1, order.SupplierID, line.ProductID)
+			}
+			line.UnitCost = cost
+		}
+		line.QuantityReceived = 0
+	}
+	return nil
+}
+
+func (s *purchaseOrderService) GetAllPurchaseOrders(status string) ([]domain.PurchaseOrder, error) {
+	if status != "" && !purchaseOrderStatuses[domain.PurchaseOrderStatus(status)] {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidQuery, status)
+	}
+	orders, err := s.orderRepo.GetAllPurchaseOrders()
+	if err != nil {
+		return nil, err
+	}
+	filtered := []domain.PurchaseOrder{}
+	for _, order := range orders {
+		if status == "" || order.Status == domain.PurchaseOrderStatus(status) {
+			filtered = append(filtered, order)
+		}
+	}
+	return filtered, nil
+}
+
+func (s *purchaseOrderService) GetPurchaseOrderByID(id int) (domain.PurchaseOrder, error) {
+	return s.orderRepo.GetPurchaseOrderByID(id)
+}
+
+func (s *purchaseOrderService) CreatePurchaseOrder(order *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	if err := s.prepareLines(order); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	order.Status = domain.PurchaseOrderDraft
+	order.Receipts = []domain.PurchaseOrderReceipt{}
+	order.CreatedAt = s.now().UTC()
+	order.UpdatedAt = order.CreatedAt
+	return s.orderRepo.CreatePurchaseOrder(order)
+}
+
+// UpdatePurchaseOrder replaces the supplier and lines of a draft order.
+func (s *purchaseOrderService) UpdatePurchaseOrder(id int, order *domain.PurchaseOrder) (*domain.PurchaseOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.orderRepo.GetPurchaseOrderByID(id)
+	if err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	if err := checkPurchaseOrderAction(current, "edit"); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	if err := s.prepareLines(order); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	current.SupplierID = order.SupplierID
+	current.Lines = order.Lines
+	current.UpdatedAt = s.now().UTC()
+	if err := s.orderRepo.UpdatePurchaseOrder(id, &current); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	return &current, nil
+}
+
+func (s *purchaseOrderService) DeletePurchaseOrder(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.orderRepo.GetPurchaseOrderByID(id)
+	if err != nil {
+		return err
+	}
+	if err := checkPurchaseOrderAction(current, "delete"); err != nil {
+		return err
+	}
+	return s.orderRepo.DeletePurchaseOrder(id)
+}
+
+// transition applies action to the order and saves it with status.
+func (s *purchaseOrderService) transition(id int, action string, status domain.PurchaseOrderStatus) (*domain.PurchaseOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, err := s.orderRepo.GetPurchaseOrderByID(id)
+	if err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	if err := checkPurchaseOrderAction(order, action); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	order.Status = status
+	order.UpdatedAt = s.now().UTC()
+	if err := s.orderRepo.UpdatePurchaseOrder(id, &order); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	return &order, nil
+}
+
+func (s *purchaseOrderService) SubmitPurchaseOrder(id int) (*domain.PurchaseOrder, error) {
+	return s.transition(id, "submit", domain.PurchaseOrderSubmitted)
+}
+
+// ClosePurchaseOrder ends the order. Closing an order that was not fully
+// received gives up on the missing units.
+func (s *purchaseOrderService) ClosePurchaseOrder(id int) (*domain.PurchaseOrder, error) {
+	return s.transition(id, "close", domain.PurchaseOrderClosed)
+}
+
+// validateReceipt checks a delivery against what the order still expects.
+func validateReceipt(order domain.PurchaseOrder, receipt domain.PurchaseOrderReceipt) error {
+	if len(receipt.Lines) == 0 {
+		return errors.New("Receipt needs at least one line")
+	}
+	outstanding := make(map[int]int, len(order.Lines))
+	for _, line := range order.Lines {
+		outstanding[line.ProductID] = line.Quantity - line.QuantityReceived
+	}
+	for i, line := range receipt.Lines {
+		remaining, ok := outstanding[line.ProductID]
+		if !ok {
+			return fmt.Errorf("Line %d: product %d is not part of the order", i+1, line.ProductID)
+		}
+		if line.Quantity <= 0 {
+			return fmt.Errorf("Line %d: quantity must be positive", i+1)
+		}
+		if !validExpiration(line.Expiration) {
+			return fmt.Errorf("Line %d: expiration date is required", i+1)
+		}
+		if line.Quantity > remaining {
+			return fmt.Errorf("%w: product %d has %d units outstanding", ErrOverReceipt, line.ProductID, remaining)
+		}
+		outstanding[line.ProductID] = remaining - line.Quantity
+	}
+	return nil
+}
+
+// measureReceipt reads the delivered measures of products sold by weight or
+// volume as quantities.
+func (s *purchaseOrderService) measureReceipt(receipt *domain.PurchaseOrderReceipt) error {
+	for i := range receipt.Lines {
+		line := &receipt.Lines[i]
+		product, err := s.productRepo.GetProductByID(line.ProductID)
+		if err != nil {
+			return fmt.Errorf("Line %d: %w", i+1, err)
+		}
+		if line.Quantity, err = measuredQuantity(unitOf(product), line.Quantity, line.Measure); err != nil {
+			return fmt.Errorf("Line %d: %w", i+1, err)
+		}
+		line.Measure = nil
+	}
+	return nil
+}
+
+// receiptMovement returns the movement stocking one delivered line as a lot,
+// so the product's expiration follows the lot that expires first.
+func receiptMovement(orderID int, line *domain.ReceiptLine) *domain.StockMovement {
+	if line.LotNumber == "" {
+		line.LotNumber = fmt.Sprintf("PO-%d-%s", orderID, line.Expiration)
+	}
+	return &domain.StockMovement{
+		ProductID:  line.ProductID,
+		Type:       domain.MovementReceipt,
+		Quantity:   line.Quantity,
+		Reason:     "purchase_order",
+		Reference:  fmt.Sprintf("PO-%d", orderID),
+		LotNumber:  line.LotNumber,
+		Expiration: &line.Expiration,
+	}
+}
+
+// ReceivePurchaseOrder books a delivery: every line is added to stock and the
+// order becomes partially received or received. The lines are stocked
+// together, and taken out again if the order cannot be saved, so stock and
+// order never disagree about what arrived.
+func (s *purchaseOrderService) ReceivePurchaseOrder(id int, receipt *domain.PurchaseOrderReceipt) (*domain.PurchaseOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, err := s.orderRepo.GetPurchaseOrderByID(id)
+	if err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	if err := checkPurchaseOrderAction(order, "receive"); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	if err := s.measureReceipt(receipt); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+	if err := validateReceipt(order, *receipt); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+
+	received := make(map[int]int, len(receipt.Lines))
+	movements := make([]*domain.StockMovement, 0, len(receipt.Lines))
+	for i := range receipt.Lines {
+		line := &receipt.Lines[i]
+		movements = append(movements, receiptMovement(id, line))
+		received[line.ProductID] += line.Quantity
+	}
+	if err := s.stockService.RecordMovements(movements...); err != nil {
+		return &domain.PurchaseOrder{}, err
+	}
+
+	complete := true
+	for i := range order.Lines {
+		order.Lines[i].QuantityReceived += received[order.Lines[i].ProductID]
+		if order.Lines[i].QuantityReceived < order.Lines[i].Quantity {
+			complete = false
+		}
+	}
+	order.Status = domain.PurchaseOrderPartiallyReceived
+	if complete {
+		order.Status = domain.PurchaseOrderReceived
+	}
+	order.UpdatedAt = s.now().UTC()
+	receipt.ReceivedAt = order.UpdatedAt
+	order.Receipts = append(order.Receipts, *receipt)
+	if err := s.orderRepo.UpdatePurchaseOrder(id, &order); err != nil {
+		if errUndo := s.stockService.ReverseMovements(movements...); errUndo != nil {
+			return &domain.PurchaseOrder{}, fmt.Errorf("%w; taking back stock failed: %v", err, errUndo)
+		}
+		return &domain.PurchaseOrder{}, err
+	}
+	return &order, nil
+}
@@ -0,0 +1,14 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type PurchaseOrderService interface {
+	GetAllPurchaseOrders(status string) ([]domain.PurchaseOrder, error)
+	GetPurchaseOrderByID(id int) (domain.PurchaseOrder, error)
+	CreatePurchaseOrder(order *domain.PurchaseOrder) (*domain.PurchaseOrder, error)
+	UpdatePurchaseOrder(id int, order *domain.PurchaseOrder) (*domain.PurchaseOrder, error)
+	DeletePurchaseOrder(id int) error
+	SubmitPurchaseOrder(id int) (*domain.PurchaseOrder, error)
+	ReceivePurchaseOrder(id int, receipt *domain.PurchaseOrderReceipt) (*domain.PurchaseOrder, error)
+	ClosePurchaseOrder(id int) (*domain.PurchaseOrder, error)
+}
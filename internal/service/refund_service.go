@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// ErrOverReturn is returned when more units are returned than were sold.
+var ErrOverReturn = errors.New("Returned quantity exceeds the sold quantity")
+
+type refundService struct {
+	saleRepo     repository.SaleRepository
+	refundRepo   repository.RefundRepository
+	stockService StockService
+	// mu serializes refunds so two returns of the same line cannot both pass
+	// the sold quantity check.
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func NewRefundService(saleRepo repository.SaleRepository, refundRepo repository.RefundRepository, stockService StockService) *refundService {
+	return &refundService{saleRepo: saleRepo, refundRepo: refundRepo, stockService: stockService, now: time.Now}
+}
+
+// returnedQuantities counts the units already returned and the amount
+// already refunded per receipt line.
+func returnedQuantities(refunds []domain.Refund) (map[int]int, map[int]domain.Money) {
+	returned := make(map[int]int)
+	refunded := make(map[int]domain.Money)
+	for _, refund := range refunds {
+		for _, line := range refund.Lines {
+			returned[line.Line] += line.Quantity
+			refunded[line.Line] = refunded[line.Line].Add(line.Amount)
+		}
+	}
+	return returned, refunded
+}
+
+// CreateRefund takes back units of a sale, or a measure of its weighed lines.
+// Restocked units go back on the shelf with a return movement; damaged units
+// are taken back and written off with a shrinkage movement. Each unit is refunded at what was paid for it;
+// the return that completes a line refunds whatever of the line is left, so
+// the refunds of a line add up to what was paid for it.
+func (s *refundService) CreateRefund(saleID int, refund *domain.Refund) (*domain.Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sale, err := s.saleRepo.GetSaleByID(saleID)
+	if err != nil {
+		return &domain.Refund{}, err
+	}
+	previous, err := s.refundRepo.GetRefundsBySale(saleID)
+	if err != nil {
+		return &domain.Refund{}, err
+	}
+	if len(refund.Lines) == 0 {
+		return &domain.Refund{}, errors.New("Refund needs at least one line")
+	}
+
+	returned, refunded := returnedQuantities(previous)
+	reference := fmt.Sprintf("SALE-%d", saleID)
+	var movements []*domain.StockMovement
+	refund.SaleID = saleID
+	refund.Total = domain.Money{}
+	for i := range refund.Lines {
+		line := &refund.Lines[i]
+		if line.Line < 1 || line.Line > len(sale.Lines) {
+			return &domain.Refund{}, fmt.Errorf("Sale %d has no line %d", saleID, line.Line)
+		}
+		sold := sale.Lines[line.Line-1]
+		quantity, err := measuredQuantity(sold.Unit, line.Quantity, line.Measure)
+		if err != nil {
+			return &domain.Refund{}, fmt.Errorf("Line %d: %w", line.Line, err)
+		}
+		if quantity <= 0 {
+			return &domain.Refund{}, fmt.Errorf("Line %d: quantity must be positive", line.Line)
+		}
+		line.Quantity, line.Measure = quantity, nil
+		if sold.Unit.Weighed() {
+			returnedMeasure := domain.MeasureOf(quantity, sold.Unit)
+			line.Measure = &returnedMeasure
+		}
+		if line.Disposition == "" {
+			line.Disposition = domain.ReturnRestock
+		}
+		if line.Disposition != domain.ReturnRestock && line.Disposition != domain.ReturnDamaged {
+			return &domain.Refund{}, fmt.Errorf("Line %d: disposition must be %s or %s", line.Line, domain.ReturnRestock, domain.ReturnDamaged)
+		}
+		returned[line.Line] += line.Quantity
+		if returned[line.Line] > sold.Quantity {
+			return &domain.Refund{}, fmt.Errorf("%w: line %d sold %d, %d returned", ErrOverReturn, line.Line, sold.Quantity, returned[line.Line])
+		}
+		line.ProductID = sold.ProductID
+		line.Amount = sold.Paid().MulRatio(int64(line.Quantity), int64(sold.Quantity))
+		if returned[line.Line] == sold.Quantity {
+			line.Amount = sold.Paid().Sub(refunded[line.Line])
+		}
+		refunded[line.Line] = refunded[line.Line].Add(line.Amount)
+		refund.Total = refund.Total.Add(line.Amount)
+
+		movements = append(movements, &domain.StockMovement{
+			ProductID: sold.ProductID,
+			Type:      domain.MovementReturn,
+			Quantity:  line.Quantity,
+			Reason:    "customer_return",
+			Reference: reference,
+		})
+		if line.Disposition == domain.ReturnDamaged {
+			movements = append(movements, &domain.StockMovement{
+				ProductID: sold.ProductID,
+				Type:      domain.MovementShrinkage,
+				Quantity:  line.Quantity,
+				Reason:    "damaged",
+				Reference: reference,
+			})
+		}
+	}
+
+	// The stock comes back before the refund is recorded; if recording it
+	// fails the stock is taken out again, so the units can still be
+	// returned.
+	if err := s.stockService.RecordMovements(movements...); err != nil {
+		return &domain.Refund{}, err
+	}
+	refund.CreatedAt = s.now().UTC()
+	created, err := s.refundRepo.CreateRefund(refund)
+	if err != nil {
+		if errUndo := s.stockService.ReverseMovements(movements...); errUndo != nil {
+			return &domain.Refund{}, fmt.Errorf("%w; taking back stock failed: %v", err, errUndo)
+		}
+		return &domain.Refund{}, err
+	}
+	return created, nil
+}
+
+func (s *refundService) GetRefundByID(id int) (domain.Refund, error) {
+	return s.refundRepo.GetRefundByID(id)
+}
+
+func (s *refundService) GetRefundsBySale(saleID int) ([]domain.Refund, error) {
+	if _, err := s.saleRepo.GetSaleByID(saleID); err != nil {
+		return nil, err
+	}
+	return s.refundRepo.GetRefundsBySale(saleID)
+}
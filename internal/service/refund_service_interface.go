@@ -0,0 +1,9 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type RefundService interface {
+	CreateRefund(saleID int, refund *domain.Refund) (*domain.Refund, error)
+	GetRefundByID(id int) (domain.Refund, error)
+	GetRefundsBySale(saleID int) ([]domain.Refund, error)
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// ReplenishmentSuggestion proposes reordering one product.
+type ReplenishmentSuggestion struct {
+	ProductID    int    `json:"product_id"`
+	Name         string `json:"name"`
+	CodeValue    string `json:"code_value"`
+	Quantity     int    `json:"quantity"`
+	OnOrder      int    `json:"on_order"`
+	MinStock     int    `json:"min_stock"`
+	ReorderPoint int    `json:"reorder_point"`
+	// Critical is set when the stock on hand is below the minimum stock.
+	Critical          bool         `json:"critical"`
+	SuggestedQuantity int          `json:"suggested_quantity"`
+	UnitCost          domain.Money `json:"unit_cost" swaggertype:"number"`
+	LeadTimeDays      int          `json:"lead_time_days"`
+}
+
+// SupplierSuggestions groups the suggestions for the cheapest supplier of each
+// product. Supplier is nil for products no supplier sells.
+type SupplierSuggestions struct {
+	Supplier  *domain.Supplier          `json:"supplier"`
+	Lines     []ReplenishmentSuggestion `json:"lines"`
+	TotalCost domain.Money              `json:"total_cost" swaggertype:"number"`
+}
+
+type replenishmentService struct {
+	productRepo          repository.ProductRepository
+	supplierService      SupplierService
+	purchaseOrderService PurchaseOrderService
+}
+
+func NewReplenishmentService(productRepo repository.ProductRepository, supplierService SupplierService, purchaseOrderService PurchaseOrderService) *replenishmentService {
+	return &replenishmentService{
+		productRepo:          productRepo,
+		supplierService:      supplierService,
+		purchaseOrderService: purchaseOrderService,
+	}
+}
+
+// reorderQuantity returns how many units of product to order given the stock
+// already on order, or 0 when the product does not need replenishing.
+func reorderQuantity(product domain.Product, onOrder int) int {
+	reorderPoint := product.ReorderPoint
+	if reorderPoint == 0 {
+		reorderPoint = product.MinStock
+	}
+	position := product.Quantity + onOrder
+	if reorderPoint == 0 || position > reorderPoint {
+		return 0
+	}
+	quantity := product.ReorderQuantity
+	if quantity == 0 {
+		quantity = reorderPoint
+	}
+	// One order must lift the stock above the reorder point, otherwise the
+	// product would be suggested again right after ordering.
+	return maxInt(quantity, reorderPoint-position+1)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// onOrder sums, per product, the units of open purchase orders that have not
+// arrived yet.
+func (s *replenishmentService) onOrder() (map[int]int, error) {
+	orders, err := s.purchaseOrderService.GetAllPurchaseOrders("")
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[int]int)
+	for _, order := range orders {
+		if order.Status == domain.PurchaseOrderReceived || order.Status == domain.PurchaseOrderClosed {
+			continue
+		}
+		for _, line := range order.Lines {
+			pending[line.ProductID] += line.Quantity - line.QuantityReceived
+		}
+	}
+	return pending, nil
+}
+
+// GetSuggestions scans the catalog for products at or below their reorder
+// point and groups them by their cheapest supplier. Groups are ordered by
+// supplier ID, with products without a supplier last.
+func (s *replenishmentService) GetSuggestions() ([]SupplierSuggestions, error) {
+	products, err := s.productRepo.GetAllProducts()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.onOrder()
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[int]*SupplierSuggestions)
+	for _, product := range products {
+		quantity := reorderQuantity(product, pending[product.ID])
+		if quantity == 0 {
+			continue
+		}
+		suggestion := ReplenishmentSuggestion{
+			ProductID:         product.ID,
+			Name:              product.Name,
+			CodeValue:         product.CodeValue,
+			Quantity:          product.Quantity,
+			OnOrder:           pending[product.ID],
+			MinStock:          product.MinStock,
+			ReorderPoint:      product.ReorderPoint,
+			Critical:          product.Quantity < product.MinStock,
+			SuggestedQuantity: quantity,
+		}
+		supplierID := 0
+		offer, err := s.supplierService.GetCheapestSupplier(product.ID)
+		if err == nil {
+			supplierID = offer.SupplierID
+			suggestion.UnitCost = offer.CostPrice
+			suggestion.LeadTimeDays = offer.LeadTimeDays
+		} else if !errors.Is(err, ErrNoSupplier) {
+			return nil, err
+		}
+		group, ok := groups[supplierID]
+		if !ok {
+			group = &SupplierSuggestions{Lines: []ReplenishmentSuggestion{}}
+			if supplierID != 0 {
+				supplier := offer.Supplier
+				group.Supplier = &supplier
+			}
+			groups[supplierID] = group
+		}
+		group.Lines = append(group.Lines, suggestion)
+		group.TotalCost = group.TotalCost.Add(unitOf(product).Cost(suggestion.UnitCost, quantity))
+	}
+
+	supplierIDs := make([]int, 0, len(groups))
+	for id := range groups {
+		supplierIDs = append(supplierIDs, id)
+	}
+	sort.Slice(supplierIDs, func(i, j int) bool {
+		switch {
+		case supplierIDs[i] == 0:
+			return false
+		case supplierIDs[j] == 0:
+			return true
+		}
+		return supplierIDs[i] < supplierIDs[j]
+	})
+	suggestions := make([]SupplierSuggestions, 0, len(groups))
+	for _, id := range supplierIDs {
+		suggestions = append(suggestions, *groups[id])
+	}
+	return suggestions, nil
+}
+
+// CreateDraftOrders turns every supplier group of the current suggestions
+// into a draft purchase order. Products without a supplier are left out.
+func (s *replenishmentService) CreateDraftOrders() ([]domain.PurchaseOrder, error) {
+	suggestions, err := s.GetSuggestions()
+	if err != nil {
+		return nil, err
+	}
+	orders := []domain.PurchaseOrder{}
+	for _, group := range suggestions {
+		if group.Supplier == nil {
+			continue
+		}
+		order := domain.PurchaseOrder{SupplierID: group.Supplier.ID}
+		for _, line := range group.Lines {
+			order.Lines = append(order.Lines, domain.PurchaseOrderLine{
+				ProductID: line.ProductID,
+				Quantity:  line.SuggestedQuantity,
+				UnitCost:  line.UnitCost,
+			})
+		}
+		created, err := s.purchaseOrderService.CreatePurchaseOrder(&order)
+		if err != nil {
+			return orders, err
+		}
+		orders = append(orders, *created)
+	}
+	return orders, nil
+}
@@ -0,0 +1,8 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type ReplenishmentService interface {
+	GetSuggestions() ([]SupplierSuggestions, error)
+	CreateDraftOrders() ([]domain.PurchaseOrder, error)
+}
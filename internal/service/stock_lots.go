@@ -0,0 +1,317 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// OpeningLotNumber names the lot created for the stock a product already had
+// when it started being tracked by lots.
+const OpeningLotNumber = "OPENING"
+
+// LotRecall describes where the units of a lot went.
+type LotRecall struct {
+	domain.Lot
+	ProductName string `json:"product_name"`
+	CodeValue   string `json:"code_value"`
+	// Received counts the units stock movements added to the lot, Sold the
+	// units that left it through sales. Quantity is what is still on the
+	// shelf.
+	Received  int                    `json:"received"`
+	Sold      int                    `json:"sold"`
+	Movements []domain.StockMovement `json:"movements"`
+}
+
+// sortFEFO orders lots first-expired-first-out: by expiration, then by
+// arrival, then by ID.
+func sortFEFO(lots []*domain.Lot) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		if c := lots[i].Expiration.Compare(lots[j].Expiration); c != 0 {
+			return c < 0
+		}
+		if c := lots[i].ReceivedAt.Compare(lots[j].ReceivedAt); c != 0 {
+			return c < 0
+		}
+		return lots[i].ID < lots[j].ID
+	})
+}
+
+// earliestExpiration returns the expiration of the first lot with stock, or
+// false when every lot is empty.
+func earliestExpiration(lots []*domain.Lot) (domain.Date, bool) {
+	var earliest domain.Date
+	found := false
+	for _, lot := range lots {
+		if lot.Quantity > 0 && (!found || lot.Expiration.Before(earliest)) {
+			earliest = lot.Expiration
+			found = true
+		}
+	}
+	return earliest, found
+}
+
+// allocateLots spreads movement over lots and returns the lots it changed or
+// created. Incoming stock goes to the lot named by the movement, a new lot
+// when the name is unknown, or else the first lot with stock (the one
+// expiring last if all are empty). Outgoing stock is taken from the named
+// lot or first-expired-first-out.
+func allocateLots(lots []*domain.Lot, movement *domain.StockMovement, today domain.Date) ([]*domain.Lot, error) {
+	sortFEFO(lots)
+	var named *domain.Lot
+	for _, lot := range lots {
+		if movement.LotNumber != "" && lot.LotNumber == movement.LotNumber {
+			named = lot
+		}
+	}
+	if named != nil && movement.Expiration != nil && movement.Expiration.Compare(named.Expiration) != 0 {
+		return nil, fmt.Errorf("Lot %s expires on %s", named.LotNumber, named.Expiration)
+	}
+	movement.Allocations = nil
+
+	if movement.Quantity > 0 {
+		target := named
+		switch {
+		case target == nil && movement.LotNumber != "":
+			if movement.Expiration == nil || !validExpiration(*movement.Expiration) {
+				return nil, fmt.Errorf("Expiration date is required for new lot %s", movement.LotNumber)
+			}
+			target = &domain.Lot{
+				ProductID:  movement.ProductID,
+				LotNumber:  movement.LotNumber,
+				Expiration: *movement.Expiration,
+				ReceivedAt: today,
+			}
+		case target == nil:
+			for _, lot := range lots {
+				if lot.Quantity > 0 {
+					target = lot
+					break
+				}
+			}
+			if target == nil {
+				target = lots[len(lots)-1]
+			}
+		}
+		target.Quantity += movement.Quantity
+		movement.Allocations = []domain.LotAllocation{{LotID: target.ID, LotNumber: target.LotNumber, Quantity: movement.Quantity}}
+		return []*domain.Lot{target}, nil
+	}
+
+	if movement.LotNumber != "" && named == nil {
+		return nil, fmt.Errorf("%w: product has no lot %s", repository.ErrLotNotFound, movement.LotNumber)
+	}
+	sources := lots
+	if named != nil {
+		sources = []*domain.Lot{named}
+	}
+	needed := -movement.Quantity
+	var changed []*domain.Lot
+	for _, lot := range sources {
+		if needed == 0 {
+			break
+		}
+		if lot.Quantity <= 0 {
+			continue
+		}
+		taken := minInt(lot.Quantity, needed)
+		lot.Quantity -= taken
+		needed -= taken
+		changed = append(changed, lot)
+		movement.Allocations = append(movement.Allocations, domain.LotAllocation{LotID: lot.ID, LotNumber: lot.LotNumber, Quantity: -taken})
+	}
+	if needed > 0 {
+		return nil, fmt.Errorf("%w: lots are %d units short", ErrInsufficientStock, needed)
+	}
+	return changed, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// lotChanges collects the lots to save, each once, in the order they were
+// first changed.
+type lotChanges struct {
+	lots []*domain.Lot
+	seen map[*domain.Lot]bool
+}
+
+func (c *lotChanges) add(lot *domain.Lot) {
+	if c.seen == nil {
+		c.seen = make(map[*domain.Lot]bool)
+	}
+	if !c.seen[lot] {
+		c.seen[lot] = true
+		c.lots = append(c.lots, lot)
+	}
+}
+
+// trackLots applies movement to the product's lots, if it has any or the
+// movement names one. The product's existing stock becomes an opening lot the
+// first time it is tracked.
+func (s *stockService) trackLots(stock *productStock, movement *domain.StockMovement, changes *lotChanges) error {
+	if len(stock.lots) == 0 && movement.LotNumber == "" {
+		return nil
+	}
+	today := domain.DateOf(s.now())
+	if len(stock.lots) == 0 && stock.balance > 0 {
+		opening := &domain.Lot{
+			ProductID:  stock.product.ID,
+			LotNumber:  OpeningLotNumber,
+			Quantity:   stock.balance,
+			Expiration: stock.product.Expiration,
+			ReceivedAt: today,
+		}
+		stock.lots = append(stock.lots, opening)
+		changes.add(opening)
+	}
+	allocated, err := allocateLots(stock.lots, movement, today)
+	if err != nil {
+		return err
+	}
+	for _, lot := range allocated {
+		changes.add(lot)
+		if !containsLot(stock.lots, lot) {
+			stock.lots = append(stock.lots, lot)
+		}
+	}
+	return nil
+}
+
+func containsLot(lots []*domain.Lot, lot *domain.Lot) bool {
+	for _, candidate := range lots {
+		if candidate == lot {
+			return true
+		}
+	}
+	return false
+}
+
+// syncExpiration gives a lot tracked product the expiration of its first
+// lot with stock. Products whose lots are all empty keep their date.
+func syncExpiration(product *domain.Product, lots []*domain.Lot) {
+	if expiration, ok := earliestExpiration(lots); ok {
+		product.Expiration = expiration
+	}
+}
+
+// GetLots lists a product's lots first-expired-first-out.
+func (s *stockService) GetLots(productID int) ([]domain.Lot, error) {
+	if _, err := s.productRepo.GetProductByID(productID); err != nil {
+		return nil, err
+	}
+	stored, err := s.lotRepo.GetLotsByProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	lots := make([]*domain.Lot, 0, len(stored))
+	for i := range stored {
+		lots = append(lots, &stored[i])
+	}
+	sortFEFO(lots)
+	sorted := make([]domain.Lot, 0, len(lots))
+	for _, lot := range lots {
+		sorted = append(sorted, *lot)
+	}
+	return sorted, nil
+}
+
+// UpdateLot corrects the number, expiration or arrival day of a lot. Its
+// quantity only changes through stock movements.
+func (s *stockService) UpdateLot(productID int, lotID int, lot *domain.Lot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return err
+	}
+	stored, err := s.lotRepo.GetLotsByProduct(productID)
+	if err != nil {
+		return err
+	}
+	lot.LotNumber = strings.TrimSpace(lot.LotNumber)
+	if lot.LotNumber == "" {
+		return errors.New("Lot number is required")
+	}
+	if !validExpiration(lot.Expiration) {
+		return errors.New("Expiration date is required")
+	}
+	var current *domain.Lot
+	lots := make([]*domain.Lot, 0, len(stored))
+	for i := range stored {
+		if stored[i].ID == lotID {
+			current = &stored[i]
+		} else if stored[i].LotNumber == lot.LotNumber {
+			return fmt.Errorf("Lot number %s already exists", lot.LotNumber)
+		}
+		lots = append(lots, &stored[i])
+	}
+	if current == nil {
+		return repository.ErrLotNotFound
+	}
+	current.LotNumber = lot.LotNumber
+	current.Expiration = lot.Expiration
+	if !lot.ReceivedAt.IsZero() {
+		current.ReceivedAt = lot.ReceivedAt
+	}
+	if err := s.lotRepo.SaveLots(current); err != nil {
+		return err
+	}
+	*lot = *current
+	expiration := product.Expiration
+	syncExpiration(&product, lots)
+	if product.Expiration.Compare(expiration) == 0 {
+		return nil
+	}
+	return s.productRepo.UpdateProduct(product.ID, &product)
+}
+
+// RecallLots finds every lot with the given number, optionally only for one
+// product, together with the movements that touched it.
+func (s *stockService) RecallLots(lotNumber string, productID int) ([]LotRecall, error) {
+	if strings.TrimSpace(lotNumber) == "" {
+		return nil, fmt.Errorf("%w: lot_number is required", ErrInvalidQuery)
+	}
+	lots, err := s.lotRepo.GetAllLots()
+	if err != nil {
+		return nil, err
+	}
+	movements, err := s.movementRepo.GetAllMovements()
+	if err != nil {
+		return nil, err
+	}
+	recalls := []LotRecall{}
+	for _, lot := range lots {
+		if lot.LotNumber != lotNumber || (productID != 0 && lot.ProductID != productID) {
+			continue
+		}
+		recall := LotRecall{Lot: lot, Movements: []domain.StockMovement{}}
+		if product, err := s.productRepo.GetProductByID(lot.ProductID); err == nil {
+			recall.ProductName = product.Name
+			recall.CodeValue = product.CodeValue
+		}
+		for _, movement := range movements {
+			for _, allocation := range movement.Allocations {
+				if allocation.LotID != lot.ID {
+					continue
+				}
+				if allocation.Quantity > 0 {
+					recall.Received += allocation.Quantity
+				} else if movement.Type == domain.MovementSale {
+					recall.Sold -= allocation.Quantity
+				}
+				recall.Movements = append(recall.Movements, movement)
+			}
+		}
+		recalls = append(recalls, recall)
+	}
+	return recalls, nil
+}
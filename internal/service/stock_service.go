@@ -0,0 +1,403 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+var ErrInsufficientStock = errors.New("Insufficient stock")
+
+// Reason codes recorded by the system itself.
+const (
+	ReasonOpeningBalance = "opening_balance"
+	ReasonManualEdit     = "manual_edit"
+	ReasonReversal       = "reversal"
+)
+
+type movementRule struct {
+	// sign is +1 or -1 when the direction is fixed by the type, 0 when the
+	// caller gives a signed quantity.
+	sign    int
+	reasons []string
+	// defaultReason is used when no reason is given; empty means required.
+	defaultReason string
+}
+
+var movementRules = map[domain.MovementType]movementRule{
+	domain.MovementReceipt:    {sign: 1, reasons: []string{"supplier_delivery", "purchase_order"}, defaultReason: "supplier_delivery"},
+	domain.MovementSale:       {sign: -1, reasons: []string{"pos_sale"}, defaultReason: "pos_sale"},
+	domain.MovementAdjustment: {sign: 0, reasons: []string{ReasonOpeningBalance, "count_correction", ReasonManualEdit, ReasonReversal}},
+	domain.MovementShrinkage:  {sign: -1, reasons: []string{"damaged", "expired", "theft", "spoilage"}},
+	domain.MovementReturn:     {sign: 1, reasons: []string{"customer_return", "restock"}, defaultReason: "customer_return"},
+	domain.MovementTransfer:   {sign: 0, reasons: []string{"transfer_in", "transfer_out"}},
+}
+
+// Reconciliation reports a product whose stored quantity did not match its
+// ledger and was corrected, or whose lots do not add up to its ledger.
+// LotQuantity is only set in the second case; lots are left as they are,
+// since which of them is wrong takes a stock count to tell.
+type Reconciliation struct {
+	ProductID   int  `json:"product_id"`
+	Before      int  `json:"before"`
+	After       int  `json:"after"`
+	LotQuantity *int `json:"lot_quantity,omitempty"`
+}
+
+type stockService struct {
+	productRepo  repository.ProductRepository
+	movementRepo repository.StockMovementRepository
+	lotRepo      repository.LotRepository
+	// mu serializes ledger writes so balances are computed from a stable
+	// history.
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func NewStockService(productRepo repository.ProductRepository, movementRepo repository.StockMovementRepository, lotRepo repository.LotRepository) *stockService {
+	return &stockService{productRepo: productRepo, movementRepo: movementRepo, lotRepo: lotRepo, now: time.Now}
+}
+
+// normalizeMovement validates the movement and turns its quantity into the
+// signed change in stock.
+func normalizeMovement(movement *domain.StockMovement) error {
+	rule, ok := movementRules[movement.Type]
+	if !ok {
+		return fmt.Errorf("Unknown movement type %q", movement.Type)
+	}
+	if movement.Quantity == 0 {
+		return errors.New("Movement quantity must not be zero")
+	}
+	if rule.sign != 0 {
+		if movement.Quantity < 0 {
+			return fmt.Errorf("Quantity for %s movements must be positive", movement.Type)
+		}
+		movement.Quantity *= rule.sign
+	}
+	if movement.Type == domain.MovementTransfer && movement.Reason == "" {
+		movement.Reason = "transfer_in"
+		if movement.Quantity < 0 {
+			movement.Reason = "transfer_out"
+		}
+	}
+	if movement.Reason == "" {
+		movement.Reason = rule.defaultReason
+	}
+	if movement.Reason == "" {
+		return fmt.Errorf("Reason is required for %s movements, one of: %s", movement.Type, strings.Join(rule.reasons, ", "))
+	}
+	for _, reason := range rule.reasons {
+		if reason == movement.Reason {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid reason %q for %s movements, one of: %s", movement.Reason, movement.Type, strings.Join(rule.reasons, ", "))
+}
+
+func ledgerBalance(movements []domain.StockMovement) int {
+	balance := 0
+	for _, movement := range movements {
+		balance += movement.Quantity
+	}
+	return balance
+}
+
+// openingBalance records the stock a product had before it had a ledger.
+func (s *stockService) openingBalance(product domain.Product) *domain.StockMovement {
+	return &domain.StockMovement{
+		ProductID: product.ID,
+		Type:      domain.MovementAdjustment,
+		Quantity:  product.Quantity,
+		Reason:    ReasonOpeningBalance,
+		Balance:   product.Quantity,
+		CreatedAt: s.now().UTC(),
+	}
+}
+
+// setQuantity stores the ledger balance on the product.
+func (s *stockService) setQuantity(product domain.Product, quantity int) error {
+	if product.Quantity == quantity {
+		return nil
+	}
+	product.Quantity = quantity
+	return s.productRepo.UpdateProduct(product.ID, &product)
+}
+
+// productStock is the stock of one product while a batch of movements is
+// being recorded.
+type productStock struct {
+	product domain.Product
+	balance int
+	lots    []*domain.Lot
+	// stored holds the lots as they were loaded, by ID, to put them back
+	// if the batch cannot be recorded.
+	stored map[int]domain.Lot
+}
+
+// loadStock reads a product's stock, queueing an opening balance in pending
+// when the product has stock but no ledger yet.
+func (s *stockService) loadStock(productID int, pending *[]*domain.StockMovement) (*productStock, error) {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.movementRepo.GetMovementsByProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	stock := &productStock{product: product, balance: ledgerBalance(history), stored: make(map[int]domain.Lot)}
+	if len(history) == 0 && product.Quantity != 0 {
+		opening := s.openingBalance(product)
+		*pending = append(*pending, opening)
+		stock.balance = opening.Balance
+	}
+	stored, err := s.lotRepo.GetLotsByProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stored {
+		stock.stored[stored[i].ID] = stored[i]
+		stock.lots = append(stock.lots, &stored[i])
+	}
+	return stock, nil
+}
+
+// restoreLots puts lots changed by a batch whose ledger could not be written
+// back as they were loaded, and removes the ones the batch created.
+func (s *stockService) restoreLots(stocks map[int]*productStock, changed []*domain.Lot) error {
+	var restored []*domain.Lot
+	var created []int
+	for _, lot := range changed {
+		if stored, ok := stocks[lot.ProductID].stored[lot.ID]; ok {
+			restored = append(restored, &stored)
+		} else {
+			created = append(created, lot.ID)
+		}
+	}
+	if len(restored) > 0 {
+		if err := s.lotRepo.SaveLots(restored...); err != nil {
+			return err
+		}
+	}
+	if len(created) > 0 {
+		return s.lotRepo.DeleteLots(created...)
+	}
+	return nil
+}
+
+// RecordMovement records a movement requested for a product, whose quantity
+// is given as a measure when the product is sold by weight or volume.
+func (s *stockService) RecordMovement(productID int, movement *domain.StockMovement) (*domain.StockMovement, error) {
+	product, err := s.productRepo.GetProductByID(productID)
+	if err != nil {
+		return &domain.StockMovement{}, err
+	}
+	measure := movement.Measure
+	movement.Measure = nil
+	if movement.Quantity, err = measuredQuantity(unitOf(product), movement.Quantity, measure); err != nil {
+		return &domain.StockMovement{}, err
+	}
+	movement.ProductID = productID
+	if err := s.RecordMovements(movement); err != nil {
+		return &domain.StockMovement{}, err
+	}
+	return movement, nil
+}
+
+// RecordMovements records several movements, each for the product in its
+// ProductID, as a unit: when one of them is invalid or would take a product
+// below zero, none is recorded.
+func (s *stockService) RecordMovements(movements ...*domain.StockMovement) error {
+	for _, movement := range movements {
+		if err := normalizeMovement(movement); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []*domain.StockMovement
+	var changes lotChanges
+	stocks := make(map[int]*productStock)
+	var order []*productStock
+	for _, movement := range movements {
+		stock, ok := stocks[movement.ProductID]
+		if !ok {
+			var err error
+			if stock, err = s.loadStock(movement.ProductID, &pending); err != nil {
+				return err
+			}
+			stocks[movement.ProductID] = stock
+			order = append(order, stock)
+		}
+		if stock.balance+movement.Quantity < 0 {
+			return fmt.Errorf("%w: product %d has %d in stock, movement needs %d", ErrInsufficientStock, movement.ProductID, stock.balance, -movement.Quantity)
+		}
+		if err := s.trackLots(stock, movement, &changes); err != nil {
+			return err
+		}
+		stock.balance += movement.Quantity
+		movement.Balance = stock.balance
+		movement.CreatedAt = s.now().UTC()
+		pending = append(pending, movement)
+	}
+
+	if len(changes.lots) > 0 {
+		if err := s.lotRepo.SaveLots(changes.lots...); err != nil {
+			return err
+		}
+		// New lots only get their ID when saved.
+		for _, movement := range movements {
+			for i, allocation := range movement.Allocations {
+				for _, lot := range changes.lots {
+					if allocation.LotID == 0 && lot.ProductID == movement.ProductID && lot.LotNumber == allocation.LotNumber {
+						movement.Allocations[i].LotID = lot.ID
+					}
+				}
+			}
+		}
+	}
+	// Lots and the ledger are written first; if updating a product fails its
+	// quantity can be rebuilt from the ledger with Reconcile. Lots need the
+	// ledger, so they are put back when it cannot be written.
+	if err := s.movementRepo.AppendMovements(pending...); err != nil {
+		if errRestore := s.restoreLots(stocks, changes.lots); errRestore != nil {
+			return fmt.Errorf("%w; restoring lots failed: %v", err, errRestore)
+		}
+		return err
+	}
+	for _, stock := range order {
+		product := stock.product
+		if len(stock.lots) > 0 {
+			syncExpiration(&product, stock.lots)
+		}
+		if product.Quantity == stock.balance && product.Expiration.Compare(stock.product.Expiration) == 0 {
+			continue
+		}
+		product.Quantity = stock.balance
+		if err := s.productRepo.UpdateProduct(product.ID, &product); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReverseMovements undoes movements recorded earlier by recording the
+// opposite adjustments, each against the lot the original was allocated to.
+// Callers use it to back out stock when a write that had to go with the
+// movements fails.
+func (s *stockService) ReverseMovements(movements ...*domain.StockMovement) error {
+	var reversals []*domain.StockMovement
+	for _, movement := range movements {
+		reversal := domain.StockMovement{
+			ProductID: movement.ProductID,
+			Type:      domain.MovementAdjustment,
+			Quantity:  -movement.Quantity,
+			Reason:    ReasonReversal,
+			Reference: movement.Reference,
+		}
+		if len(movement.Allocations) == 0 {
+			reversals = append(reversals, &reversal)
+			continue
+		}
+		for _, allocation := range movement.Allocations {
+			lotReversal := reversal
+			lotReversal.Quantity = -allocation.Quantity
+			lotReversal.LotNumber = allocation.LotNumber
+			reversals = append(reversals, &lotReversal)
+		}
+	}
+	if len(reversals) == 0 {
+		return nil
+	}
+	return s.RecordMovements(reversals...)
+}
+
+func (s *stockService) GetMovements(productID int) ([]domain.StockMovement, error) {
+	if _, err := s.productRepo.GetProductByID(productID); err != nil {
+		return nil, err
+	}
+	return s.movementRepo.GetMovementsByProduct(productID)
+}
+
+// OpenLedger records the initial stock of a newly created product.
+func (s *stockService) OpenLedger(product domain.Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history, err := s.movementRepo.GetMovementsByProduct(product.ID)
+	if err != nil {
+		return err
+	}
+	if len(history) > 0 || product.Quantity == 0 {
+		return nil
+	}
+	return s.movementRepo.AppendMovements(s.openingBalance(product))
+}
+
+// Reconcile rebuilds every product's quantity from its ledger. Products that
+// have no ledger yet get an opening balance with their current quantity, so
+// nothing is lost the first time it runs. Products tracked by lots whose lots
+// do not add up to the ledger are reported too.
+func (s *stockService) Reconcile() ([]Reconciliation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	products, err := s.productRepo.GetAllProducts()
+	if err != nil {
+		return nil, err
+	}
+	movements, err := s.movementRepo.GetAllMovements()
+	if err != nil {
+		return nil, err
+	}
+	lots, err := s.lotRepo.GetAllLots()
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[int]int)
+	hasLedger := make(map[int]bool)
+	for _, movement := range movements {
+		balances[movement.ProductID] += movement.Quantity
+		hasLedger[movement.ProductID] = true
+	}
+	lotQuantities := make(map[int]int)
+	hasLots := make(map[int]bool)
+	for _, lot := range lots {
+		lotQuantities[lot.ProductID] += lot.Quantity
+		hasLots[lot.ProductID] = true
+	}
+
+	var openings []*domain.StockMovement
+	corrections := []Reconciliation{}
+	for _, product := range products {
+		balance := product.Quantity
+		if !hasLedger[product.ID] {
+			if product.Quantity != 0 {
+				openings = append(openings, s.openingBalance(product))
+			}
+		} else {
+			balance = balances[product.ID]
+		}
+		correction := Reconciliation{ProductID: product.ID, Before: product.Quantity, After: balance}
+		if lotQuantity := lotQuantities[product.ID]; hasLots[product.ID] && lotQuantity != balance {
+			correction.LotQuantity = &lotQuantity
+		}
+		if balance == product.Quantity && correction.LotQuantity == nil {
+			continue
+		}
+		corrections = append(corrections, correction)
+		if err := s.setQuantity(product, balance); err != nil {
+			return corrections, err
+		}
+	}
+	if len(openings) > 0 {
+		if err := s.movementRepo.AppendMovements(openings...); err != nil {
+			return corrections, err
+		}
+	}
+	return corrections, nil
+}
@@ -0,0 +1,15 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type StockService interface {
+	RecordMovement(productID int, movement *domain.StockMovement) (*domain.StockMovement, error)
+	RecordMovements(movements ...*domain.StockMovement) error
+	ReverseMovements(movements ...*domain.StockMovement) error
+	GetMovements(productID int) ([]domain.StockMovement, error)
+	OpenLedger(product domain.Product) error
+	Reconcile() ([]Reconciliation, error)
+	GetLots(productID int) ([]domain.Lot, error)
+	UpdateLot(productID int, lotID int, lot *domain.Lot) error
+	RecallLots(lotNumber string, productID int) ([]LotRecall, error)
+}
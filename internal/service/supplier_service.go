@@ -0,0 +1,220 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/NPG27/supermarket_dop/internal/repository"
+)
+
+// ErrNoSupplier is returned when a product is not sold by any supplier.
+var ErrNoSupplier = errors.New("Product has no suppliers")
+
+// ProductSupplier is a supplier's offer for a product together with the
+// supplier itself.
+type ProductSupplier struct {
+	domain.SupplierProduct
+	Supplier domain.Supplier `json:"supplier"`
+}
+
+type supplierService struct {
+	supplierRepo repository.SupplierRepository
+	linkRepo     repository.SupplierProductRepository
+	productRepo  repository.ProductRepository
+}
+
+func NewSupplierService(supplierRepo repository.SupplierRepository, linkRepo repository.SupplierProductRepository, productRepo repository.ProductRepository) *supplierService {
+	return &supplierService{supplierRepo: supplierRepo, linkRepo: linkRepo, productRepo: productRepo}
+}
+
+func validateSupplier(supplier *domain.Supplier) error {
+	supplier.Name = strings.TrimSpace(supplier.Name)
+	if supplier.Name == "" {
+		return errors.New("Supplier name is required")
+	}
+	return nil
+}
+
+func validateSupplierProduct(link domain.SupplierProduct) error {
+	if link.CostPrice.Sign() <= 0 {
+		return errors.New("Cost price must be positive")
+	}
+	if link.LeadTimeDays < 0 {
+		return errors.New("Lead time must not be negative")
+	}
+	return nil
+}
+
+func (s *supplierService) GetAllSuppliers() ([]domain.Supplier, error) {
+	return s.supplierRepo.GetAllSuppliers()
+}
+
+func (s *supplierService) GetSupplierByID(id int) (domain.Supplier, error) {
+	return s.supplierRepo.GetSupplierByID(id)
+}
+
+func (s *supplierService) CreateSupplier(supplier *domain.Supplier) (*domain.Supplier, error) {
+	if err := validateSupplier(supplier); err != nil {
+		return &domain.Supplier{}, err
+	}
+	return s.supplierRepo.CreateSupplier(supplier)
+}
+
+func (s *supplierService) UpdateSupplier(id int, supplier *domain.Supplier) error {
+	if err := validateSupplier(supplier); err != nil {
+		return err
+	}
+	return s.supplierRepo.UpdateSupplier(id, supplier)
+}
+
+// DeleteSupplier removes the supplier and every product link it had.
+func (s *supplierService) DeleteSupplier(id int) error {
+	if err := s.supplierRepo.DeleteSupplier(id); err != nil {
+		return err
+	}
+	return s.linkRepo.DeleteSupplierProductsBySupplier(id)
+}
+
+// GetSupplierProducts lists what a supplier sells, skipping links to products
+// that were deleted from the catalog since.
+func (s *supplierService) GetSupplierProducts(supplierID int) ([]domain.SupplierProduct, error) {
+	if _, err := s.supplierRepo.GetSupplierByID(supplierID); err != nil {
+		return nil, err
+	}
+	links, err := s.linkRepo.GetSupplierProductsBySupplier(supplierID)
+	if err != nil {
+		return nil, err
+	}
+	existing := []domain.SupplierProduct{}
+	for _, link := range links {
+		if _, err := s.productRepo.GetProductByID(link.ProductID); err == nil {
+			existing = append(existing, link)
+		}
+	}
+	return existing, nil
+}
+
+func (s *supplierService) LinkProduct(supplierID int, link *domain.SupplierProduct) (*domain.SupplierProduct, error) {
+	if _, err := s.supplierRepo.GetSupplierByID(supplierID); err != nil {
+		return &domain.SupplierProduct{}, err
+	}
+	if _, err := s.productRepo.GetProductByID(link.ProductID); err != nil {
+		return &domain.SupplierProduct{}, err
+	}
+	if err := validateSupplierProduct(*link); err != nil {
+		return &domain.SupplierProduct{}, err
+	}
+	link.SupplierID = supplierID
+	return s.linkRepo.CreateSupplierProduct(link)
+}
+
+func (s *supplierService) findLink(supplierID int, productID int) (domain.SupplierProduct, error) {
+	links, err := s.linkRepo.GetSupplierProductsBySupplier(supplierID)
+	if err != nil {
+		return domain.SupplierProduct{}, err
+	}
+	for _, link := range links {
+		if link.ProductID == productID {
+			return link, nil
+		}
+	}
+	return domain.SupplierProduct{}, repository.ErrSupplierProductNotFound
+}
+
+func (s *supplierService) UpdateLink(supplierID int, productID int, link *domain.SupplierProduct) error {
+	current, err := s.findLink(supplierID, productID)
+	if err != nil {
+		return err
+	}
+	if err := validateSupplierProduct(*link); err != nil {
+		return err
+	}
+	link.SupplierID = supplierID
+	link.ProductID = productID
+	return s.linkRepo.UpdateSupplierProduct(current.ID, link)
+}
+
+func (s *supplierService) UnlinkProduct(supplierID int, productID int) error {
+	current, err := s.findLink(supplierID, productID)
+	if err != nil {
+		return err
+	}
+	return s.linkRepo.DeleteSupplierProduct(current.ID)
+}
+
+// GetProductSuppliers lists every supplier offer for a product, cheapest
+// first.
+func (s *supplierService) GetProductSuppliers(productID int) ([]ProductSupplier, error) {
+	if _, err := s.productRepo.GetProductByID(productID); err != nil {
+		return nil, err
+	}
+	links, err := s.linkRepo.GetSupplierProductsByProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	offers := make([]ProductSupplier, 0, len(links))
+	for _, link := range links {
+		supplier, err := s.supplierRepo.GetSupplierByID(link.SupplierID)
+		if errors.Is(err, repository.ErrSupplierNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		offers = append(offers, ProductSupplier{SupplierProduct: link, Supplier: supplier})
+	}
+	sortOffers(offers, byCost)
+	return offers, nil
+}
+
+type offerOrder func(a, b ProductSupplier) int
+
+func byCost(a, b ProductSupplier) int {
+	if c := a.CostPrice.Compare(b.CostPrice); c != 0 {
+		return c
+	}
+	return compareInts(a.LeadTimeDays, b.LeadTimeDays)
+}
+
+func byLeadTime(a, b ProductSupplier) int {
+	if c := compareInts(a.LeadTimeDays, b.LeadTimeDays); c != 0 {
+		return c
+	}
+	return a.CostPrice.Compare(b.CostPrice)
+}
+
+// sortOffers orders offers by order, falling back to the supplier ID so ties
+// always resolve the same way.
+func sortOffers(offers []ProductSupplier, order offerOrder) {
+	sort.Slice(offers, func(i, j int) bool {
+		if c := order(offers[i], offers[j]); c != 0 {
+			return c < 0
+		}
+		return offers[i].SupplierID < offers[j].SupplierID
+	})
+}
+
+func (s *supplierService) bestOffer(productID int, order offerOrder) (ProductSupplier, error) {
+	offers, err := s.GetProductSuppliers(productID)
+	if err != nil {
+		return ProductSupplier{}, err
+	}
+	if len(offers) == 0 {
+		return ProductSupplier{}, ErrNoSupplier
+	}
+	sortOffers(offers, order)
+	return offers[0], nil
+}
+
+// GetCheapestSupplier returns the lowest cost offer, the shortest lead time
+// winning ties.
+func (s *supplierService) GetCheapestSupplier(productID int) (ProductSupplier, error) {
+	return s.bestOffer(productID, byCost)
+}
+
+// GetFastestSupplier returns the offer with the shortest lead time, the lowest
+// cost winning ties.
+func (s *supplierService) GetFastestSupplier(productID int) (ProductSupplier, error) {
+	return s.bestOffer(productID, byLeadTime)
+}
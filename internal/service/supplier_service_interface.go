@@ -0,0 +1,18 @@
+package service
+
+import "github.com/NPG27/supermarket_dop/internal/domain"
+
+type SupplierService interface {
+	GetAllSuppliers() ([]domain.Supplier, error)
+	GetSupplierByID(id int) (domain.Supplier, error)
+	CreateSupplier(supplier *domain.Supplier) (*domain.Supplier, error)
+	UpdateSupplier(id int, supplier *domain.Supplier) error
+	DeleteSupplier(id int) error
+	GetSupplierProducts(supplierID int) ([]domain.SupplierProduct, error)
+	LinkProduct(supplierID int, link *domain.SupplierProduct) (*domain.SupplierProduct, error)
+	UpdateLink(supplierID int, productID int, link *domain.SupplierProduct) error
+	UnlinkProduct(supplierID int, productID int) error
+	GetProductSuppliers(productID int) ([]ProductSupplier, error)
+	GetCheapestSupplier(productID int) (ProductSupplier, error)
+	GetFastestSupplier(productID int) (ProductSupplier, error)
+}
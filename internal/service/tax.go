@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+)
+
+// TaxPolicy holds the rate of each tax class, in percent, and whether list
+// prices already include tax.
+type TaxPolicy struct {
+	StandardRate     float64
+	ReducedRate      float64
+	PricesIncludeTax bool
+}
+
+// DefaultTaxPolicy taxes at 21% and 10.5% with tax-inclusive prices.
+func DefaultTaxPolicy() TaxPolicy {
+	return TaxPolicy{StandardRate: 21, ReducedRate: 10.5, PricesIncludeTax: true}
+}
+
+// validateTaxClass accepts the known classes and empty, which means standard.
+func validateTaxClass(class domain.TaxClass) error {
+	switch class {
+	case "", domain.TaxStandard, domain.TaxReduced, domain.TaxExempt:
+		return nil
+	}
+	return fmt.Errorf("Invalid tax class %q, one of: %s, %s, %s", class, domain.TaxStandard, domain.TaxReduced, domain.TaxExempt)
+}
+
+// TaxCalculator works out the tax in prices and sales. Amounts are split into
+// net and tax exactly, so net plus tax always equals gross.
+type TaxCalculator struct {
+	policy TaxPolicy
+}
+
+func NewTaxCalculator(policy TaxPolicy) *TaxCalculator {
+	return &TaxCalculator{policy: policy}
+}
+
+// classOf resolves the class a product is taxed under.
+func classOf(class domain.TaxClass) domain.TaxClass {
+	if class == "" {
+		return domain.TaxStandard
+	}
+	return class
+}
+
+// Rate returns the rate of class, in percent.
+func (c *TaxCalculator) Rate(class domain.TaxClass) float64 {
+	switch classOf(class) {
+	case domain.TaxReduced:
+		return c.policy.ReducedRate
+	case domain.TaxExempt:
+		return 0
+	}
+	return c.policy.StandardRate
+}
+
+// split divides an amount in the price mode of the policy into net and tax.
+func (c *TaxCalculator) split(amount domain.Money, rate float64) (net domain.Money, tax domain.Money) {
+	if c.policy.PricesIncludeTax {
+		// net = amount / (1 + rate/100) = amount * 100 / (100 + rate)
+		factor := new(big.Rat).Quo(big.NewRat(100, 1), new(big.Rat).Add(big.NewRat(100, 1), domain.ExactRat(rate)))
+		net = amount.MulRat(factor)
+		return net, amount.Sub(net)
+	}
+	return amount, amount.Percent(rate)
+}
+
+// PriceProduct fills in the tax of a priced product's effective price.
+func (c *TaxCalculator) PriceProduct(priced *domain.PricedProduct) {
+	priced.TaxRate = c.Rate(priced.TaxClass)
+	net, tax := c.split(priced.EffectivePrice, priced.TaxRate)
+	priced.NetPrice = net
+	priced.TaxAmount = tax
+	priced.GrossPrice = net.Add(tax)
+}
+
+// TaxSale taxes the lines of sale from their totals and tax classes. The tax
+// of each class is rounded once, on the sum of its lines, and then shared out
+// over the lines in proportion to their totals, so the line taxes add up to
+// the breakdown. In tax-exclusive mode the tax is added to the sale total.
+func (c *TaxCalculator) TaxSale(sale *domain.Sale) {
+	sale.PricesIncludeTax = c.policy.PricesIncludeTax
+	sale.Taxes = []domain.TaxBreakdown{}
+	sale.Tax = domain.Money{}
+	byClass := make(map[domain.TaxClass][]int)
+	for i := range sale.Lines {
+		class := classOf(sale.Lines[i].TaxClass)
+		sale.Lines[i].TaxClass = class
+		sale.Lines[i].TaxRate = c.Rate(class)
+		byClass[class] = append(byClass[class], i)
+	}
+
+	for _, class := range []domain.TaxClass{domain.TaxStandard, domain.TaxReduced, domain.TaxExempt} {
+		indexes := byClass[class]
+		if len(indexes) == 0 {
+			continue
+		}
+		rate := c.Rate(class)
+		var amount domain.Money
+		weights := make([]int64, len(indexes))
+		for k, i := range indexes {
+			amount = amount.Add(sale.Lines[i].Total)
+			weights[k] = sale.Lines[i].Total.Cents()
+		}
+		net, tax := c.split(amount, rate)
+		for k, share := range tax.Allocate(weights) {
+			line := &sale.Lines[indexes[k]]
+			line.Tax = share
+			line.Gross = line.Total
+			if !c.policy.PricesIncludeTax {
+				line.Gross = line.Total.Add(share)
+			}
+		}
+		sale.Tax = sale.Tax.Add(tax)
+		sale.Taxes = append(sale.Taxes, domain.TaxBreakdown{
+			Class: class,
+			Rate:  rate,
+			Net:   net,
+			Tax:   tax,
+			Gross: net.Add(tax),
+		})
+	}
+	if !c.policy.PricesIncludeTax {
+		sale.Total = sale.Total.Add(sale.Tax)
+	}
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Collection persists a list of records in a JSON file together with the
+// highest ID ever handed out. It offers the same guarantees as the product
+// JSON store: mutations are serialized and the file is replaced atomically.
+type Collection[T any] struct {
+	pathToFile string
+	mu         sync.RWMutex
+}
+
+type collectionFile[T any] struct {
+	LastID int `json:"last_id"`
+	Items  []T `json:"items"`
+}
+
+func NewCollection[T any](path string) *Collection[T] {
+	return &Collection[T]{pathToFile: path}
+}
+
+// load returns an empty collection when the file does not exist yet.
+func (c *Collection[T]) load() (collectionFile[T], error) {
+	file := collectionFile[T]{Items: []T{}}
+	bytes, err := os.ReadFile(c.pathToFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, err
+	}
+	if err := json.Unmarshal(bytes, &file); err != nil {
+		return file, fmt.Errorf("%w: %s: %v", ErrCorruptedFile, c.pathToFile, err)
+	}
+	if file.Items == nil {
+		file.Items = []T{}
+	}
+	return file, nil
+}
+
+// All returns every record in the collection.
+func (c *Collection[T]) All() ([]T, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	file, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return file.Items, nil
+}
+
+// Mutate loads the records, lets fn change them and saves the result. nextID
+// allocates IDs that are never reused, even after deletes. Nothing is written
+// when fn returns an error.
+func (c *Collection[T]) Mutate(fn func(items []T, nextID func() int) ([]T, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	file, err := c.load()
+	if err != nil {
+		return err
+	}
+	nextID := func() int {
+		file.LastID++
+		return file.LastID
+	}
+	items, err := fn(file.Items, nextID)
+	if err != nil {
+		return err
+	}
+	file.Items = items
+	bytes, err := json.MarshalIndent(file, "", "    ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.pathToFile, bytes)
+}
@@ -3,8 +3,11 @@ package store
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sync"
 
 	"github.com/NPG27/supermarket_dop/internal/domain"
 )
@@ -15,12 +18,23 @@ type Store interface {
 	CreateProduct(product *domain.Product) (*domain.Product, error)
 	UpdateProduct(product domain.Product) error
 	DeleteProduct(id int) error
+	// Version identifies the current state of the stored data. It changes
+	// whenever the data may have been modified, including by other processes.
+	Version() (string, error)
 	saveProducts(products []domain.Product) error
 	loadProducts() ([]domain.Product, error)
 }
 
+// ErrCorruptedFile is returned when the products file exists but does not
+// hold a valid JSON array of products.
+var ErrCorruptedFile = errors.New("products file is corrupted")
+
 type jsonStore struct {
 	pathToFile string
+	// mu serializes mutations so concurrent read-modify-write cycles do not
+	// overwrite each other. Readers only need the read lock because writes
+	// replace the file atomically.
+	mu sync.RWMutex
 }
 
 func (s *jsonStore) loadProducts() ([]domain.Product, error) {
@@ -38,17 +52,110 @@ func (s *jsonStore) loadProducts() ([]domain.Product, error) {
 
 	err = json.Unmarshal(byteValue, &products)
 	if err != nil {
-		return nil, errors.New("Cannot load json")
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%w: %s: %v at byte offset %d", ErrCorruptedFile, s.pathToFile, syntaxErr, syntaxErr.Offset)
+		}
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorruptedFile, s.pathToFile, err)
+	}
+	if products == nil {
+		return nil, fmt.Errorf("%w: %s: expected a JSON array of products", ErrCorruptedFile, s.pathToFile)
 	}
 	return products, nil
 }
 
+// saveProducts writes the products to a temporary file in the same directory,
+// flushes it to disk and renames it over the original, so a crash leaves
+// either the old or the new contents but never a partial file.
 func (s *jsonStore) saveProducts(products []domain.Product) error {
 	bytes, err := json.Marshal(products)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(s.pathToFile, bytes, 0644)
+	return writeFileAtomic(s.pathToFile, bytes)
+}
+
+// sequencePath is the sidecar file holding the highest ID ever assigned.
+func (s *jsonStore) sequencePath() string {
+	return s.pathToFile + ".seq"
+}
+
+type sequence struct {
+	LastID int `json:"last_id"`
+}
+
+// loadLastID returns the persisted high-water mark, or 0 when no sequence has
+// been written yet.
+func (s *jsonStore) loadLastID() (int, error) {
+	bytes, err := os.ReadFile(s.sequencePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var seq sequence
+	if err := json.Unmarshal(bytes, &seq); err != nil {
+		return 0, fmt.Errorf("%w: %s: %v", ErrCorruptedFile, s.sequencePath(), err)
+	}
+	return seq.LastID, nil
+}
+
+func (s *jsonStore) saveLastID(id int) error {
+	bytes, err := json.Marshal(sequence{LastID: id})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.sequencePath(), bytes)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes the directory entry so the rename itself survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Version is derived from the file's size and modification time, so edits made
+// outside the API are noticed as well.
+func (s *jsonStore) Version() (string, error) {
+	info, err := os.Stat(s.pathToFile)
+	if err != nil {
+		return "", errors.New("File not found")
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
 }
 
 func NewStore(path string) Store {
@@ -58,6 +165,8 @@ func NewStore(path string) Store {
 }
 
 func (s *jsonStore) GetAllProducts() ([]domain.Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	products, err := s.loadProducts()
 	if err != nil {
 		return nil, err
@@ -66,6 +175,8 @@ func (s *jsonStore) GetAllProducts() ([]domain.Product, error) {
 }
 
 func (s *jsonStore) GetProductByID(id int) (domain.Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	products, err := s.loadProducts()
 	if err != nil {
 		return domain.Product{}, err
@@ -79,17 +190,32 @@ func (s *jsonStore) GetProductByID(id int) (domain.Product, error) {
 }
 
 func (s *jsonStore) CreateProduct(product *domain.Product) (*domain.Product, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	products, err := s.loadProducts()
 	if err != nil {
 		return &domain.Product{}, err
 	}
-	product.ID = len(products) + 1
+	lastID, err := s.loadLastID()
+	if err != nil {
+		return &domain.Product{}, err
+	}
+	product.ID = nextID(lastID, products)
+	// The sequence is persisted first: a crash before the products are saved
+	// only skips an ID, it never hands the same one out twice.
+	if err := s.saveLastID(product.ID); err != nil {
+		return &domain.Product{}, err
+	}
 	products = append(products, *product)
-	s.saveProducts(products)
+	if err := s.saveProducts(products); err != nil {
+		return &domain.Product{}, err
+	}
 	return product, nil
 }
 
 func (s *jsonStore) UpdateProduct(product domain.Product) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	products, err := s.loadProducts()
 	if err != nil {
 		return err
@@ -104,12 +230,23 @@ func (s *jsonStore) UpdateProduct(product domain.Product) error {
 }
 
 func (s *jsonStore) DeleteProduct(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	products, err := s.loadProducts()
 	if err != nil {
 		return err
 	}
 	for i, p := range products {
 		if p.ID == id {
+			// Record the high-water mark before the highest ID can disappear
+			// from the file, otherwise nextID would hand it out again.
+			lastID, err := s.loadLastID()
+			if err != nil {
+				return err
+			}
+			if err := s.saveLastID(nextID(lastID, products) - 1); err != nil {
+				return err
+			}
 			products = append(products[:i], products[i+1:]...)
 			return s.saveProducts(products)
 		}
@@ -0,0 +1,22 @@
+package store
+
+import "fmt"
+
+// Open returns the product store for driver ("json", the default, or
+// "sqlite"). An empty path selects the driver's file under ./data.
+func Open(driver string, path string) (Store, error) {
+	switch driver {
+	case "", "json":
+		if path == "" {
+			path = "./data/products.json"
+		}
+		return NewStore(path), nil
+	case "sqlite":
+		if path == "" {
+			path = "./data/products.db"
+		}
+		return NewSQLiteStore(path)
+	default:
+		return nil, fmt.Errorf("unknown STORE_DRIVER %q", driver)
+	}
+}
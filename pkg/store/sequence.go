@@ -0,0 +1,49 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+)
+
+// nextID returns the ID for a new product given the persisted high-water mark.
+// The mark is never trusted below the largest ID actually stored, so data
+// written before sequences existed keeps working.
+func nextID(lastID int, products []domain.Product) int {
+	for _, p := range products {
+		if p.ID > lastID {
+			lastID = p.ID
+		}
+	}
+	return lastID + 1
+}
+
+// CheckIntegrity loads every product from s and reports IDs that appear more
+// than once. It is meant to run at startup, before the data is served.
+func CheckIntegrity(s Store) error {
+	products, err := s.GetAllProducts()
+	if err != nil {
+		return err
+	}
+	counts := make(map[int]int)
+	for _, p := range products {
+		counts[p.ID]++
+	}
+	var duplicates []int
+	for id, count := range counts {
+		if count > 1 {
+			duplicates = append(duplicates, id)
+		}
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+	sort.Ints(duplicates)
+	report := make([]string, len(duplicates))
+	for i, id := range duplicates {
+		report[i] = fmt.Sprintf("%d (x%d)", id, counts[id])
+	}
+	return fmt.Errorf("duplicate product IDs found: %s", strings.Join(report, ", "))
+}
@@ -0,0 +1,276 @@
+package store
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteMigrations are applied in order and tracked through PRAGMA user_version,
+// so a database created by an older build is brought up to date on open.
+var sqliteMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS products (
+		id           INTEGER PRIMARY KEY,
+		name         TEXT    NOT NULL,
+		quantity     INTEGER NOT NULL,
+		code_value   TEXT    NOT NULL,
+		is_published INTEGER NOT NULL DEFAULT 0,
+		expiration   TEXT    NOT NULL,
+		price        REAL    NOT NULL
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_products_code_value ON products(code_value);`,
+	`CREATE TABLE IF NOT EXISTS sequences (
+		name  TEXT    PRIMARY KEY,
+		value INTEGER NOT NULL
+	);
+	INSERT OR IGNORE INTO sequences (name, value) SELECT 'products', COALESCE(MAX(id), 0) FROM products;`,
+	`ALTER TABLE products ADD COLUMN category_ids TEXT NOT NULL DEFAULT '[]';`,
+	`ALTER TABLE products ADD COLUMN min_stock INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN reorder_point INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN reorder_quantity INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE products ADD COLUMN tax_class TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE products ADD COLUMN barcodes TEXT NOT NULL DEFAULT '[]';`,
+	`ALTER TABLE products ADD COLUMN unit TEXT NOT NULL DEFAULT '';
+	ALTER TABLE products ADD COLUMN plu TEXT NOT NULL DEFAULT '';`,
+	// Prices move from REAL to whole cents, so they never go through a float.
+	`ALTER TABLE products ADD COLUMN price_cents INTEGER NOT NULL DEFAULT 0;
+	UPDATE products SET price_cents = CAST(ROUND(price * 100) AS INTEGER);
+	ALTER TABLE products DROP COLUMN price;
+	ALTER TABLE products RENAME COLUMN price_cents TO price;`,
+}
+
+// bumpProductSequence advances the product high-water mark past any stored ID.
+const bumpProductSequence = `UPDATE sequences
+	SET value = MAX(value, (SELECT COALESCE(MAX(id), 0) FROM products)) + ?
+	WHERE name = 'products'`
+
+type sqliteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(path string) (Store, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on", path))
+	if err != nil {
+		return nil, err
+	}
+	// SQLite serializes writers anyway; a single connection avoids SQLITE_BUSY
+	// between our own goroutines.
+	db.SetMaxOpenConns(1)
+	s := &sqliteStore{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *sqliteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Cannot apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const productColumns = "id, name, quantity, code_value, is_published, expiration, price, category_ids, min_stock, reorder_point, reorder_quantity, tax_class, barcodes, unit, plu"
+
+// jsonColumn stores a slice or other composite field as JSON text.
+type jsonColumn struct {
+	target interface{}
+}
+
+func (c jsonColumn) Value() (driver.Value, error) {
+	bytes, err := json.Marshal(c.target)
+	if err != nil {
+		return nil, err
+	}
+	if string(bytes) == "null" {
+		return "[]", nil
+	}
+	return string(bytes), nil
+}
+
+func (c jsonColumn) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(value), c.target)
+	case []byte:
+		return json.Unmarshal(value, c.target)
+	}
+	return fmt.Errorf("cannot scan %T into a JSON column", src)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProduct(row rowScanner) (domain.Product, error) {
+	var p domain.Product
+	err := row.Scan(&p.ID, &p.Name, &p.Quantity, &p.CodeValue, &p.IsPublished, &p.Expiration, &p.Price, jsonColumn{&p.CategoryIDs}, &p.MinStock, &p.ReorderPoint, &p.ReorderQuantity, &p.TaxClass, jsonColumn{&p.Barcodes}, &p.Unit, &p.PLU)
+	if len(p.CategoryIDs) == 0 {
+		p.CategoryIDs = nil
+	}
+	if len(p.Barcodes) == 0 {
+		p.Barcodes = nil
+	}
+	return p, err
+}
+
+func (s *sqliteStore) loadProducts() ([]domain.Product, error) {
+	rows, err := s.db.Query("SELECT " + productColumns + " FROM products ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	products := []domain.Product{}
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// saveProducts replaces the whole table in a single transaction. It is only
+// used for bulk loads such as ImportJSON.
+func (s *sqliteStore) saveProducts(products []domain.Product) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM products"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	stmt, err := tx.Prepare("INSERT INTO products (" + productColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range products {
+		if _, err := stmt.Exec(p.ID, p.Name, p.Quantity, p.CodeValue, p.IsPublished, p.Expiration, p.Price, jsonColumn{p.CategoryIDs}, p.MinStock, p.ReorderPoint, p.ReorderQuantity, p.TaxClass, jsonColumn{p.Barcodes}, p.Unit, p.PLU); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("Cannot import product %d: %w", p.ID, err)
+		}
+	}
+	if _, err := tx.Exec(bumpProductSequence, 0); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Version uses PRAGMA data_version, which changes when another connection,
+// e.g. the importer, commits to the database.
+func (s *sqliteStore) Version() (string, error) {
+	var version int64
+	if err := s.db.QueryRow("PRAGMA data_version").Scan(&version); err != nil {
+		return "", err
+	}
+	return fmt.Sprint(version), nil
+}
+
+func (s *sqliteStore) GetAllProducts() ([]domain.Product, error) {
+	return s.loadProducts()
+}
+
+func (s *sqliteStore) GetProductByID(id int) (domain.Product, error) {
+	row := s.db.QueryRow("SELECT "+productColumns+" FROM products WHERE id = ?", id)
+	product, err := scanProduct(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Product{}, errors.New("product not found")
+	}
+	if err != nil {
+		return domain.Product{}, err
+	}
+	return product, nil
+}
+
+func (s *sqliteStore) CreateProduct(product *domain.Product) (*domain.Product, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return &domain.Product{}, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(bumpProductSequence, 1); err != nil {
+		return &domain.Product{}, err
+	}
+	var id int
+	if err := tx.QueryRow("SELECT value FROM sequences WHERE name = 'products'").Scan(&id); err != nil {
+		return &domain.Product{}, err
+	}
+	_, err = tx.Exec("INSERT INTO products ("+productColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, product.Name, product.Quantity, product.CodeValue, product.IsPublished, product.Expiration, product.Price, jsonColumn{product.CategoryIDs}, product.MinStock, product.ReorderPoint, product.ReorderQuantity, product.TaxClass, jsonColumn{product.Barcodes}, product.Unit, product.PLU)
+	if err != nil {
+		return &domain.Product{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return &domain.Product{}, err
+	}
+	product.ID = id
+	return product, nil
+}
+
+func (s *sqliteStore) UpdateProduct(product domain.Product) error {
+	result, err := s.db.Exec("UPDATE products SET name = ?, quantity = ?, code_value = ?, is_published = ?, expiration = ?, price = ?, category_ids = ?, min_stock = ?, reorder_point = ?, reorder_quantity = ?, tax_class = ?, barcodes = ?, unit = ?, plu = ? WHERE id = ?",
+		product.Name, product.Quantity, product.CodeValue, product.IsPublished, product.Expiration, product.Price, jsonColumn{product.CategoryIDs}, product.MinStock, product.ReorderPoint, product.ReorderQuantity, product.TaxClass, jsonColumn{product.Barcodes}, product.Unit, product.PLU, product.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.New("Product not found")
+	}
+	return nil
+}
+
+func (s *sqliteStore) DeleteProduct(id int) error {
+	result, err := s.db.Exec("DELETE FROM products WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.New("product not found")
+	}
+	return nil
+}
+
+// ImportJSON copies every product from a JSON file into dst, replacing its
+// current contents. It is meant to be run once when switching to SQLite.
+func ImportJSON(jsonPath string, dst Store) (int, error) {
+	src := &jsonStore{pathToFile: jsonPath}
+	products, err := src.loadProducts()
+	if err != nil {
+		return 0, err
+	}
+	if err := dst.saveProducts(products); err != nil {
+		return 0, err
+	}
+	return len(products), nil
+}
@@ -0,0 +1,255 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NPG27/supermarket_dop/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSQLiteStore opens a store on a fresh database file in a temp directory.
+func newSQLiteStore(t *testing.T) *sqliteStore {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "products.db")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	t.Cleanup(func() { s.(*sqliteStore).db.Close() })
+	return s.(*sqliteStore)
+}
+
+// fullProduct sets every persisted column so a round trip loses nothing.
+func fullProduct(code string) domain.Product {
+	return domain.Product{
+		Name:            "Bananas",
+		Quantity:        5000,
+		CodeValue:       code,
+		IsPublished:     true,
+		Expiration:      domain.NewDate(2099, time.December, 31),
+		Price:           domain.Cents(240),
+		CategoryIDs:     []int{2, 3},
+		MinStock:        1000,
+		ReorderPoint:    2000,
+		ReorderQuantity: 10000,
+		TaxClass:        domain.TaxReduced,
+		Barcodes:        []domain.Barcode{{GTIN: "04006381333931", EAN13: "4006381333931", Packaging: domain.PackagingUnit, Units: 1}},
+		Unit:            domain.UnitKilogram,
+		PLU:             "4011",
+	}
+}
+
+func userVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatalf("Error reading user_version: %v", err)
+	}
+	return version
+}
+
+func Test_SQLite_Migrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.db")
+	// A database left by a build that only knew the first migration, with
+	// the day-first dates it wrote then.
+	db, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(sqliteMigrations[0] + `
+		INSERT INTO products (id, name, quantity, code_value, is_published, expiration, price)
+		VALUES (7, 'Milk', 3, 'MILK', 1, '31/01/2099', 1.15);
+		PRAGMA user_version = 1;`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Error migrating database: %v", err)
+	}
+	migrated := s.(*sqliteStore)
+	defer migrated.db.Close()
+
+	assert.Equal(t, len(sqliteMigrations), userVersion(t, migrated.db))
+	product, err := migrated.GetProductByID(7)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Product{ID: 7, Name: "Milk", Quantity: 3, CodeValue: "MILK", IsPublished: true, Expiration: domain.NewDate(2099, time.January, 31), Price: domain.Cents(115)}, product)
+
+	// The price was converted to whole cents, 1.15 not being exact as a float.
+	var priceType string
+	assert.NoError(t, migrated.db.QueryRow("SELECT typeof(price) FROM products WHERE id = 7").Scan(&priceType))
+	assert.Equal(t, "integer", priceType)
+
+	var sequence int
+	assert.NoError(t, migrated.db.QueryRow("SELECT value FROM sequences WHERE name = 'products'").Scan(&sequence))
+	assert.Equal(t, 7, sequence)
+
+	// Reopening an up to date database applies nothing.
+	migrated.db.Close()
+	s, err = NewSQLiteStore(path)
+	if assert.NoError(t, err) {
+		defer s.(*sqliteStore).db.Close()
+		assert.Equal(t, len(sqliteMigrations), userVersion(t, s.(*sqliteStore).db))
+	}
+}
+
+func Test_SQLite_Columns(t *testing.T) {
+	s := newSQLiteStore(t)
+
+	product := fullProduct("BAN-KG")
+	created, err := s.CreateProduct(&product)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, created.ID)
+	stored, err := s.GetProductByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, product, stored)
+
+	stored.Name = "Plantains"
+	stored.Quantity = 0
+	stored.IsPublished = false
+	stored.Expiration = domain.NewDate(2100, time.January, 1)
+	stored.Price = domain.Cents(315)
+	stored.CategoryIDs = nil
+	stored.MinStock, stored.ReorderPoint, stored.ReorderQuantity = 0, 0, 0
+	stored.TaxClass = domain.TaxStandard
+	stored.Barcodes = append(stored.Barcodes, domain.Barcode{GTIN: "14006381333938", Packaging: domain.PackagingCase, Units: 6})
+	stored.Unit = domain.UnitEach
+	stored.PLU = ""
+	assert.NoError(t, s.UpdateProduct(stored))
+	updated, err := s.GetProductByID(1)
+	assert.NoError(t, err)
+	assert.Equal(t, stored, updated)
+
+	all, err := s.GetAllProducts()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Product{stored}, all)
+
+	assert.Error(t, s.UpdateProduct(domain.Product{ID: 2, CodeValue: "NONE"}))
+	_, err = s.GetProductByID(2)
+	assert.Error(t, err)
+}
+
+func Test_SQLite_UniqueCodeValue(t *testing.T) {
+	s := newSQLiteStore(t)
+
+	first, second := fullProduct("BAN-KG"), fullProduct("BAN-KG")
+	_, err := s.CreateProduct(&first)
+	assert.NoError(t, err)
+	_, err = s.CreateProduct(&second)
+	assert.Error(t, err)
+
+	other := fullProduct("PLT-KG")
+	_, err = s.CreateProduct(&other)
+	assert.NoError(t, err)
+	other.CodeValue = "BAN-KG"
+	assert.Error(t, s.UpdateProduct(other))
+
+	all, err := s.GetAllProducts()
+	assert.NoError(t, err)
+	if assert.Len(t, all, 2) {
+		assert.Equal(t, "PLT-KG", all[1].CodeValue)
+	}
+}
+
+func Test_SQLite_Sequence(t *testing.T) {
+	s := newSQLiteStore(t)
+
+	for _, code := range []string{"A", "B", "C"} {
+		product := fullProduct(code)
+		_, err := s.CreateProduct(&product)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, s.DeleteProduct(3))
+	assert.Error(t, s.DeleteProduct(3))
+
+	// A deleted ID is never handed out again.
+	product := fullProduct("D")
+	created, err := s.CreateProduct(&product)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, created.ID)
+	}
+}
+
+func Test_SQLite_Version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.db")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := s.(*sqliteStore)
+	defer reader.db.Close()
+	s, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := s.(*sqliteStore)
+	defer writer.db.Close()
+
+	before, err := reader.Version()
+	assert.NoError(t, err)
+	unchanged, _ := reader.Version()
+	assert.Equal(t, before, unchanged)
+
+	// A commit from another connection, as the importer makes, changes it.
+	product := fullProduct("BAN-KG")
+	_, err = writer.CreateProduct(&product)
+	assert.NoError(t, err)
+	after, err := reader.Version()
+	assert.NoError(t, err)
+	assert.NotEqual(t, before, after)
+}
+
+func Test_SQLite_ImportJSON(t *testing.T) {
+	s := newSQLiteStore(t)
+	existing := fullProduct("OLD")
+	_, err := s.CreateProduct(&existing)
+	assert.NoError(t, err)
+
+	products := []domain.Product{fullProduct("BAN-KG"), fullProduct("S82254D")}
+	products[0].ID = 10
+	products[1].ID = 42
+	products[1].IsPublished = false
+	products[1].Barcodes, products[1].Unit, products[1].PLU, products[1].CategoryIDs = nil, "", "", nil
+	data, _ := json.Marshal(products)
+	jsonPath := filepath.Join(t.TempDir(), "products.json")
+	if err := os.WriteFile(jsonPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := ImportJSON(jsonPath, s)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	all, err := s.GetAllProducts()
+	assert.NoError(t, err)
+	assert.Equal(t, products, all)
+
+	product := fullProduct("NEW")
+	created, err := s.CreateProduct(&product)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 43, created.ID)
+	}
+
+	// A file with a repeated code is rejected and the table is left alone.
+	duplicated := []domain.Product{fullProduct("DUP"), fullProduct("DUP")}
+	duplicated[0].ID, duplicated[1].ID = 1, 2
+	data, _ = json.Marshal(duplicated)
+	if err := os.WriteFile(jsonPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ImportJSON(jsonPath, s)
+	assert.Error(t, err)
+	after, _ := s.GetAllProducts()
+	assert.Len(t, after, 3)
+
+	_, err = ImportJSON(filepath.Join(t.TempDir(), "missing.json"), s)
+	assert.Error(t, err)
+}
@@ -13,7 +13,26 @@ type errorResponse struct {
 }
 
 type response struct {
-	Data interface{} `json:"data"`
+	Data  interface{} `json:"data"`
+	Meta  *Meta       `json:"meta,omitempty"`
+	Links *Links      `json:"links,omitempty"`
+}
+
+// Meta describes the page of a paginated collection.
+type Meta struct {
+	Total      int    `json:"total"`
+	Count      int    `json:"count"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Links holds the URLs of the current, next and previous pages.
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
 }
 
 func Success(ctx *gin.Context, status int, data interface{}) {
@@ -22,6 +41,14 @@ func Success(ctx *gin.Context, status int, data interface{}) {
 	})
 }
 
+func SuccessPage(ctx *gin.Context, status int, data interface{}, meta Meta, links Links) {
+	ctx.JSON(status, response{
+		Data:  data,
+		Meta:  &meta,
+		Links: &links,
+	})
+}
+
 func Failure(ctx *gin.Context, status int, err error) {
 	ctx.JSON(status, errorResponse{
 		Message: err.Error(),